@@ -13,8 +13,11 @@ func main() {
 	qBits := 128
 
 	// Zero-padded 'Hello world'
-	msg := make([]byte, 64)
-	copy(msg, []byte("Hello world"))
+	msg, err := elgamal.PadMessage([]byte("Hello world"))
+	if err != nil {
+		fmt.Printf("Padding message failed: %v\n", err)
+		return
+	}
 
 	fmt.Printf("Message = 0x%x\n", msg)
 
@@ -66,6 +69,7 @@ func main() {
 		fmt.Printf("Message recovery failed: %v\n", err)
 	}
 	fmt.Printf("Recovered message: 0x%x\n", recovered)
+	fmt.Printf("Unpadded: %q\n", elgamal.UnpadMessage(recovered))
 
 	if bytes.Equal(recovered, msg) {
 		fmt.Println("Recovered == Message")
@@ -0,0 +1,142 @@
+package elgamal
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"fmt"
+	"math/big"
+)
+
+// macKeyDomain is prepended when deriving the MAC key from y^r, separating
+// it from the key stream, which is derived from either y^r itself or its
+// length-prefixed form, both of which start differently.
+const macKeyDomain byte = 0x01
+
+// EncAD encrypts a message using hashed ElGamal, binding it to the passed
+// associated data, and attaching an integrity tag.
+//
+// The key stream is derived as H(|y^r| || y^r || ad), such that decrypting
+// with different associated data yields a different plaintext. Additionally
+// an HMAC over R, C and the associated data, keyed by a separate hash of y^r,
+// is stored in the ciphertext's tag, which allows RecoverAD() to detect such
+// a mismatch, as well as any tampering with R or C.
+//
+// Unlike labels, the associated data is not stored in the ciphertext, and
+// must be known to the recipient.
+//
+// Parameters:
+// - pub: Public key to use for encryption
+// - message: Message to encrypt. Must be of length pub.BlockSize()
+// - ad: Associated data to bind the ciphertext to, e.g. a recipient ID
+//
+// An error is returned if encryption fails.
+func EncAD(pub PublicKey, message []byte, ad []byte) (Ciphertext, error) {
+	var ctxt Ciphertext
+
+	hash, err := checkMessage(&pub, message)
+	if err != nil {
+		return ctxt, err
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return ctxt, err
+	}
+
+	r, err := randomExponent(nil, &pub)
+	if err != nil {
+		return ctxt, err
+	}
+
+	ctxt.R = zp.Exp(pub.G, r) // g^r
+	ctxt.rSize = (pub.P.BitLen() + 7) / 8
+	z := zp.Exp(pub.Y, r) // y^r
+	err = checkSharedSecret(z)
+	if err != nil {
+		return Ciphertext{}, err
+	}
+	ctxt.C = hashedXOR(hash, z, pub.P, ad, message)
+	ctxt.Tag = authTag(hash, z, pub.P, ctxt.R, ctxt.C, ad)
+
+	return ctxt, nil
+}
+
+// RecoverAD decrypts a ciphertext produced by EncAD() using t decryption
+// shares, given the same associated data it was encrypted with.
+//
+// An error is returned if the ciphertext has no tag, or if the tag does not
+// verify, e.g. as the associated data differs, or as R or C were tampered
+// with. No plaintext is returned in this case.
+func RecoverAD(pub PublicKey, decryptionShares []DecryptionShare, ctxt Ciphertext, ad []byte) ([]byte, error) {
+	if len(ctxt.Tag) == 0 {
+		return nil, fmt.Errorf("Ciphertext is missing tag")
+	}
+
+	hash, err := pub.hashFunc()
+	if err != nil {
+		return nil, err
+	}
+	z, err := combineSharesOf(pub, decryptionShares, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = checkSharedSecret(z)
+	if err != nil {
+		return nil, err
+	}
+	if len(ctxt.C) != hash.Size() {
+		return nil, fmt.Errorf("Ciphertext must be %d bytes; got %d", hash.Size(), len(ctxt.C))
+	}
+	if ctxt.R == nil {
+		return nil, fmt.Errorf("Ciphertext is missing R")
+	}
+
+	if !constantTimeEqual(ctxt.Tag, authTag(hash, z, pub.P, ctxt.R, ctxt.C, ad)) {
+		return nil, fmt.Errorf("Ciphertext failed integrity check")
+	}
+
+	return hashedXOR(hash, z, pub.P, ad, ctxt.C), nil
+}
+
+// authTag computes the integrity tag HMAC(K, |R| || R || |C| || C || ad),
+// with the MAC key K = H(0x01 || |z| || z) derived from z = y^r. As for the
+// key stream, z is encoded with the byte length of p.
+func authTag(hash crypto.Hash, z *big.Int, p *big.Int, R *big.Int, c []byte, ad []byte) []byte {
+	kh := hash.New()
+	kh.Write([]byte{macKeyDomain})
+	kh.Write(appendLengthPrefixed(nil, fixedBytes(z, (p.BitLen()+7)/8)))
+	key := kh.Sum(nil)
+
+	mac := hmac.New(hash.New, key)
+	mac.Write(appendLengthPrefixed(nil, R.Bytes()))
+	mac.Write(appendLengthPrefixed(nil, c))
+	mac.Write(ad)
+
+	return mac.Sum(nil)
+}
+
+// EncAuth encrypts a message using hashed ElGamal, attaching an integrity tag
+// over R and C. It is equivalent to EncAD() with empty associated data.
+//
+// Plain hashed ElGamal, as implemented by Enc(), is malleable: Flipping a bit
+// in C flips the same bit in the recovered plaintext, undetected. Ciphertexts
+// produced by EncAuth() must be decrypted using RecoverAuth(), which detects
+// such tampering.
+//
+// Parameters:
+// - pub: Public key to use for encryption
+// - message: Message to encrypt. Must be of length pub.BlockSize()
+//
+// An error is returned if encryption fails.
+func EncAuth(pub PublicKey, message []byte) (Ciphertext, error) {
+	return EncAD(pub, message, nil)
+}
+
+// RecoverAuth decrypts a ciphertext produced by EncAuth() using t decryption
+// shares, after verifying its integrity tag.
+//
+// An error is returned if the ciphertext has no tag, or if the tag does not
+// verify. No plaintext is returned in this case.
+func RecoverAuth(pub PublicKey, decryptionShares []DecryptionShare, ctxt Ciphertext) ([]byte, error) {
+	return RecoverAD(pub, decryptionShares, ctxt, nil)
+}
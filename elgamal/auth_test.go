@@ -0,0 +1,201 @@
+package elgamal
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/sha512"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestEncAD(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+	ad := []byte("recipient-1")
+
+	ctxt, err := EncAD(pub, msg, ad)
+	if err != nil {
+		t.Fatalf("EncAD returned error: %v", err)
+	}
+	if len(ctxt.Tag) == 0 {
+		t.Errorf("Expected ciphertext to carry a tag")
+	}
+	if len(ctxt.Label) != 0 {
+		t.Errorf("Expected associated data not to be stored in ciphertext; got label %q", ctxt.Label)
+	}
+
+	decShares, err := DecAll(pub, shares[:3], ctxt)
+	if err != nil {
+		t.Fatalf("DecAll returned error: %v", err)
+	}
+
+	recovered, err := RecoverAD(pub, decShares, ctxt, ad)
+	if err != nil {
+		t.Fatalf("RecoverAD returned error: %v", err)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recovered)
+	}
+
+	// Different associated data
+	_, err = RecoverAD(pub, decShares, ctxt, []byte("recipient-2"))
+	if err == nil {
+		t.Errorf("Expected error with different associated data; got none")
+	}
+	_, err = RecoverAD(pub, decShares, ctxt, nil)
+	if err == nil {
+		t.Errorf("Expected error with missing associated data; got none")
+	}
+
+	// Without verification, the key stream differs too
+	recovered, err = RecoverLabeled(pub, decShares, Ciphertext{R: ctxt.R, C: ctxt.C, Label: []byte("recipient-2")}, []byte("recipient-2"))
+	if err != nil {
+		t.Fatalf("RecoverLabeled returned error: %v", err)
+	}
+	if bytes.Equal(recovered, msg) {
+		t.Errorf("Expected different associated data to yield different plaintext")
+	}
+
+	// Tampered ciphertext
+	tampered := ctxt.Clone()
+	tampered.C[0] ^= 0x01
+	_, err = RecoverAD(pub, decShares, tampered, ad)
+	if err == nil {
+		t.Errorf("Expected error with tampered C; got none")
+	}
+
+	tampered = ctxt.Clone()
+	tampered.Tag[0] ^= 0x01
+	_, err = RecoverAD(pub, decShares, tampered, ad)
+	if err == nil {
+		t.Errorf("Expected error with tampered tag; got none")
+	}
+
+	// Missing tag
+	untagged := ctxt.Clone()
+	untagged.Tag = nil
+	_, err = RecoverAD(pub, decShares, untagged, ad)
+	if err == nil {
+		t.Errorf("Expected error with missing tag; got none")
+	}
+
+	_, err = RecoverAD(pub, decShares[:2], ctxt, ad)
+	if err == nil {
+		t.Errorf("Expected error with fewer than t shares; got none")
+	}
+}
+
+func TestEncAuth(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 2, 3)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+
+	ctxt, err := EncAuth(pub, msg)
+	if err != nil {
+		t.Fatalf("EncAuth returned error: %v", err)
+	}
+
+	decShares, err := DecAll(pub, shares[:2], ctxt)
+	if err != nil {
+		t.Fatalf("DecAll returned error: %v", err)
+	}
+
+	recovered, err := RecoverAuth(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("RecoverAuth returned error: %v", err)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recovered)
+	}
+
+	// Flipping a byte of C goes unnoticed by Recover(), but not by
+	// RecoverAuth()
+	tampered := ctxt.Clone()
+	tampered.C[0] ^= 0xFF
+
+	recovered, err = Recover(pub, decShares, tampered)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if recovered[0] != msg[0]^0xFF {
+		t.Errorf("Expected unauthenticated recovery to flip first byte")
+	}
+
+	_, err = RecoverAuth(pub, decShares, tampered)
+	if err == nil {
+		t.Errorf("Expected verification error with tampered C; got none")
+	}
+
+	// Unauthenticated ciphertexts are rejected
+	plain, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+	decShares, err = DecAll(pub, shares[:2], plain)
+	if err != nil {
+		t.Fatalf("DecAll returned error: %v", err)
+	}
+	_, err = RecoverAuth(pub, decShares, plain)
+	if err == nil {
+		t.Errorf("Expected error with untagged ciphertext; got none")
+	}
+}
+
+func TestRecoverAuthShares(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 2, 3)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	ctxt, err := EncAuth(pub, make([]byte, 64))
+	if err != nil {
+		t.Fatalf("EncAuth returned error: %v", err)
+	}
+	decShares, err := DecAll(pub, shares[:2], ctxt)
+	if err != nil {
+		t.Fatalf("DecAll returned error: %v", err)
+	}
+
+	_, err = RecoverAuth(pub, []DecryptionShare{decShares[0], decShares[0]}, ctxt)
+	if !errors.Is(err, ErrDuplicateShare) {
+		t.Errorf("Expected ErrDuplicateShare; got %v", err)
+	}
+	_, err = RecoverAuth(pub, []DecryptionShare{decShares[0], {ID: 2}}, ctxt)
+	if err == nil {
+		t.Errorf("Expected error with nil decryption share; got none")
+	}
+	_, err = RecoverAuth(pub, decShares[:1], ctxt)
+	if !errors.Is(err, ErrThresholdNotMet) {
+		t.Errorf("Expected ErrThresholdNotMet; got %v", err)
+	}
+}
+
+func TestAuthTagPadding(t *testing.T) {
+	// A small y^r with a modulus of 3 bytes is encoded as 3 bytes when
+	// deriving the MAC key, as for the key stream
+	p := big.NewInt(65537)
+	R := big.NewInt(3)
+	c := make([]byte, 64)
+
+	kh := sha512.New()
+	kh.Write([]byte{macKeyDomain, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x05})
+	mac := hmac.New(sha512.New, kh.Sum(nil))
+	mac.Write(appendLengthPrefixed(nil, R.Bytes()))
+	mac.Write(appendLengthPrefixed(nil, c))
+
+	got := authTag(crypto.SHA512, big.NewInt(5), p, R, c, nil)
+	if !bytes.Equal(got, mac.Sum(nil)) {
+		t.Errorf("Expected tag keyed by H(01 || 00000003 || 00 00 05); got %x", got)
+	}
+}
@@ -0,0 +1,155 @@
+package elgamal
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// benchmarkSizes are the bit lengths of p and q which the benchmarks of the
+// core operations run over.
+var benchmarkSizes = []struct {
+	pBits, qBits int
+}{
+	{1024, 160},
+	{2048, 256},
+}
+
+// benchmarkGroups caches one Schnorr group per size, as generating them
+// dominates the time of any benchmark.
+var benchmarkGroups = struct {
+	sync.Mutex
+	groups map[string]SchnorrGroup
+}{groups: make(map[string]SchnorrGroup)}
+
+// benchmarkGroup returns a Schnorr group of the passed size, generating it on
+// first use. It must be called before the timer is reset.
+func benchmarkGroup(b *testing.B, pBits int, qBits int) SchnorrGroup {
+	benchmarkGroups.Lock()
+	defer benchmarkGroups.Unlock()
+
+	key := fmt.Sprintf("%d/%d", pBits, qBits)
+	group, ok := benchmarkGroups.groups[key]
+	if !ok {
+		var err error
+		group, err = GenerateSchnorrGroup(pBits, qBits)
+		if err != nil {
+			b.Fatalf("GenerateSchnorrGroup returned error: %v", err)
+		}
+		benchmarkGroups.groups[key] = group
+	}
+
+	return group
+}
+
+// benchmarkFixture is a t-out-of-n key within a cached group, along with a
+// ciphertext of an all-zero message and the decryption shares of all n
+// parties.
+type benchmarkFixture struct {
+	pub       PublicKey
+	keyShares []PrivateKeyShare
+	ctxt      Ciphertext
+	decShares []DecryptionShare
+}
+
+// newBenchmarkFixture sets up a t-out-of-n fixture within a cached group of
+// the passed size. It must be called before the timer is reset.
+func newBenchmarkFixture(b *testing.B, pBits int, qBits int, t int, n int) benchmarkFixture {
+	pub, _, keyShares, err := RekeyInGroup(benchmarkGroup(b, pBits, qBits), t, n)
+	if err != nil {
+		b.Fatalf("RekeyInGroup returned error: %v", err)
+	}
+
+	ctxt, err := Enc(pub, make([]byte, MessageSize(pub)))
+	if err != nil {
+		b.Fatalf("Enc returned error: %v", err)
+	}
+
+	decShares, err := DecAll(pub, keyShares, ctxt)
+	if err != nil {
+		b.Fatalf("DecAll returned error: %v", err)
+	}
+
+	return benchmarkFixture{pub: pub, keyShares: keyShares, ctxt: ctxt, decShares: decShares}
+}
+
+// benchmarkMessages returns count all-zero messages of the maximum size the
+// passed key allows.
+func benchmarkMessages(pub PublicKey, count int) [][]byte {
+	messages := make([][]byte, count)
+	for i := range messages {
+		messages[i] = make([]byte, MessageSize(pub))
+	}
+
+	return messages
+}
+
+// runSizes runs the passed benchmark as one sub-benchmark per group size.
+func runSizes(b *testing.B, bench func(b *testing.B, pBits int, qBits int)) {
+	for _, size := range benchmarkSizes {
+		size := size
+		b.Run(fmt.Sprintf("p%d-q%d", size.pBits, size.qBits), func(b *testing.B) {
+			bench(b, size.pBits, size.qBits)
+		})
+	}
+}
+
+// BenchmarkKeyGen measures generation of a 3-out-of-5 key within an existing
+// group. The search for the group's primes is excluded, as its running time
+// varies wildly.
+func BenchmarkKeyGen(b *testing.B) {
+	runSizes(b, func(b *testing.B, pBits int, qBits int) {
+		group := benchmarkGroup(b, pBits, qBits)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			_, _, _, err := RekeyInGroup(group, 3, 5)
+			if err != nil {
+				b.Fatalf("RekeyInGroup returned error: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkEnc(b *testing.B) {
+	runSizes(b, func(b *testing.B, pBits int, qBits int) {
+		f := newBenchmarkFixture(b, pBits, qBits, 3, 5)
+		msg := make([]byte, MessageSize(f.pub))
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			_, err := Enc(f.pub, msg)
+			if err != nil {
+				b.Fatalf("Enc returned error: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkDec(b *testing.B) {
+	runSizes(b, func(b *testing.B, pBits int, qBits int) {
+		f := newBenchmarkFixture(b, pBits, qBits, 3, 5)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			_, err := Dec(f.pub, f.keyShares[0], f.ctxt)
+			if err != nil {
+				b.Fatalf("Dec returned error: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkRecover(b *testing.B) {
+	runSizes(b, func(b *testing.B, pBits int, qBits int) {
+		f := newBenchmarkFixture(b, pBits, qBits, 3, 5)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			_, err := Recover(f.pub, f.decShares[:3], f.ctxt)
+			if err != nil {
+				b.Fatalf("Recover returned error: %v", err)
+			}
+		}
+	})
+}
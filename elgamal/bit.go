@@ -0,0 +1,164 @@
+package elgamal
+
+import (
+	"fmt"
+	"github.com/lavode/secret-sharing/gf"
+	"math/big"
+)
+
+// BitProof represents a non-interactive disjunctive Chaum-Pedersen proof that
+// an exponential ElGamal ciphertext (R, C) = (g^r, g^b * y^r) encrypts a bit
+// b in {0, 1}, without revealing which one.
+//
+// For each j in {0, 1}, it proves that log_g(R) = log_y(C / g^j), of which
+// only the statement for j = b is true. The proof for the other one is
+// simulated, which is possible as the prover may choose its challenge, as
+// long as both challenges sum up to the Fiat-Shamir challenge.
+type BitProof struct {
+	// Challenges c_0 and c_1, from (Z / qZ), with c_0 + c_1 = c
+	C0 *big.Int
+	C1 *big.Int
+	// Responses s_0 and s_1, from (Z / qZ)
+	S0 *big.Int
+	S1 *big.Int
+}
+
+// EncBit encrypts a bit using exponential ElGamal, as either g^0 or g^1,
+// along with a proof that the plaintext is a bit. This is useful for yes/no
+// votes, which can then be tallied using AddExp() and RecoverExpValue().
+//
+// An error is returned if encryption fails.
+func EncBit(pub PublicKey, b bool) (ExpCiphertext, BitProof, error) {
+	var ctxt ExpCiphertext
+	var proof BitProof
+
+	m := 0
+	if b {
+		m = 1
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return ctxt, proof, err
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return ctxt, proof, err
+	}
+
+	r, err := randomExponent(nil, &pub)
+	if err != nil {
+		return ctxt, proof, err
+	}
+
+	gm := zp.Exp(pub.G, big.NewInt(int64(m)))
+	ctxt.R = zp.Exp(pub.G, r)             // g^r
+	ctxt.C = zp.Mul(gm, zp.Exp(pub.Y, r)) // g^m * y^r
+
+	proof, err = proveBit(&pub, zp, zq, ctxt, m, r)
+	if err != nil {
+		return ctxt, proof, err
+	}
+
+	return ctxt, proof, nil
+}
+
+// proveBit creates a BitProof for a ciphertext with exponent r, claiming that
+// it encrypts the bit m.
+func proveBit(pub *PublicKey, zp gf.GF, zq gf.GF, ctxt ExpCiphertext, m int, r *big.Int) (BitProof, error) {
+	var proof BitProof
+
+	if m != 0 && m != 1 {
+		return proof, fmt.Errorf("m must be 0 or 1; got %d", m)
+	}
+
+	// Simulated proof for the false statement j = 1-m, with chosen
+	// challenge and response
+	cSim, err := zq.Rand()
+	if err != nil {
+		return proof, err
+	}
+	sSim, err := zq.Rand()
+	if err != nil {
+		return proof, err
+	}
+	a := make([]*big.Int, 2)
+	b := make([]*big.Int, 2)
+	sim := 1 - m
+	a[sim], b[sim] = bitCommitments(pub, zp, ctxt, sim, cSim, sSim)
+
+	// Actual proof for the true statement j = m
+	w, err := zq.Rand()
+	if err != nil {
+		return proof, err
+	}
+	a[m] = zp.Exp(pub.G, w) // g^w
+	b[m] = zp.Exp(pub.Y, w) // y^w
+
+	c, err := challenge(pub, ctxt.R, ctxt.C, a[0], b[0], a[1], b[1])
+	if err != nil {
+		return proof, err
+	}
+	cReal := zq.Sub(c, cSim)
+	sReal := zq.Sub(w, zq.Mul(cReal, r)) // w - c_m * r
+
+	if m == 0 {
+		proof.C0, proof.S0 = cReal, sReal
+		proof.C1, proof.S1 = cSim, sSim
+	} else {
+		proof.C0, proof.S0 = cSim, sSim
+		proof.C1, proof.S1 = cReal, sReal
+	}
+
+	return proof, nil
+}
+
+// VerifyBit checks whether the passed proof shows that the exponential
+// ElGamal ciphertext encrypts either g^0 or g^1.
+func VerifyBit(pub PublicKey, ctxt ExpCiphertext, proof BitProof) bool {
+	zp, err := pub.Zp()
+	if err != nil {
+		return false
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return false
+	}
+
+	// Both components must be of order q, as with VerifyDecryptionShare()
+	for _, elem := range []*big.Int{ctxt.R, ctxt.C} {
+		if !isSubgroupElement(zp, pub.Q, elem) {
+			return false
+		}
+	}
+	for _, elem := range []*big.Int{proof.C0, proof.C1, proof.S0, proof.S1} {
+		if elem == nil || !zq.IsGroupElement(elem) {
+			return false
+		}
+	}
+
+	a0, b0 := bitCommitments(&pub, zp, ctxt, 0, proof.C0, proof.S0)
+	a1, b1 := bitCommitments(&pub, zp, ctxt, 1, proof.C1, proof.S1)
+
+	c, err := challenge(&pub, ctxt.R, ctxt.C, a0, b0, a1, b1)
+	if err != nil {
+		return false
+	}
+
+	return zq.Add(proof.C0, proof.C1).Cmp(c) == 0
+}
+
+// bitCommitments recomputes the commitments g^w and y^w of the statement
+// log_g(R) = log_y(C / g^j) from its challenge c and response s, as
+// a = g^s * R^c and b = y^s * (C / g^j)^c.
+func bitCommitments(pub *PublicKey, zp gf.GF, ctxt ExpCiphertext, j int, c *big.Int, s *big.Int) (*big.Int, *big.Int) {
+	d := ctxt.C
+	if j == 1 {
+		d = zp.Div(ctxt.C, pub.G) // C / g
+	}
+
+	a := zp.Mul(zp.Exp(pub.G, s), zp.Exp(ctxt.R, c))
+	b := zp.Mul(zp.Exp(pub.Y, s), zp.Exp(d, c))
+
+	return a, b
+}
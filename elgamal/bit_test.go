@@ -0,0 +1,151 @@
+package elgamal
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestEncBit(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	votes := []bool{true, false, true, true, false}
+	var tally ExpCiphertext
+	for i, vote := range votes {
+		ctxt, proof, err := EncBit(pub, vote)
+		if err != nil {
+			t.Fatalf("EncBit returned error: %v", err)
+		}
+		if !VerifyBit(pub, ctxt, proof) {
+			t.Errorf("Expected proof of vote %d to verify; it did not", i)
+		}
+
+		// Proof is bound to the ciphertext
+		other, _, err := EncBit(pub, vote)
+		if err != nil {
+			t.Fatalf("EncBit returned error: %v", err)
+		}
+		if VerifyBit(pub, other, proof) {
+			t.Errorf("Expected proof of vote %d not to verify for other ciphertext; it did", i)
+		}
+
+		if i == 0 {
+			tally = ctxt
+		} else {
+			tally, err = AddExp(pub, tally, ctxt)
+			if err != nil {
+				t.Fatalf("AddExp returned error: %v", err)
+			}
+		}
+	}
+
+	decShares := make([]DecryptionShare, 3)
+	for i := range decShares {
+		decShares[i], err = DecExp(pub, shares[i], tally)
+		if err != nil {
+			t.Fatalf("DecExp returned error: %v", err)
+		}
+	}
+	sum, err := RecoverExpValue(pub, decShares, tally, int64(len(votes)+1))
+	if err != nil {
+		t.Fatalf("RecoverExpValue returned error: %v", err)
+	}
+	if sum.Cmp(big.NewInt(3)) != 0 {
+		t.Errorf("Expected tally of 3; got %d", sum)
+	}
+}
+
+func TestVerifyBitRejectsNonBit(t *testing.T) {
+	pub, _, _, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	zp, err := pub.Zp()
+	if err != nil {
+		t.Fatalf("Error creating field: %v", err)
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		t.Fatalf("Error creating field: %v", err)
+	}
+
+	// Ciphertext of g^2, with proofs claiming either bit
+	r := big.NewInt(12345)
+	ctxt := ExpCiphertext{
+		R: zp.Exp(pub.G, r),
+		C: zp.Mul(zp.Exp(pub.G, big.NewInt(2)), zp.Exp(pub.Y, r)),
+	}
+	for _, m := range []int{0, 1} {
+		proof, err := proveBit(&pub, zp, zq, ctxt, m, r)
+		if err != nil {
+			t.Fatalf("proveBit returned error: %v", err)
+		}
+		if VerifyBit(pub, ctxt, proof) {
+			t.Errorf("Expected proof claiming bit %d for g^2 not to verify; it did", m)
+		}
+	}
+
+	// Honest proof with tampered responses
+	ctxt, proof, err := EncBit(pub, true)
+	if err != nil {
+		t.Fatalf("EncBit returned error: %v", err)
+	}
+	tampered := proof
+	tampered.S1 = zq.Add(proof.S1, big.NewInt(1))
+	if VerifyBit(pub, ctxt, tampered) {
+		t.Errorf("Expected tampered proof not to verify; it did")
+	}
+
+	// Swapping the branches
+	swapped := BitProof{C0: proof.C1, C1: proof.C0, S0: proof.S1, S1: proof.S0}
+	if VerifyBit(pub, ctxt, swapped) {
+		t.Errorf("Expected swapped proof not to verify; it did")
+	}
+
+	if VerifyBit(pub, ctxt, BitProof{}) {
+		t.Errorf("Expected empty proof not to verify; it did")
+	}
+}
+
+func TestVerifyBitRejectsNegated(t *testing.T) {
+	pub, _, _, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	zp, _ := pub.Zp()
+	zq, _ := pub.Zq()
+
+	// C = -(g * y^r) is not of order q. With b = -(y^w) for the real
+	// branch, the verifier's y^s * (C / g)^c = (-1)^c * y^w matches it for
+	// odd c.
+	r := big.NewInt(12345)
+	ctxt := ExpCiphertext{R: zp.Exp(pub.G, r)}
+	ctxt.C = new(big.Int).Sub(pub.P, zp.Mul(pub.G, zp.Exp(pub.Y, r)))
+
+	var proof BitProof
+	for {
+		cSim, _ := zq.Rand()
+		sSim, _ := zq.Rand()
+		a0, b0 := bitCommitments(&pub, zp, ctxt, 0, cSim, sSim)
+
+		w, _ := zq.Rand()
+		a1 := zp.Exp(pub.G, w)
+		b1 := new(big.Int).Sub(pub.P, zp.Exp(pub.Y, w))
+
+		c, err := challenge(&pub, ctxt.R, ctxt.C, a0, b0, a1, b1)
+		if err != nil {
+			t.Fatalf("challenge returned error: %v", err)
+		}
+		cReal := zq.Sub(c, cSim)
+		if cReal.Bit(0) == 1 {
+			proof = BitProof{C0: cSim, S0: sSim, C1: cReal, S1: zq.Sub(w, zq.Mul(cReal, r))}
+			break
+		}
+	}
+
+	if VerifyBit(pub, ctxt, proof) {
+		t.Errorf("Expected forged proof of negated ciphertext not to verify; it did")
+	}
+}
@@ -0,0 +1,112 @@
+package elgamal
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+// Codec converts values to and from byte strings, allowing EncValue() and
+// RecoverValue() to encrypt structured records rather than raw blocks.
+type Codec interface {
+	// Encode encodes the passed value.
+	Encode(v interface{}) ([]byte, error)
+	// Decode decodes data into the value pointed to by v.
+	Decode(data []byte, v interface{}) error
+}
+
+// GobCodec is a Codec using encoding/gob. Each value is encoded with its own
+// encoder, so type information is included every time.
+type GobCodec struct{}
+
+// Encode encodes v using encoding/gob.
+func (GobCodec) Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decode decodes data, as produced by Encode(), into the value pointed to by
+// v.
+func (GobCodec) Decode(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// EncValue encodes a value using the passed codec, and encrypts the encoding
+// in as many blocks as needed.
+//
+// The encoding is prefixed with its length as a 4-byte big-endian unsigned
+// integer, zero-padded to a multiple of pub.BlockSize() and split into
+// blocks, which are encrypted using EncBatch(). The returned ciphertexts must
+// be kept in order, and decrypted using RecoverValue().
+//
+// An error is returned if encoding or encryption fails.
+func EncValue(pub PublicKey, codec Codec, v interface{}) ([]Ciphertext, error) {
+	blockSize := pub.BlockSize()
+	if blockSize == 0 {
+		_, err := pub.hashFunc()
+		return nil, err
+	}
+
+	data, err := codec.Encode(v)
+	if err != nil {
+		return nil, fmt.Errorf("Error encoding value: %w", err)
+	}
+
+	data = appendLengthPrefixed(nil, data)
+	if rem := len(data) % blockSize; rem != 0 {
+		data = append(data, make([]byte, blockSize-rem)...)
+	}
+
+	blocks := make([][]byte, 0, len(data)/blockSize)
+	for i := 0; i < len(data); i += blockSize {
+		blocks = append(blocks, data[i:i+blockSize])
+	}
+
+	return EncBatch(pub, blocks)
+}
+
+// RecoverValue decrypts ciphertexts produced by EncValue(), and decodes the
+// value into the one pointed to by v using the passed codec.
+//
+// decryptionShares[i] must hold at least t decryption shares of ctxts[i].
+//
+// An error is returned if the number of share sets does not match the number
+// of ciphertexts, if recovery of any block fails, or if the recovered data is
+// not a valid encoding.
+func RecoverValue(pub PublicKey, codec Codec, decryptionShares [][]DecryptionShare, ctxts []Ciphertext, v interface{}) error {
+	if len(decryptionShares) != len(ctxts) {
+		return fmt.Errorf("Need decryption shares for each of %d ciphertexts; got %d", len(ctxts), len(decryptionShares))
+	}
+	if len(ctxts) == 0 {
+		return fmt.Errorf("No ciphertexts given")
+	}
+
+	var data []byte
+	for i, ctxt := range ctxts {
+		block, err := Recover(pub, decryptionShares[i], ctxt)
+		if err != nil {
+			return fmt.Errorf("Error recovering block %d: %w", i, err)
+		}
+		data = append(data, block...)
+	}
+
+	encoded, padding, err := readLengthPrefixed("value", data)
+	if err != nil {
+		return err
+	}
+	if len(padding) >= pub.BlockSize() {
+		return fmt.Errorf("Value is followed by %d bytes; expected less than one block", len(padding))
+	}
+
+	err = codec.Decode(encoded, v)
+	if err != nil {
+		return fmt.Errorf("Error decoding value: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package elgamal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testRecord struct {
+	Name    string
+	Balance int64
+	Tags    []string
+}
+
+func TestEncValue(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	// Large enough to span multiple blocks
+	record := testRecord{
+		Name:    strings.Repeat("Alice", 20),
+		Balance: -1337,
+		Tags:    []string{"admin", "auditor"},
+	}
+
+	ctxts, err := EncValue(pub, GobCodec{}, record)
+	if err != nil {
+		t.Fatalf("EncValue returned error: %v", err)
+	}
+	if len(ctxts) < 2 {
+		t.Errorf("Expected value to span multiple blocks; got %d", len(ctxts))
+	}
+
+	decShares := make([][]DecryptionShare, len(ctxts))
+	for i, ctxt := range ctxts {
+		decShares[i], err = DecAll(pub, shares[:3], ctxt)
+		if err != nil {
+			t.Fatalf("DecAll returned error: %v", err)
+		}
+	}
+
+	var recovered testRecord
+	err = RecoverValue(pub, GobCodec{}, decShares, ctxts, &recovered)
+	if err != nil {
+		t.Fatalf("RecoverValue returned error: %v", err)
+	}
+	if !reflect.DeepEqual(recovered, record) {
+		t.Errorf("Expected recovered value %+v; got %+v", record, recovered)
+	}
+
+	err = RecoverValue(pub, GobCodec{}, decShares[1:], ctxts, &recovered)
+	if err == nil {
+		t.Errorf("Expected error with missing decryption shares; got none")
+	}
+
+	// Swapping blocks breaks the encoding
+	ctxts[0], ctxts[1] = ctxts[1], ctxts[0]
+	decShares[0], decShares[1] = decShares[1], decShares[0]
+	err = RecoverValue(pub, GobCodec{}, decShares, ctxts, &recovered)
+	if err == nil {
+		t.Errorf("Expected error with reordered ciphertexts; got none")
+	}
+}
@@ -0,0 +1,84 @@
+package elgamal
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ShareCollector accumulates decryption shares of a single ciphertext as they
+// arrive, e.g. over the network, until enough are available to recover the
+// plaintext. It is safe for concurrent use.
+type ShareCollector struct {
+	pub    PublicKey
+	mu     sync.Mutex
+	shares []DecryptionShare
+	seen   map[int]struct{}
+}
+
+// NewShareCollector returns an empty share collector for decryption shares
+// under the passed public key.
+func NewShareCollector(pub PublicKey) *ShareCollector {
+	return &ShareCollector{
+		pub:  pub,
+		seen: make(map[int]struct{}),
+	}
+}
+
+// Add adds a decryption share to the collector.
+//
+// An error is returned if the share's ID is not positive, or if a share with
+// the same ID was added before.
+func (sc *ShareCollector) Add(share DecryptionShare) error {
+	if share.ID <= 0 {
+		return fmt.Errorf("Share IDs must be positive; got %d", share.ID)
+	}
+	if share.Value == nil {
+		return fmt.Errorf("Share with ID %d is missing value", share.ID)
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if _, ok := sc.seen[share.ID]; ok {
+		return fmt.Errorf("%w: ID %d", ErrDuplicateShare, share.ID)
+	}
+	sc.seen[share.ID] = struct{}{}
+	sc.shares = append(sc.shares, share)
+
+	return nil
+}
+
+// Len returns the number of decryption shares collected so far.
+func (sc *ShareCollector) Len() int {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	return len(sc.shares)
+}
+
+// Ready returns whether at least pub.Threshold decryption shares have been
+// collected, such that Recover() may be called. At least one share is
+// required even if the threshold of the public key is unknown, as for keys
+// decoded by ParsePublicKey().
+func (sc *ShareCollector) Ready() bool {
+	n := sc.Len()
+	return n > 0 && n >= sc.pub.Threshold
+}
+
+// Recover decrypts the ciphertext using the decryption shares collected so
+// far, as per Recover().
+//
+// An error is returned if the collector is not ready yet, or if decryption
+// fails.
+func (sc *ShareCollector) Recover(ctxt Ciphertext) ([]byte, error) {
+	sc.mu.Lock()
+	shares := make([]DecryptionShare, len(sc.shares))
+	copy(shares, sc.shares)
+	sc.mu.Unlock()
+
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("%w: no decryption shares collected", ErrThresholdNotMet)
+	}
+
+	return Recover(sc.pub, shares, ctxt)
+}
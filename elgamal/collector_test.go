@@ -0,0 +1,153 @@
+package elgamal
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestShareCollector(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	decShares, err := DecAll(pub, shares, ctxt)
+	if err != nil {
+		t.Fatalf("DecAll returned error: %v", err)
+	}
+
+	collector := NewShareCollector(pub)
+	for i, share := range decShares[:3] {
+		if collector.Ready() {
+			t.Errorf("Expected collector not to be ready with %d shares", i)
+		}
+
+		_, err = collector.Recover(ctxt)
+		if err == nil {
+			t.Errorf("Expected error recovering with %d shares; got none", i)
+		}
+
+		err = collector.Add(share)
+		if err != nil {
+			t.Fatalf("Add returned error: %v", err)
+		}
+	}
+
+	if !collector.Ready() {
+		t.Errorf("Expected collector to be ready with 3 shares")
+	}
+
+	err = collector.Add(decShares[0])
+	if err == nil {
+		t.Errorf("Expected error adding duplicate share; got none")
+	}
+	if collector.Len() != 3 {
+		t.Errorf("Expected 3 collected shares; got %d", collector.Len())
+	}
+
+	recovered, err := collector.Recover(ctxt)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recovered)
+	}
+}
+
+func TestShareCollectorConcurrent(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	msg := make([]byte, 64)
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	decShares, err := DecAll(pub, shares, ctxt)
+	if err != nil {
+		t.Fatalf("DecAll returned error: %v", err)
+	}
+
+	// Every share is added twice, only one of which may succeed
+	collector := NewShareCollector(pub)
+	errs := make(chan error, 2*len(decShares))
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		for _, share := range decShares {
+			wg.Add(1)
+			go func(share DecryptionShare) {
+				defer wg.Done()
+				errs <- collector.Add(share)
+			}(share)
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	failures := 0
+	for err := range errs {
+		if err != nil {
+			failures++
+		}
+	}
+	if failures != len(decShares) {
+		t.Errorf("Expected %d duplicate adds to fail; got %d", len(decShares), failures)
+	}
+	if collector.Len() != len(decShares) {
+		t.Errorf("Expected %d collected shares; got %d", len(decShares), collector.Len())
+	}
+
+	recovered, err := collector.Recover(ctxt)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recovered)
+	}
+}
+
+func TestShareCollectorUnknownThreshold(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 2, 3)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	ctxt, err := Enc(pub, make([]byte, 64))
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	// As decoded by ParsePublicKey()
+	pub.Threshold = 0
+	collector := NewShareCollector(pub)
+	if collector.Ready() {
+		t.Errorf("Expected collector not to be ready without shares")
+	}
+	_, err = collector.Recover(ctxt)
+	if !errors.Is(err, ErrThresholdNotMet) {
+		t.Errorf("Expected ErrThresholdNotMet without shares; got %v", err)
+	}
+
+	share, err := Dec(pub, shares[0], ctxt)
+	if err != nil {
+		t.Fatalf("Dec returned error: %v", err)
+	}
+	err = collector.Add(share)
+	if err != nil {
+		t.Fatalf("Add returned error: %v", err)
+	}
+	if !collector.Ready() {
+		t.Errorf("Expected collector to be ready with one share and unknown threshold")
+	}
+}
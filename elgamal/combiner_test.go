@@ -0,0 +1,151 @@
+package elgamal
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestCombiner(t *testing.T) {
+	pub, _, privShares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	parties := []PrivateKeyShare{privShares[4], privShares[0], privShares[2]}
+	ids := make([]int, len(parties))
+	for i, share := range parties {
+		ids[i] = share.ID
+	}
+
+	combiner, err := NewCombiner(pub, ids)
+	if err != nil {
+		t.Fatalf("NewCombiner returned error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		msg := bytes.Repeat([]byte{byte(i)}, 64)
+		ctxt, err := Enc(pub, msg)
+		if err != nil {
+			t.Fatalf("Enc returned error: %v", err)
+		}
+
+		decShares, err := DecAll(pub, parties, ctxt)
+		if err != nil {
+			t.Fatalf("DecAll returned error: %v", err)
+		}
+		// Order of shares must not matter
+		decShares[0], decShares[2] = decShares[2], decShares[0]
+
+		combined, err := combiner.Combine(decShares, ctxt)
+		if err != nil {
+			t.Fatalf("Combine returned error: %v", err)
+		}
+		recovered, err := Recover(pub, decShares, ctxt)
+		if err != nil {
+			t.Fatalf("Recover returned error: %v", err)
+		}
+
+		if !bytes.Equal(combined, msg) {
+			t.Errorf("Expected combined message %x; got %x", msg, combined)
+		}
+		if !bytes.Equal(combined, recovered) {
+			t.Errorf("Expected Combine to match Recover; got %x and %x", combined, recovered)
+		}
+
+		// Shares of a party outside the combiner's
+		other, err := Dec(pub, privShares[1], ctxt)
+		if err != nil {
+			t.Fatalf("Dec returned error: %v", err)
+		}
+		_, err = combiner.Combine([]DecryptionShare{decShares[0], decShares[1], other}, ctxt)
+		if err == nil {
+			t.Errorf("Expected error with share of unknown party; got none")
+		}
+		_, err = combiner.Combine(decShares[:2], ctxt)
+		if err == nil {
+			t.Errorf("Expected error with too few shares; got none")
+		}
+		_, err = combiner.Combine([]DecryptionShare{decShares[0], decShares[1], decShares[1]}, ctxt)
+		if !errors.Is(err, ErrDuplicateShare) {
+			t.Errorf("Expected ErrDuplicateShare with duplicate share; got %v", err)
+		}
+		for _, value := range []*big.Int{nil, big.NewInt(0), pub.P} {
+			invalid := []DecryptionShare{decShares[0], decShares[1], {ID: decShares[2].ID, Value: value}}
+			_, err = combiner.Combine(invalid, ctxt)
+			if err == nil {
+				t.Errorf("Expected error with share value %v; got none", value)
+			}
+		}
+	}
+
+	_, err = NewCombiner(pub, ids[:2])
+	if !errors.Is(err, ErrThresholdNotMet) {
+		t.Errorf("Expected ErrThresholdNotMet with too few parties; got %v", err)
+	}
+	_, err = NewCombiner(pub, []int{1, 2, 2})
+	if !errors.Is(err, ErrDuplicateShare) {
+		t.Errorf("Expected ErrDuplicateShare with duplicate party; got %v", err)
+	}
+	_, err = NewCombiner(pub, []int{0, 1, 2})
+	if err == nil {
+		t.Errorf("Expected error with party ID 0; got none")
+	}
+}
+
+func benchmarkCombinerSetup(b *testing.B) (PublicKey, []int, [][]DecryptionShare, []Ciphertext) {
+	f := newBenchmarkFixture(b, 1024, 256, 3, 5)
+	pub := f.pub
+	parties := f.keyShares[:3]
+	ids := []int{parties[0].ID, parties[1].ID, parties[2].ID}
+
+	shares := make([][]DecryptionShare, 1000)
+	ctxts := make([]Ciphertext, 1000)
+	for i := range ctxts {
+		var err error
+		ctxts[i], err = Enc(pub, make([]byte, MessageSize(pub)))
+		if err != nil {
+			b.Fatalf("Enc returned error: %v", err)
+		}
+		shares[i], err = DecAll(pub, parties, ctxts[i])
+		if err != nil {
+			b.Fatalf("DecAll returned error: %v", err)
+		}
+	}
+
+	return pub, ids, shares, ctxts
+}
+
+func BenchmarkRecover1000(b *testing.B) {
+	pub, _, shares, ctxts := benchmarkCombinerSetup(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for j, ctxt := range ctxts {
+			_, err := Recover(pub, shares[j], ctxt)
+			if err != nil {
+				b.Fatalf("Recover returned error: %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkCombiner1000(b *testing.B) {
+	pub, ids, shares, ctxts := benchmarkCombinerSetup(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		// Including precomputation
+		combiner, err := NewCombiner(pub, ids)
+		if err != nil {
+			b.Fatalf("NewCombiner returned error: %v", err)
+		}
+		for j, ctxt := range ctxts {
+			_, err := combiner.Combine(shares[j], ctxt)
+			if err != nil {
+				b.Fatalf("Combine returned error: %v", err)
+			}
+		}
+	}
+}
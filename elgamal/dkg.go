@@ -0,0 +1,271 @@
+package elgamal
+
+import (
+	"fmt"
+	"github.com/lavode/secret-sharing/gf"
+	"github.com/lavode/secret-sharing/secretshare"
+	"math/big"
+	"sort"
+)
+
+// DealerlessKeygen represents a single party's state in a Pedersen-style
+// distributed key generation, which - unlike KeyGen() - does not require a
+// trusted dealer. No party ever learns the private key x.
+//
+// The protocol between n parties with IDs 1 to n proceeds as follows:
+// 1. Every party i creates its state with NewDealerlessKeygen(), sampling a random polynomial f_i of degree t-1
+// 2. Every party i broadcasts its Feldman commitments Commitments()
+// 3. Every party i privately sends SubShare(j) = f_i(j) to every other party j
+// 4. Every party j checks each received sub-share with Receive(), complaining about senders of inconsistent ones
+// 5. Complaints() are broadcast, and every party calls Disqualify() for each party complained about
+// 6. Every party derives its private key share and the joint public key with Finish()
+//
+// The resulting private key x is the sum of the constant terms of the
+// polynomials of all qualified parties.
+type DealerlessKeygen struct {
+	group SchnorrGroup
+	id    int
+	t     int
+	n     int
+
+	zp gf.GF
+	zq gf.GF
+
+	poly        gf.Polynomial
+	commitments []*big.Int
+
+	// Sub-shares and commitments received from other parties, by their ID
+	received            map[int]*big.Int
+	receivedCommitments map[int][]*big.Int
+
+	complaints   []int
+	disqualified map[int]bool
+}
+
+// NewDealerlessKeygen sets up the state of party id in a distributed
+// t-out-of-n key generation over the passed Schnorr group.
+//
+// An error is returned if t, n or id are out of range, or if sourcing of
+// randomness fails.
+func NewDealerlessKeygen(group SchnorrGroup, id int, t int, n int) (*DealerlessKeygen, error) {
+	if t < 1 || t > n {
+		return nil, fmt.Errorf("t must be in [1, n]; got t = %d, n = %d", t, n)
+	}
+	if id < 1 || id > n {
+		return nil, fmt.Errorf("id must be in [1, n]; got id = %d, n = %d", id, n)
+	}
+
+	dkg := &DealerlessKeygen{
+		group:               group,
+		id:                  id,
+		t:                   t,
+		n:                   n,
+		received:            make(map[int]*big.Int),
+		receivedCommitments: make(map[int][]*big.Int),
+		disqualified:        make(map[int]bool),
+	}
+
+	var err error
+	dkg.zp, err = group.Zp()
+	if err != nil {
+		return nil, err
+	}
+	dkg.zq, err = group.Zq()
+	if err != nil {
+		return nil, err
+	}
+	if !dkg.zq.IsGroupElement(big.NewInt(int64(n))) {
+		return nil, fmt.Errorf("n must be < q")
+	}
+
+	dkg.poly, err = dkg.zq.RandomPolynomial(t - 1)
+	if err != nil {
+		return nil, err
+	}
+	dkg.commitments = feldmanCommitments(dkg.zp, group.G, dkg.poly)
+
+	// Our own sub-share needs no verification
+	own, err := dkg.poly.Evaluate(big.NewInt(int64(id)))
+	if err != nil {
+		return nil, err
+	}
+	dkg.received[id] = own
+	dkg.receivedCommitments[id] = dkg.commitments
+
+	return dkg, nil
+}
+
+// ID returns the ID of the party.
+func (dkg *DealerlessKeygen) ID() int {
+	return dkg.id
+}
+
+// Commitments returns the Feldman commitments g^{a_j} mod p to the
+// coefficients of the party's polynomial, which are to be broadcast to all
+// other parties.
+func (dkg *DealerlessKeygen) Commitments() []*big.Int {
+	out := make([]*big.Int, len(dkg.commitments))
+	for i, commitment := range dkg.commitments {
+		out[i] = new(big.Int).Set(commitment)
+	}
+
+	return out
+}
+
+// SubShare returns the sub-share f_i(to) of the party's polynomial for the
+// party with the passed ID, which is to be sent privately to it.
+func (dkg *DealerlessKeygen) SubShare(to int) (PrivateKeyShare, error) {
+	var share PrivateKeyShare
+
+	if to < 1 || to > dkg.n {
+		return share, fmt.Errorf("Recipient must be in [1, %d]; got %d", dkg.n, to)
+	}
+
+	value, err := dkg.poly.Evaluate(big.NewInt(int64(to)))
+	if err != nil {
+		return share, err
+	}
+
+	share.ID = to
+	share.Value = value
+
+	return share, nil
+}
+
+// Receive processes the sub-share sent by party from, verifying it against
+// the commitments broadcast by that party.
+//
+// If the sub-share is inconsistent with the commitments, or if any commitment
+// is not an element of the subgroup of order q, a complaint against the
+// sender is recorded and an error is returned.
+func (dkg *DealerlessKeygen) Receive(from int, subShare PrivateKeyShare, commitments []*big.Int) error {
+	if from < 1 || from > dkg.n || from == dkg.id {
+		return fmt.Errorf("Sender must be in [1, %d] and not ourselves; got %d", dkg.n, from)
+	}
+	if _, ok := dkg.received[from]; ok {
+		return fmt.Errorf("Already received sub-share from party %d", from)
+	}
+
+	valid := subShare.ID == dkg.id &&
+		len(commitments) == dkg.t &&
+		dkg.commitmentsInSubgroup(commitments) &&
+		subShare.Value != nil &&
+		dkg.zq.IsGroupElement(subShare.Value) &&
+		verifyFeldman(dkg.zp, dkg.zq, dkg.group.G, commitments, dkg.id, subShare.Value)
+	if !valid {
+		dkg.complaints = append(dkg.complaints, from)
+		return fmt.Errorf("Sub-share from party %d is inconsistent with its commitments", from)
+	}
+
+	dkg.received[from] = new(big.Int).Set(subShare.Value)
+	dkg.receivedCommitments[from] = commitments
+
+	return nil
+}
+
+// commitmentsInSubgroup checks whether all passed commitments are elements of
+// the subgroup of order q.
+func (dkg *DealerlessKeygen) commitmentsInSubgroup(commitments []*big.Int) bool {
+	for _, commitment := range commitments {
+		if !isSubgroupElement(dkg.zp, dkg.group.Q, commitment) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Complaints returns the IDs of the parties which sent inconsistent
+// sub-shares, which are to be broadcast to all other parties.
+func (dkg *DealerlessKeygen) Complaints() []int {
+	out := make([]int, len(dkg.complaints))
+	copy(out, dkg.complaints)
+
+	return out
+}
+
+// Disqualify excludes the party with the passed ID from the key generation,
+// such that its polynomial does not contribute to the private key. All
+// parties - including a disqualified party itself - must disqualify the same
+// set of parties, such as every party which any party complained about.
+func (dkg *DealerlessKeygen) Disqualify(id int) error {
+	if id < 1 || id > dkg.n {
+		return fmt.Errorf("ID must be in [1, %d]; got %d", dkg.n, id)
+	}
+	dkg.disqualified[id] = true
+
+	return nil
+}
+
+// Finish derives the party's share of the private key, and the joint public
+// key, from the sub-shares and commitments of all qualified parties.
+//
+// The public key includes the verification keys of all n parties. An error is
+// returned if a sub-share of a qualified party is missing. An error wrapping
+// ErrThresholdNotMet is returned if fewer than t parties remain qualified, and
+// one wrapping ErrDegenerateSecret if the joint public key is 1.
+func (dkg *DealerlessKeygen) Finish() (PublicKey, PrivateKeyShare, error) {
+	var pub PublicKey
+	var share PrivateKeyShare
+
+	var qualified []int
+	for i := 1; i <= dkg.n; i++ {
+		if dkg.disqualified[i] {
+			continue
+		}
+		if _, ok := dkg.received[i]; !ok {
+			return pub, share, fmt.Errorf("Missing sub-share from qualified party %d", i)
+		}
+		qualified = append(qualified, i)
+	}
+	sort.Ints(qualified)
+	if len(qualified) < dkg.t {
+		return pub, share, fmt.Errorf("%w: need at least %d qualified parties; got %d", ErrThresholdNotMet, dkg.t, len(qualified))
+	}
+
+	// x_j = sum_{i in QUAL} f_i(j)
+	value := big.NewInt(0)
+	// Commitments to the joint polynomial f = sum_{i in QUAL} f_i
+	commitments := make([]*big.Int, dkg.t)
+	for j := range commitments {
+		commitments[j] = big.NewInt(1)
+	}
+
+	for _, i := range qualified {
+		value = dkg.zq.Add(value, dkg.received[i])
+		for j, commitment := range dkg.receivedCommitments[i] {
+			commitments[j] = dkg.zp.Mul(commitments[j], commitment)
+		}
+	}
+
+	// y = 1 corresponds to the private key 0, under which encryption is
+	// the identity
+	if commitments[0].Cmp(big.NewInt(1)) == 0 {
+		return pub, share, fmt.Errorf("%w: joint public key is 1", ErrDegenerateSecret)
+	}
+
+	pub.SchnorrGroup = dkg.group
+	pub.Threshold = dkg.t
+	pub.Commitments = commitments
+	pub.fields = newFieldCache()
+	// y = g^{f(0)} = prod_{i in QUAL} g^{a_{i,0}}
+	pub.Y = commitments[0]
+
+	pub.VerificationKeys = make([]VerificationKey, dkg.n)
+	for k := 1; k <= dkg.n; k++ {
+		// g^{x_k} = prod_j C_j^{k^j}
+		x := big.NewInt(int64(k))
+		vk := big.NewInt(1)
+		for j, commitment := range commitments {
+			exp := dkg.zq.Exp(x, big.NewInt(int64(j)))
+			vk = dkg.zp.Mul(vk, dkg.zp.Exp(commitment, exp))
+		}
+
+		pub.VerificationKeys[k-1] = VerificationKey(secretshare.Share{ID: k, Value: vk})
+	}
+
+	share.ID = dkg.id
+	share.Value = value
+
+	return pub, share, nil
+}
@@ -0,0 +1,264 @@
+package elgamal
+
+import (
+	"bytes"
+	"errors"
+	"github.com/lavode/secret-sharing/secretshare"
+	"math/big"
+	"testing"
+)
+
+// runDealerlessKeygen simulates a distributed key generation between n
+// parties. Party `cheater`, if non-zero, sends a corrupted sub-share to party
+// `victim`.
+func runDealerlessKeygen(t *testing.T, group SchnorrGroup, threshold int, n int, cheater int, victim int) ([]PublicKey, []PrivateKeyShare, [][]int) {
+	parties := make([]*DealerlessKeygen, n)
+	for i := range parties {
+		dkg, err := NewDealerlessKeygen(group, i+1, threshold, n)
+		if err != nil {
+			t.Fatalf("NewDealerlessKeygen returned error: %v", err)
+		}
+		parties[i] = dkg
+	}
+
+	for _, sender := range parties {
+		commitments := sender.Commitments()
+
+		for _, recipient := range parties {
+			if sender.ID() == recipient.ID() {
+				continue
+			}
+
+			subShare, err := sender.SubShare(recipient.ID())
+			if err != nil {
+				t.Fatalf("SubShare returned error: %v", err)
+			}
+			if sender.ID() == cheater && recipient.ID() == victim {
+				subShare.Value = new(big.Int).Add(subShare.Value, big.NewInt(1))
+			}
+
+			err = recipient.Receive(sender.ID(), subShare, commitments)
+			isCheat := sender.ID() == cheater && recipient.ID() == victim
+			if isCheat && err == nil {
+				t.Errorf("Expected error for corrupted sub-share from %d to %d; got none", sender.ID(), recipient.ID())
+			}
+			if !isCheat && err != nil {
+				t.Errorf("Receive returned error: %v", err)
+			}
+		}
+	}
+
+	// Broadcast complaints, and disqualify everyone complained about
+	complaints := make([][]int, n)
+	for i, party := range parties {
+		complaints[i] = party.Complaints()
+	}
+	for _, party := range parties {
+		for _, partyComplaints := range complaints {
+			for _, accused := range partyComplaints {
+				err := party.Disqualify(accused)
+				if err != nil {
+					t.Fatalf("Disqualify returned error: %v", err)
+				}
+			}
+		}
+	}
+
+	pubs := make([]PublicKey, n)
+	shares := make([]PrivateKeyShare, n)
+	for i, party := range parties {
+		pub, share, err := party.Finish()
+		if err != nil {
+			t.Fatalf("Finish returned error: %v", err)
+		}
+		pubs[i] = pub
+		shares[i] = share
+	}
+
+	return pubs, shares, complaints
+}
+
+func TestDealerlessKeygen(t *testing.T) {
+	group, err := GenerateSchnorrGroup(512, 128)
+	if err != nil {
+		t.Fatalf("Error generating Schnorr group: %v", err)
+	}
+
+	pubs, shares, _ := runDealerlessKeygen(t, group, 3, 5, 0, 0)
+
+	pub := pubs[0]
+	for _, other := range pubs[1:] {
+		if other.Y.Cmp(pub.Y) != 0 {
+			t.Errorf("Expected all parties to derive y = %d; got %d", pub.Y, other.Y)
+		}
+	}
+
+	zq, err := pub.Zq()
+	if err != nil {
+		t.Fatalf("Error generating field: %v", err)
+	}
+
+	recoverShares := []secretshare.Share{
+		secretshare.Share(shares[0]),
+		secretshare.Share(shares[2]),
+		secretshare.Share(shares[4]),
+	}
+	x, err := secretshare.TOutOfNRecover(recoverShares, zq)
+	if err != nil {
+		t.Fatalf("Error recovering private key: %v", err)
+	}
+
+	var y = &big.Int{}
+	y.Exp(pub.G, x, pub.P)
+	if y.Cmp(pub.Y) != 0 {
+		t.Errorf("Expected reconstructed private key to match y = %d; got g^x = %d", pub.Y, y)
+	}
+
+	for i, share := range shares {
+		var vk = &big.Int{}
+		vk.Exp(pub.G, share.Value, pub.P)
+		if pub.VerificationKeys[i].ID != share.ID || pub.VerificationKeys[i].Value.Cmp(vk) != 0 {
+			t.Errorf("Expected verification key %d = %d; got %+v", share.ID, vk, pub.VerificationKeys[i])
+		}
+	}
+
+	// Threshold decryption with the jointly generated key
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	decShares := make([]DecryptionShare, 3)
+	for i, share := range []PrivateKeyShare{shares[1], shares[2], shares[3]} {
+		decShares[i], err = Dec(pub, share, ctxt)
+		if err != nil {
+			t.Fatalf("Dec returned error: %v", err)
+		}
+	}
+
+	recov, err := Recover(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if !bytes.Equal(msg, recov) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recov)
+	}
+}
+
+func TestDealerlessKeygenComplaint(t *testing.T) {
+	group, err := GenerateSchnorrGroup(512, 128)
+	if err != nil {
+		t.Fatalf("Error generating Schnorr group: %v", err)
+	}
+
+	pubs, shares, complaints := runDealerlessKeygen(t, group, 3, 5, 2, 4)
+
+	if len(complaints[3]) != 1 || complaints[3][0] != 2 {
+		t.Errorf("Expected party 4 to complain about party 2; got %v", complaints[3])
+	}
+	for i, partyComplaints := range complaints {
+		if i != 3 && len(partyComplaints) != 0 {
+			t.Errorf("Expected no complaints from party %d; got %v", i+1, partyComplaints)
+		}
+	}
+
+	pub := pubs[0]
+	for _, other := range pubs[1:] {
+		if other.Y.Cmp(pub.Y) != 0 {
+			t.Errorf("Expected all parties to derive y = %d; got %d", pub.Y, other.Y)
+		}
+	}
+
+	zq, err := pub.Zq()
+	if err != nil {
+		t.Fatalf("Error generating field: %v", err)
+	}
+
+	recoverShares := []secretshare.Share{
+		secretshare.Share(shares[1]),
+		secretshare.Share(shares[3]),
+		secretshare.Share(shares[4]),
+	}
+	x, err := secretshare.TOutOfNRecover(recoverShares, zq)
+	if err != nil {
+		t.Fatalf("Error recovering private key: %v", err)
+	}
+
+	var y = &big.Int{}
+	y.Exp(pub.G, x, pub.P)
+	if y.Cmp(pub.Y) != 0 {
+		t.Errorf("Expected reconstructed private key to match y = %d; got g^x = %d", pub.Y, y)
+	}
+}
+
+func TestDealerlessKeygenTooFewQualified(t *testing.T) {
+	group := SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(11), G: big.NewInt(4)}
+
+	dkg, err := NewDealerlessKeygen(group, 1, 2, 3)
+	if err != nil {
+		t.Fatalf("NewDealerlessKeygen returned error: %v", err)
+	}
+	for id := 1; id <= 3; id++ {
+		err = dkg.Disqualify(id)
+		if err != nil {
+			t.Fatalf("Disqualify returned error: %v", err)
+		}
+	}
+
+	_, _, err = dkg.Finish()
+	if !errors.Is(err, ErrThresholdNotMet) {
+		t.Errorf("Expected ErrThresholdNotMet with all parties disqualified; got %v", err)
+	}
+}
+
+func TestDealerlessKeygenCommitmentSubgroup(t *testing.T) {
+	group := SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(11), G: big.NewInt(4)}
+
+	sender, err := NewDealerlessKeygen(group, 1, 2, 3)
+	if err != nil {
+		t.Fatalf("NewDealerlessKeygen returned error: %v", err)
+	}
+	recipient, err := NewDealerlessKeygen(group, 2, 2, 3)
+	if err != nil {
+		t.Fatalf("NewDealerlessKeygen returned error: %v", err)
+	}
+
+	subShare, err := sender.SubShare(2)
+	if err != nil {
+		t.Fatalf("SubShare returned error: %v", err)
+	}
+
+	// -C_1 is of order 2q, but raised to the even ID 2 it still satisfies
+	// the Feldman check
+	commitments := sender.Commitments()
+	commitments[1] = new(big.Int).Sub(group.P, commitments[1])
+
+	err = recipient.Receive(1, subShare, commitments)
+	if err == nil {
+		t.Errorf("Expected error with commitment outside the subgroup; got none")
+	}
+	complaints := recipient.Complaints()
+	if len(complaints) != 1 || complaints[0] != 1 {
+		t.Errorf("Expected complaint about party 1; got %v", complaints)
+	}
+}
+
+func TestNewDealerlessKeygenParameters(t *testing.T) {
+	group := SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(11), G: big.NewInt(4)}
+
+	invalid := []struct{ id, t, n int }{
+		{1, 0, 5},
+		{1, 6, 5},
+		{0, 3, 5},
+		{6, 3, 5},
+		{1, 3, 11},
+	}
+	for _, params := range invalid {
+		_, err := NewDealerlessKeygen(group, params.id, params.t, params.n)
+		if err == nil {
+			t.Errorf("Expected error for id = %d, t = %d, n = %d; got none", params.id, params.t, params.n)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package elgamal
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// DiscreteLogTable is a precomputed table of baby steps for solving g^m =
+// target mod p for m in [0, bound) using the baby-step giant-step algorithm.
+//
+// Building the table takes O(sqrt(bound)) time and memory, after which each
+// lookup takes O(sqrt(bound)) time. Callers recovering many values under the
+// same generator, e.g. tallies of multiple elections, should build the table
+// once and reuse it. It is safe for concurrent use.
+type DiscreteLogTable struct {
+	p     *big.Int
+	bound int64
+	// Number of baby steps, ceil(sqrt(bound))
+	m int64
+	// Maps g^j to j, for j in [0, m)
+	baby map[string]int64
+	// g^{-m}
+	giant *big.Int
+}
+
+// NewDiscreteLogTable precomputes a table for solving discrete logarithms to
+// base g modulo p, for logarithms in [0, bound).
+//
+// An error is returned if bound is not positive, or if g is not invertible
+// modulo p.
+func NewDiscreteLogTable(g *big.Int, p *big.Int, bound int64) (*DiscreteLogTable, error) {
+	if bound <= 0 {
+		return nil, fmt.Errorf("Bound must be > 0; got %d", bound)
+	}
+
+	m := int64(1)
+	for m*m < bound {
+		m++
+	}
+
+	table := &DiscreteLogTable{
+		p:     p,
+		bound: bound,
+		m:     m,
+		baby:  make(map[string]int64, m),
+	}
+
+	elem := big.NewInt(1) // g^0
+	for j := int64(0); j < m; j++ {
+		key := string(elem.Bytes())
+		// Only keep the smallest exponent, if the order of g is below m
+		if _, ok := table.baby[key]; !ok {
+			table.baby[key] = j
+		}
+
+		elem = new(big.Int).Mul(elem, g)
+		elem.Mod(elem, p)
+	}
+
+	// elem = g^m at this point
+	table.giant = new(big.Int).ModInverse(elem, p)
+	if table.giant == nil {
+		return nil, fmt.Errorf("Generator %d is not invertible modulo %d", g, p)
+	}
+
+	return table, nil
+}
+
+// Lookup returns the smallest m in [0, bound) such that g^m = target mod p.
+//
+// An error is returned if there is no such m.
+func (table *DiscreteLogTable) Lookup(target *big.Int) (*big.Int, error) {
+	gamma := new(big.Int).Mod(target, table.p)
+
+	// Giant steps: target * g^{-i * m}
+	for i := int64(0); i*table.m < table.bound; i++ {
+		if j, ok := table.baby[string(gamma.Bytes())]; ok {
+			x := i*table.m + j
+			if x < table.bound {
+				return big.NewInt(x), nil
+			}
+		}
+
+		gamma.Mul(gamma, table.giant)
+		gamma.Mod(gamma, table.p)
+	}
+
+	return nil, fmt.Errorf("No discrete logarithm below %d found", table.bound)
+}
+
+// DiscreteLog returns the smallest m in [0, bound) such that g^m = target mod
+// p, using the baby-step giant-step algorithm in O(sqrt(bound)) time and
+// memory.
+//
+// An error is returned if there is no such m. To solve multiple discrete
+// logarithms with the same generator, use NewDiscreteLogTable() instead.
+func DiscreteLog(g *big.Int, target *big.Int, p *big.Int, bound int64) (*big.Int, error) {
+	table, err := NewDiscreteLogTable(g, p, bound)
+	if err != nil {
+		return nil, err
+	}
+
+	return table.Lookup(target)
+}
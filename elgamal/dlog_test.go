@@ -0,0 +1,81 @@
+package elgamal
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestDiscreteLog(t *testing.T) {
+	g := big.NewInt(4)
+	p := big.NewInt(23)
+
+	// 4^7 = 16384 = 8 mod 23
+	m, err := DiscreteLog(g, big.NewInt(8), p, 11)
+	if err != nil {
+		t.Fatalf("DiscreteLog returned error: %v", err)
+	}
+	if m.Cmp(big.NewInt(7)) != 0 {
+		t.Errorf("Expected m = 7; got %d", m)
+	}
+
+	// Outside of bound
+	_, err = DiscreteLog(g, big.NewInt(8), p, 7)
+	if err == nil {
+		t.Errorf("Expected error if m is outside of bound; got none")
+	}
+
+	// Not in subgroup generated by g
+	_, err = DiscreteLog(g, big.NewInt(5), p, 100)
+	if err == nil {
+		t.Errorf("Expected error if target is not a power of g; got none")
+	}
+
+	_, err = DiscreteLog(g, big.NewInt(8), p, 0)
+	if err == nil {
+		t.Errorf("Expected error with bound of 0; got none")
+	}
+}
+
+func TestDiscreteLogTable(t *testing.T) {
+	g := big.NewInt(4)
+	p := big.NewInt(23)
+
+	// Order of g is 11, so the bound exceeding it must still yield the
+	// smallest logarithm.
+	table, err := NewDiscreteLogTable(g, p, 50)
+	if err != nil {
+		t.Fatalf("NewDiscreteLogTable returned error: %v", err)
+	}
+
+	for expected := int64(0); expected < 11; expected++ {
+		target := new(big.Int).Exp(g, big.NewInt(expected), p)
+		m, err := table.Lookup(target)
+		if err != nil {
+			t.Fatalf("Lookup returned error for m = %d: %v", expected, err)
+		}
+		if m.Int64() != expected {
+			t.Errorf("Expected m = %d; got %d", expected, m)
+		}
+	}
+
+	// Larger group, with logarithms in the millions
+	pub, _, _, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	bound := int64(4000000)
+	table, err = NewDiscreteLogTable(pub.G, pub.P, bound)
+	if err != nil {
+		t.Fatalf("NewDiscreteLogTable returned error: %v", err)
+	}
+	for _, expected := range []int64{0, 1, 1999, 3999999} {
+		target := new(big.Int).Exp(pub.G, big.NewInt(expected), pub.P)
+		m, err := table.Lookup(target)
+		if err != nil {
+			t.Fatalf("Lookup returned error for m = %d: %v", expected, err)
+		}
+		if m.Int64() != expected {
+			t.Errorf("Expected m = %d; got %d", expected, m)
+		}
+	}
+}
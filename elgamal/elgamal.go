@@ -1,38 +1,321 @@
 package elgamal
 
 import (
-	"crypto/sha512"
+	"bytes"
+	"context"
+	"crypto"
+	_ "crypto/sha256" // Registers SHA256
+	_ "crypto/sha512" // Registers SHA384 and SHA512
+	"encoding/hex"
 	"fmt"
 	"github.com/lavode/secret-sharing/gf"
 	"github.com/lavode/secret-sharing/secretshare"
+	"io"
 	"math/big"
+	"sort"
+	"sync"
 )
 
-// hashByteSize is the size - in bytes - of the hash algorithm used by this
-// implementation of hashed ElGamal.
+// defaultHash is the hash algorithm used by this implementation of hashed
+// ElGamal, unless a public key specifies a different one.
 // In our case we use SHA512, hence 64 bytes.
-// All messages to encrypt must contain exactly hashByteSize bytes, and all
-// ciphertexts will also be of the same length.
-const hashByteSize int = 64
+const defaultHash crypto.Hash = crypto.SHA512
+
+// supportedHashes are the hash algorithms which a public key may specify.
+var supportedHashes = []crypto.Hash{
+	crypto.SHA256,
+	crypto.SHA384,
+	crypto.SHA512,
+}
 
 // PublicKey represents a public key of the ElGamal cryptosystem.
+//
+// A public key may be used by many goroutines at once, e.g. calling Enc(),
+// Dec() and Recover() concurrently, as long as none of them modifies it. Its
+// memoized fields are populated exactly once, guarded by a sync.Once.
 type PublicKey struct {
 	SchnorrGroup
 
 	// Public key y = g^x mod p
 	Y *big.Int
+
+	// Hash algorithm used to derive the key stream from y^r. The zero
+	// value selects SHA512.
+	// All messages to encrypt must contain exactly as many bytes as the
+	// output of the hash algorithm, and all ciphertexts will also be of
+	// the same length.
+	Hash crypto.Hash
+
+	// Per-party verification keys g^{x_i} mod p, one for each private key
+	// share. May be empty if the key was not generated by KeyGen().
+	VerificationKeys []VerificationKey
+
+	// Number of decryption shares required to recover a message. Zero if
+	// unknown, in which case recovery does not check the number of
+	// shares.
+	Threshold int
+
+	// Feldman commitments g^{a_j} mod p to the coefficients a_j of the
+	// polynomial used to share the private key, allowing parties to
+	// verify their shares with VerifyKeyShare(). May be empty if the key
+	// was not generated by KeyGen().
+	Commitments []*big.Int
+
+	// Memoized fields (Z/pZ) and (Z/qZ). May be nil, in which case the
+	// fields are constructed on every call to Zp() and Zq().
+	fields *fieldCache
+}
+
+// fieldCache memoizes the fields of a public key, as constructing them - which
+// involves a primality test - dominates repeated operations on the same key.
+//
+// It is shared between all copies of a public key, and safe for concurrent
+// use.
+type fieldCache struct {
+	once sync.Once
+
+	// Copies of the moduli the fields were constructed for
+	p *big.Int
+	q *big.Int
+
+	zp  gf.GF
+	zq  gf.GF
+	err error
+
+	// Whether q divides p-1
+	divides bool
+}
+
+// newFieldCache returns an empty field cache, which will be populated on first
+// use.
+func newFieldCache() *fieldCache {
+	return &fieldCache{}
+}
+
+// cachedFields returns the memoized fields of the public key, populating the
+// cache on first use. False is returned if the key has no cache, if
+// constructing the fields failed, or if P or Q changed since the cache was
+// populated.
+func (pk *PublicKey) cachedFields() (gf.GF, gf.GF, bool) {
+	cache := pk.fields
+	if cache == nil || pk.P == nil || pk.Q == nil {
+		return gf.GF{}, gf.GF{}, false
+	}
+
+	cache.once.Do(func() {
+		cache.p = new(big.Int).Set(pk.P)
+		cache.q = new(big.Int).Set(pk.Q)
+
+		cache.zp, cache.err = gf.NewGF(cache.p)
+		if cache.err != nil {
+			return
+		}
+		cache.zq, cache.err = gf.NewGF(cache.q)
+		cache.divides = qDividesPMinusOne(cache.p, cache.q)
+	})
+
+	if cache.err != nil || cache.p.Cmp(pk.P) != 0 || cache.q.Cmp(pk.Q) != 0 {
+		return gf.GF{}, gf.GF{}, false
+	}
+
+	return cache.zp, cache.zq, true
+}
+
+// checkGroup performs a cheap consistency check of the group of the public
+// key, returning an error wrapping ErrInvalidGroup if q does not divide p-1.
+// For keys with memoized fields, the result is memoized too.
+func (pk *PublicKey) checkGroup() error {
+	if _, _, ok := pk.cachedFields(); ok && pk.fields.divides {
+		return nil
+	}
+
+	if pk.P == nil || pk.Q == nil {
+		return fmt.Errorf("%w: p or q is missing", ErrInvalidGroup)
+	}
+	if !qDividesPMinusOne(pk.P, pk.Q) {
+		return fmt.Errorf("%w: q does not divide p-1", ErrInvalidGroup)
+	}
+
+	return nil
+}
+
+// checkComplete returns an error naming the first of P, Q, G and Y which is
+// nil, rather than letting math/big panic on it later. Missing group
+// parameters yield an error wrapping ErrInvalidGroup.
+func (pk *PublicKey) checkComplete() error {
+	for _, param := range []struct {
+		name  string
+		value *big.Int
+	}{{"P", pk.P}, {"Q", pk.Q}, {"G", pk.G}} {
+		if param.value == nil {
+			return fmt.Errorf("%w: public key %s is nil", ErrInvalidGroup, param.name)
+		}
+	}
+	if pk.Y == nil {
+		return fmt.Errorf("Public key Y is nil")
+	}
+
+	return nil
+}
+
+// checkComplete returns an error wrapping ErrInvalidCiphertext if R or C of
+// the ciphertext is nil.
+func (ctxt *Ciphertext) checkComplete() error {
+	if ctxt.R == nil {
+		return fmt.Errorf("%w: R is nil", ErrInvalidCiphertext)
+	}
+	if ctxt.C == nil {
+		return fmt.Errorf("%w: C is nil", ErrInvalidCiphertext)
+	}
+
+	return nil
+}
+
+// qDividesPMinusOne returns whether q is positive and divides p-1.
+func qDividesPMinusOne(p *big.Int, q *big.Int) bool {
+	if q.Sign() <= 0 {
+		return false
+	}
+
+	rem := new(big.Int).Sub(p, big.NewInt(1))
+	return rem.Mod(rem, q).Sign() == 0
+}
+
+// hashFunc returns the hash algorithm of the public key, or an error if it is
+// not supported.
+func (pk *PublicKey) hashFunc() (crypto.Hash, error) {
+	if pk.Hash == 0 {
+		return defaultHash, nil
+	}
+
+	for _, hash := range supportedHashes {
+		if pk.Hash == hash {
+			return hash, nil
+		}
+	}
+
+	return pk.Hash, fmt.Errorf("Unsupported hash algorithm %v", pk.Hash)
+}
+
+// BlockSize returns the size - in bytes - of messages and ciphertexts under
+// this public key, which is the output size of its hash algorithm. Zero is
+// returned if the hash algorithm is not supported.
+func (pk *PublicKey) BlockSize() int {
+	hash, err := pk.hashFunc()
+	if err != nil {
+		return 0
+	}
+
+	return hash.Size()
+}
+
+// MessageSize returns the length - in bytes - which messages passed to Enc()
+// must have under the passed public key, that is 64 with the default hash
+// algorithm SHA512. It is equivalent to pub.BlockSize(), and zero if the hash
+// algorithm of the key is not supported.
+func MessageSize(pub PublicKey) int {
+	return pub.BlockSize()
 }
 
 // Zp returns the finite field (Z / pZ), which G - over which the ElGamal
 // cryptosystem is defined - is a subgroup of.
+//
+// If the key was generated by KeyGen() or decoded from JSON, the field is
+// memoized.
 func (pk *PublicKey) Zp() (gf.GF, error) {
-	return gf.NewGF(pk.P)
+	zp, _, ok := pk.cachedFields()
+	if ok {
+		return zp, nil
+	}
+
+	return pk.SchnorrGroup.Zp()
 }
 
 // Zq returns the finite field (Z / qZ), which is used in the secret sharing
 // scheme.
+//
+// If the key was generated by KeyGen() or decoded from JSON, the field is
+// memoized.
 func (pk *PublicKey) Zq() (gf.GF, error) {
-	return gf.NewGF(pk.Q)
+	_, zq, ok := pk.cachedFields()
+	if ok {
+		return zq, nil
+	}
+
+	return pk.SchnorrGroup.Zq()
+}
+
+// newField returns the finite field of the passed prime order. An error
+// wrapping ErrInvalidGroup is returned if the order is missing or not prime.
+// The name of the order is used in error messages.
+func newField(name string, order *big.Int) (gf.GF, error) {
+	if order == nil {
+		return gf.GF{}, fmt.Errorf("%w: %s is missing", ErrInvalidGroup, name)
+	}
+
+	field, err := gf.NewGF(order)
+	if err != nil {
+		return field, fmt.Errorf("%w: %v", ErrInvalidGroup, err)
+	}
+
+	return field, nil
+}
+
+// VerificationKey returns the verification key of the party with the passed
+// ID, or an error if the public key holds no such verification key.
+func (pk *PublicKey) VerificationKey(id int) (VerificationKey, error) {
+	for _, vk := range pk.VerificationKeys {
+		if vk.ID == id {
+			return vk, nil
+		}
+	}
+
+	return VerificationKey{}, fmt.Errorf("No verification key with ID %d", id)
+}
+
+// Fingerprint returns a short, stable identifier of the public key, for use
+// in logs and user interfaces. It is the hex-encoded SHA256 hash of P, Q, G
+// and Y, each encoded as its big-endian bytes prefixed with their length.
+//
+// The fingerprint only depends on the values of P, Q, G and Y, not on the
+// hash algorithm, threshold or verification keys of the key. Missing values
+// are treated as empty.
+func (pk PublicKey) Fingerprint() string {
+	var data []byte
+	for _, x := range []*big.Int{pk.P, pk.Q, pk.G, pk.Y} {
+		var b []byte
+		if x != nil {
+			b = x.Bytes()
+		}
+		data = appendLengthPrefixed(data, b)
+	}
+
+	h := crypto.SHA256.New()
+	h.Write(data)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MatchesPrivate checks whether the passed private key belongs to the public
+// key, that is whether g^x = y. This allows to catch corrupted shares or
+// mismatched groups after reconstructing a private key.
+//
+// An error is returned if the private key is missing x, or if the field
+// (Z/pZ) cannot be constructed.
+func (pk *PublicKey) MatchesPrivate(priv PrivateKey) (bool, error) {
+	if priv.X == nil {
+		return false, fmt.Errorf("Private key is missing x")
+	}
+	if pk.Y == nil {
+		return false, fmt.Errorf("Public key is missing y")
+	}
+
+	zp, err := pk.Zp()
+	if err != nil {
+		return false, err
+	}
+
+	return zp.Exp(pk.G, priv.X).Cmp(pk.Y) == 0, nil
 }
 
 // PrivateKey represents a private key of the ElGamal cryptosystem.
@@ -48,12 +331,64 @@ type PrivateKeyShare secretshare.Share
 // DecryptionShare represents a single party's decryption share.
 type DecryptionShare secretshare.Share
 
+// VerificationKey represents the public commitment g^{x_i} mod p to a single
+// party's private key share x_i.
+type VerificationKey secretshare.Share
+
 // Ciphertext represents a ciphertext of the hashed ElGamal cryptosystem.
 type Ciphertext struct {
 	// R = g^x mod p
 	R *big.Int
-	// C = H(y^r) XOR m
+	// C = H(y^r) XOR m, or C = H(y^r || Label) XOR m if labeled
 	C []byte
+	// Optional label binding the ciphertext to its intended context, such
+	// as a tenant. Empty for unlabeled ciphertexts.
+	Label []byte
+	// Optional integrity tag, as produced by EncAD(). Empty for
+	// unauthenticated ciphertexts.
+	Tag []byte
+
+	// Byte length of p of the public key the ciphertext was created under,
+	// to which R is left-padded in the binary encoding. Zero if unknown, in
+	// which case R is encoded in its minimal form.
+	rSize int
+}
+
+// Equal reports whether both ciphertexts have equal R, C, label and tag. Two
+// nil values of R are considered equal.
+func (ctxt Ciphertext) Equal(other Ciphertext) bool {
+	if (ctxt.R == nil) != (other.R == nil) {
+		return false
+	}
+	if ctxt.R != nil && ctxt.R.Cmp(other.R) != 0 {
+		return false
+	}
+
+	return bytes.Equal(ctxt.C, other.C) && bytes.Equal(ctxt.Label, other.Label) && bytes.Equal(ctxt.Tag, other.Tag)
+}
+
+// Clone returns a deep copy of the ciphertext, such that mutating the copy
+// does not affect the original.
+func (ctxt Ciphertext) Clone() Ciphertext {
+	clone := Ciphertext{rSize: ctxt.rSize}
+
+	if ctxt.R != nil {
+		clone.R = new(big.Int).Set(ctxt.R)
+	}
+	if ctxt.C != nil {
+		clone.C = make([]byte, len(ctxt.C))
+		copy(clone.C, ctxt.C)
+	}
+	if ctxt.Label != nil {
+		clone.Label = make([]byte, len(ctxt.Label))
+		copy(clone.Label, ctxt.Label)
+	}
+	if ctxt.Tag != nil {
+		clone.Tag = make([]byte, len(ctxt.Tag))
+		copy(clone.Tag, ctxt.Tag)
+	}
+
+	return clone
 }
 
 // KeyGen implements key generation for a distributed ElGamal cryptosystem. It
@@ -67,19 +402,147 @@ type Ciphertext struct {
 // - qBits: Bit length of prime order of subgroup G over which ElGamal operates
 // - t: Number of secret shares which should be able to reconstruct private key
 // - n: Number of total secret shares to generate
+//
+// An error is returned if t < 1, n < 1 or t > n.
 func KeyGen(pBits int, qBits int, t int, n int) (PublicKey, PrivateKey, []PrivateKeyShare, error) {
+	return KeyGenContext(context.Background(), pBits, qBits, t, n)
+}
+
+// KeyGenContext is like KeyGen(), but aborts the generation of the Schnorr
+// group once ctx is done, returning ctx.Err(). This allows to bound the
+// otherwise unpredictable time spent searching for primes.
+func KeyGenContext(ctx context.Context, pBits int, qBits int, t int, n int) (PublicKey, PrivateKey, []PrivateKeyShare, error) {
+	return keyGen(ctx, nil, pBits, qBits, t, n)
+}
+
+// KeyGenWithReader is like KeyGen(), but sources all randomness - for the
+// Schnorr group, the private key and the sharing polynomial - from the passed
+// reader, e.g. a hardware RNG. If the reader is nil, crypto/rand's Reader is
+// used.
+//
+// Given a deterministic reader, the generated keys are reproducible, which
+// is useful for test vectors.
+func KeyGenWithReader(random io.Reader, pBits int, qBits int, t int, n int) (PublicKey, PrivateKey, []PrivateKeyShare, error) {
+	return keyGen(context.Background(), random, pBits, qBits, t, n)
+}
+
+// KeyGenSeeded is like KeyGen(), but derives all randomness - for the Schnorr
+// group, the private key and the sharing polynomial - from the passed seed,
+// using HMAC-SHA256 in counter mode as CSPRNG.
+//
+// Two calls with the same seed and parameters yield identical keys and
+// shares, which is useful for cross-implementation test vectors. Anyone
+// knowing the seed can recompute the private key, so it must be kept as
+// secret as the key itself, and contain sufficient entropy if used outside
+// of tests.
+//
+// An error is returned if the seed is empty, or as with KeyGen().
+func KeyGenSeeded(seed []byte, pBits int, qBits int, t int, n int) (PublicKey, PrivateKey, []PrivateKeyShare, error) {
+	if len(seed) == 0 {
+		return PublicKey{}, PrivateKey{}, nil, fmt.Errorf("Seed must not be empty")
+	}
+
+	return keyGen(context.Background(), newSeededReader(seed), pBits, qBits, t, n)
+}
+
+// RekeyInGroup generates a fresh private key, public key and t-out-of-n
+// sharing like KeyGen(), but within the passed, existing, Schnorr group. This
+// allows to rotate keys without the expensive search for primes, while
+// keeping a vetted group.
+//
+// An error is returned if the group is invalid as per SchnorrGroup.Validate(),
+// or if t < 1, n < 1 or t > n.
+func RekeyInGroup(group SchnorrGroup, t int, n int) (PublicKey, PrivateKey, []PrivateKeyShare, error) {
+	err := checkThreshold(t, n)
+	if err != nil {
+		return PublicKey{}, PrivateKey{}, nil, err
+	}
+
+	err = group.Validate()
+	if err != nil {
+		return PublicKey{}, PrivateKey{}, nil, err
+	}
+
+	return keyGenInGroup(nil, group, t, n)
+}
+
+// KeyGenRange is like KeyGen(), but draws the private key x from
+// [2^{qBits-1}, q) rather than [0, q), such that it always has exactly qBits
+// bits. This rules out trivially small private keys such as 0 or 1, as some
+// protocols require, at the cost of less than one bit of entropy.
+//
+// An error is returned as with KeyGen().
+func KeyGenRange(pBits int, qBits int, t int, n int) (PublicKey, PrivateKey, []PrivateKeyShare, error) {
+	err := checkThreshold(t, n)
+	if err != nil {
+		return PublicKey{}, PrivateKey{}, nil, err
+	}
+
+	schnorr, err := generateSchnorrGroup(context.Background(), nil, pBits, qBits, defaultPrimeChecks)
+	if err != nil {
+		return PublicKey{}, PrivateKey{}, make([]PrivateKeyShare, n), err
+	}
+
+	minX := new(big.Int).Lsh(big.NewInt(1), uint(schnorr.Q.BitLen()-1)) // 2^{qBits-1}
+	return keyGenInGroupFrom(nil, schnorr, t, n, minX)
+}
+
+// keyGen implements key generation, checking ctx for cancellation and
+// sourcing randomness from the passed reader.
+func keyGen(ctx context.Context, random io.Reader, pBits int, qBits int, t int, n int) (PublicKey, PrivateKey, []PrivateKeyShare, error) {
+	// Checking parameters before the expensive generation of the group
+	err := checkThreshold(t, n)
+	if err != nil {
+		return PublicKey{}, PrivateKey{}, nil, err
+	}
+
+	schnorr, err := generateSchnorrGroup(ctx, random, pBits, qBits, defaultPrimeChecks)
+	if err != nil {
+		return PublicKey{}, PrivateKey{}, make([]PrivateKeyShare, n), err
+	}
+
+	return keyGenInGroup(random, schnorr, t, n)
+}
+
+// checkThreshold checks the parameters of a t-out-of-n sharing.
+func checkThreshold(t int, n int) error {
+	if t < 1 {
+		return fmt.Errorf("t must be >= 1; got %d", t)
+	}
+	if n < 1 {
+		return fmt.Errorf("n must be >= 1; got %d", n)
+	}
+	if t > n {
+		return fmt.Errorf("t must be <= n; got t = %d, n = %d", t, n)
+	}
+
+	return nil
+}
+
+// keyGenInGroup generates a private key within the passed Schnorr group, and
+// shares it t-out-of-n, sourcing randomness from the passed reader.
+func keyGenInGroup(random io.Reader, schnorr SchnorrGroup, t int, n int) (PublicKey, PrivateKey, []PrivateKeyShare, error) {
+	return keyGenInGroupFrom(random, schnorr, t, n, nil)
+}
+
+// keyGenInGroupFrom is like keyGenInGroup(), but draws the private key from
+// [minX, q). If minX is nil, it is drawn from [0, q).
+func keyGenInGroupFrom(random io.Reader, schnorr SchnorrGroup, t int, n int, minX *big.Int) (PublicKey, PrivateKey, []PrivateKeyShare, error) {
 	var pub PublicKey
 	var priv PrivateKey
-	shares := make([]PrivateKeyShare, n)
 
-	schnorr, err := GenerateSchnorrGroup(pBits, qBits)
+	err := checkThreshold(t, n)
 	if err != nil {
-		return pub, priv, shares, err
+		return pub, priv, nil, err
 	}
 
+	shares := make([]PrivateKeyShare, n)
+
 	pub.P = schnorr.P
 	pub.Q = schnorr.Q
 	pub.G = schnorr.G
+	pub.Threshold = t
+	pub.fields = newFieldCache()
 
 	// (Z/qZ) is used for:
 	// - Generation of a private key x, such that `g^x` is an element of G
@@ -96,7 +559,12 @@ func KeyGen(pBits int, qBits int, t int, n int) (PublicKey, PrivateKey, []Privat
 		return pub, priv, shares, err
 	}
 
-	x, err := zq.Rand()
+	var x *big.Int
+	if minX == nil {
+		x, err = randomInt(random, zq.P)
+	} else {
+		x, err = randomInRange(random, minX, zq.P) // [minX, q)
+	}
 	if err != nil {
 		return pub, priv, shares, err
 	}
@@ -104,104 +572,718 @@ func KeyGen(pBits int, qBits int, t int, n int) (PublicKey, PrivateKey, []Privat
 
 	pub.Y = zp.Exp(pub.G, x)
 
-	tnShares, _, err := secretshare.TOutOfN(priv.X, t, n, zq)
-	if err != nil {
-		return pub, priv, shares, err
+	// Sharing polynomial of degree t-1 with f(0) = x, over (Z/qZ)
+	poly := gf.Polynomial{
+		Field:        zq,
+		Coefficients: make([]*big.Int, t),
 	}
-	for i, share := range tnShares {
-		shares[i] = PrivateKeyShare(share)
+	poly.Coefficients[0] = x
+	for i := 1; i < t; i++ {
+		poly.Coefficients[i], err = randomInt(random, zq.P)
+		if err != nil {
+			return pub, priv, shares, err
+		}
+	}
+
+	pub.Commitments = feldmanCommitments(zp, pub.G, poly)
+	pub.VerificationKeys = make([]VerificationKey, n)
+	for i := range shares {
+		id := i + 1
+		value, err := poly.Evaluate(big.NewInt(int64(id))) // x_i = f(i)
+		if err != nil {
+			return pub, priv, shares, err
+		}
+
+		shares[i] = PrivateKeyShare{ID: id, Value: value}
+		pub.VerificationKeys[i] = VerificationKey(
+			secretshare.Share{
+				ID:    id,
+				Value: zp.Exp(pub.G, value), // g^{x_i} mod p
+			},
+		)
 	}
 
 	return pub, priv, shares, nil
 }
 
-// Enc encrypts a message using hashed ElGamal.
+// Enc encrypts a message using hashed ElGamal. Its random exponent r is not
+// checked against a NonceTracker, for which EncTracked() is to be used.
 //
 // Parameters:
 // - pub: Public key to use for encryption
-// - message: Message to encrypt. Must be of length hashByteSize
+// - message: Message to encrypt. Must be of length pub.BlockSize()
 //
 // An error is returned if encryption fails.
 func Enc(pub PublicKey, message []byte) (Ciphertext, error) {
+	return EncTracked(pub, message, nil)
+}
+
+// EncWithReader encrypts a message like Enc(), but sources the random
+// exponent r from the passed reader, e.g. a hardware RNG. If the reader is
+// nil, crypto/rand's Reader is used.
+//
+// Given a deterministic reader, the ciphertext is reproducible, which is
+// useful for test vectors. Never use a deterministic reader in production,
+// as reuse of r leaks the XOR of the plaintexts.
+func EncWithReader(random io.Reader, pub PublicKey, message []byte) (Ciphertext, error) {
+	ctxt, _, err := encrypt(random, pub, message, nil, nil)
+	return ctxt, err
+}
+
+// EncTracked encrypts a message using hashed ElGamal, consulting the passed
+// nonce tracker to reject any random exponent r which was used before.
+//
+// Parameters:
+// - pub: Public key to use for encryption
+// - message: Message to encrypt. Must be of length pub.BlockSize()
+// - tracker: Nonce tracker to consult. May be nil to disable tracking
+//
+// An error is returned if encryption fails, or if the tracker rejects the
+// nonce.
+func EncTracked(pub PublicKey, message []byte, tracker NonceTracker) (Ciphertext, error) {
+	ctxt, _, err := encrypt(nil, pub, message, nil, tracker)
+	return ctxt, err
+}
+
+// EncLabeled encrypts a message using hashed ElGamal, binding the ciphertext
+// to the passed label. The label is stored in the ciphertext, and hashed into
+// the key stream, such that RecoverLabeled() refuses to decrypt it under a
+// different label.
+//
+// Parameters:
+// - pub: Public key to use for encryption
+// - message: Message to encrypt. Must be of length pub.BlockSize()
+// - label: Label to bind the ciphertext to, e.g. a tenant ID
+//
+// An error is returned if encryption fails.
+func EncLabeled(pub PublicKey, message []byte, label []byte) (Ciphertext, error) {
+	ctxt, _, err := encrypt(nil, pub, message, label, nil)
+	return ctxt, err
+}
+
+// EncWithEphemeral encrypts a message like Enc(), additionally returning the
+// ephemeral exponent r with R = g^r which was used. This allows to prove
+// properties of the ciphertext, e.g. using ProveEncryption().
+//
+// The returned exponent is a copy, and must be kept secret, as it allows to
+// decrypt the ciphertext using the public key alone.
+func EncWithEphemeral(pub PublicKey, message []byte) (Ciphertext, *big.Int, error) {
+	ctxt, r, err := encrypt(nil, pub, message, nil, nil)
+	if err != nil {
+		return ctxt, nil, err
+	}
+
+	return ctxt, new(big.Int).Set(r), nil
+}
+
+// EncWithRandomness encrypts a message using hashed ElGamal, using the passed
+// exponent r rather than sourcing it randomly. This allows to produce
+// deterministic ciphertexts, e.g. for test vectors.
+//
+// Never reuse r for multiple encryptions under the same key, as this leaks
+// the XOR of the plaintexts.
+//
+// Parameters:
+// - pub: Public key to use for encryption
+// - message: Message to encrypt. Must be of length pub.BlockSize()
+// - r: Exponent to use. Must be in [1, q)
+//
+// An error is returned if r is out of range, or if encryption fails.
+func EncWithRandomness(pub PublicKey, message []byte, r *big.Int) (Ciphertext, error) {
+	if r == nil || r.Sign() <= 0 || r.Cmp(pub.Q) >= 0 {
+		return Ciphertext{}, fmt.Errorf("r must be in [1, q)")
+	}
+
+	return encryptWithRandomness(pub, message, nil, r)
+}
+
+// encrypt implements hashed ElGamal encryption with a random exponent r,
+// optionally binding the ciphertext to a label, and optionally consulting a
+// nonce tracker. The exponent r is sourced from the passed reader, or
+// crypto/rand's Reader if it is nil, and returned alongside the ciphertext.
+func encrypt(random io.Reader, pub PublicKey, message []byte, label []byte, tracker NonceTracker) (Ciphertext, *big.Int, error) {
 	var ctxt Ciphertext
-	// Using SHA512
-	ctxt.C = make([]byte, hashByteSize)
 
-	if len(message) != hashByteSize {
-		return ctxt, fmt.Errorf("Message must be %d bytes; got %d", hashByteSize, len(message))
+	_, err := checkMessage(&pub, message)
+	if err != nil {
+		return ctxt, nil, err
 	}
 
-	zq, err := pub.Zq()
+	// r = 0 would yield R = y^r = 1, so we draw from [1, q)
+	r, err := randomExponent(random, &pub)
 	if err != nil {
-		return ctxt, err
+		return ctxt, nil, err
 	}
-	zp, err := pub.Zp()
+	if tracker != nil {
+		err = tracker.Check(r)
+		if err != nil {
+			return ctxt, nil, err
+		}
+	}
+
+	ctxt, err = encryptWithRandomness(pub, message, label, r)
+	return ctxt, r, err
+}
+
+// encryptWithRandomness implements hashed ElGamal encryption with the passed
+// exponent r, optionally binding the ciphertext to a label.
+func encryptWithRandomness(pub PublicKey, message []byte, label []byte, r *big.Int) (Ciphertext, error) {
+	var ctxt Ciphertext
+
+	hash, err := checkMessage(&pub, message)
 	if err != nil {
 		return ctxt, err
 	}
 
-	r, err := zq.Rand()
+	zp, err := pub.Zp()
 	if err != nil {
 		return ctxt, err
 	}
+
+	return encryptInField(zp, hash, &pub, message, label, r)
+}
+
+// encryptInField implements hashed ElGamal encryption with the passed
+// exponent r, operating over the passed, precomputed, field (Z/pZ). The
+// message must already have been checked to be of the correct length.
+//
+// An error wrapping ErrDegenerateSecret is returned if y^r is 0 or 1.
+func encryptInField(zp gf.GF, hash crypto.Hash, pub *PublicKey, message []byte, label []byte, r *big.Int) (Ciphertext, error) {
+	var ctxt Ciphertext
+
 	ctxt.R = zp.Exp(pub.G, r) // g^r = R
+	ctxt.rSize = (zp.P.BitLen() + 7) / 8
 
 	yr := zp.Exp(pub.Y, r) // y^r
+	err := checkSharedSecret(yr)
+	if err != nil {
+		return Ciphertext{}, err
+	}
 
-	key := sha512.Sum512(yr.Bytes())
-
-	for i, keyByte := range key {
-		ctxt.C[i] = message[i] ^ keyByte
+	if len(label) > 0 {
+		ctxt.Label = make([]byte, len(label))
+		copy(ctxt.Label, label)
 	}
+	ctxt.C = hashedXOR(hash, yr, zp.P, ctxt.Label, message)
 
 	return ctxt, nil
 }
 
-// Dec creates a single decryption share of a ciphertext based on the passed
-// share of the private key.
-//
-// t of these can be passed to Recover() to decrypt the ciphertext.
-func Dec(pub PublicKey, keyShare PrivateKeyShare, ctxt Ciphertext) (DecryptionShare, error) {
-	decryptionShare := DecryptionShare(
-		secretshare.Share{
-			ID: keyShare.ID,
-		},
-	)
-
-	zp, err := pub.Zp()
-	if err != nil {
-		return decryptionShare, err
+// checkSharedSecret returns an error wrapping ErrDegenerateSecret if the
+// shared secret z = y^r = R^x is 0 or 1. In a valid group with a valid key
+// and ciphertext, z is an element of the order-q subgroup other than 1, so
+// either value indicates a broken group, key or ciphertext, and would yield a
+// key stream independent of the private key.
+func checkSharedSecret(z *big.Int) error {
+	if z.Sign() == 0 || z.Cmp(big.NewInt(1)) == 0 {
+		return fmt.Errorf("%w: y^r = %d", ErrDegenerateSecret, z)
 	}
 
-	// While the coefficients of the secret sharing polynomials are over
-	// (Z/qZ), the values (by virtue of being a power of a generator of G)
-	// are in (Z/pZ)
-	decryptionShare.Value = zp.Exp(ctxt.R, keyShare.Value) // R^{x_i} mod p
+	return nil
+}
 
-	return decryptionShare, nil
+// EncBatch encrypts many messages under the same public key, as if calling
+// Enc() for each of them. The returned ciphertexts are in the same order as
+// the messages.
+//
+// Fields and hash algorithm are only set up once, and the exponentiations
+// are distributed across a pool of at most GOMAXPROCS workers. Every message
+// is encrypted with its own random exponent r.
+//
+// An error identifying the index of the offending message is returned if
+// any message is not of length pub.BlockSize(). No ciphertexts are returned
+// in this case.
+func EncBatch(pub PublicKey, messages [][]byte) ([]Ciphertext, error) {
+	return EncBatchTracked(pub, messages, nil)
 }
 
-// Recover decrypts a ciphertext using t decryption shares.
-func Recover(pub PublicKey, decryptionShares []DecryptionShare, ctxt Ciphertext) ([]byte, error) {
-	msg := make([]byte, hashByteSize)
+// EncBatchTracked encrypts many messages like EncBatch(), consulting the
+// passed nonce tracker - if not nil - to reject any random exponent r which
+// was used before.
+//
+// An error identifying the index of the offending message is returned if
+// the tracker rejects its nonce. No ciphertexts are returned in this case,
+// though the nonces of preceding messages remain recorded as used.
+func EncBatchTracked(pub PublicKey, messages [][]byte, tracker NonceTracker) ([]Ciphertext, error) {
+	hash, err := pub.hashFunc()
+	if err != nil {
+		return nil, err
+	}
+	for i, message := range messages {
+		if len(message) != hash.Size() {
+			return nil, fmt.Errorf("%w: message %d must be %d bytes; got %d", ErrMessageLength, i, hash.Size(), len(message))
+		}
+	}
 
-	xs := make([]*big.Int, len(decryptionShares))
-	for i, share := range decryptionShares {
-		xs[i] = big.NewInt(int64(share.ID))
+	err = pub.checkGroup()
+	if err != nil {
+		return nil, err
 	}
 
 	zp, err := pub.Zp()
 	if err != nil {
-		return msg, err
+		return nil, err
 	}
 
-	// Mind that secret sharing happens over (Z/qZ)
-	zq, err := pub.Zq()
-	if err != nil {
-		return msg, err
+	rs := make([]*big.Int, len(messages))
+	for i := range rs {
+		rs[i], err = randomExponent(nil, &pub)
+		if err != nil {
+			return nil, err
+		}
+		if tracker != nil {
+			err = tracker.Check(rs[i])
+			if err != nil {
+				return nil, fmt.Errorf("Message %d: %w", i, err)
+			}
+		}
 	}
 
-	// Starting with 1, as identity of multiplication
+	ctxts := make([]Ciphertext, len(messages))
+	errs := make([]error, len(messages))
+	parallelFor(len(messages), func(i int) {
+		ctxts[i], errs[i] = encryptInField(zp, hash, &pub, messages[i], nil, rs[i])
+	})
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("Message %d: %w", i, err)
+		}
+	}
+
+	return ctxts, nil
+}
+
+// EncMulti encrypts the same message to many independent recipients, as if
+// calling Enc() with each of their public keys. The returned ciphertexts are
+// in the same order as the public keys.
+//
+// Every ciphertext is encrypted with its own random exponent r, so the
+// ciphertexts are unlinkable. Reusing r across recipients would allow anyone
+// knowing one recipient's plaintext to decrypt the others'. Like EncBatch(),
+// all keys are checked before any exponentiation, the memoized fields of each
+// key are used, and the exponentiations are distributed across a pool of at
+// most GOMAXPROCS workers.
+//
+// An error identifying the index of the offending recipient is returned if
+// the message is not of length pub.BlockSize() of any public key, or if any
+// public key is invalid. No ciphertexts are returned in this case.
+func EncMulti(pubs []PublicKey, message []byte) ([]Ciphertext, error) {
+	hashes := make([]crypto.Hash, len(pubs))
+	fields := make([]gf.GF, len(pubs))
+	for i := range pubs {
+		hash, err := checkMessage(&pubs[i], message)
+		if err != nil {
+			return nil, fmt.Errorf("Recipient %d: %w", i, err)
+		}
+		hashes[i] = hash
+
+		fields[i], err = pubs[i].Zp()
+		if err != nil {
+			return nil, fmt.Errorf("Recipient %d: %w", i, err)
+		}
+	}
+
+	rs := make([]*big.Int, len(pubs))
+	for i := range rs {
+		var err error
+		rs[i], err = randomExponent(nil, &pubs[i])
+		if err != nil {
+			return nil, fmt.Errorf("Recipient %d: %w", i, err)
+		}
+	}
+
+	ctxts := make([]Ciphertext, len(pubs))
+	errs := make([]error, len(pubs))
+	parallelFor(len(pubs), func(i int) {
+		ctxts[i], errs[i] = encryptInField(fields[i], hashes[i], &pubs[i], message, nil, rs[i])
+	})
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("Recipient %d: %w", i, err)
+		}
+	}
+
+	return ctxts, nil
+}
+
+// checkMessage checks that the message is of the block size of the public
+// key, returning the key's hash algorithm. The group of the key is checked
+// for consistency as per checkGroup().
+func checkMessage(pub *PublicKey, message []byte) (crypto.Hash, error) {
+	hash, err := pub.hashFunc()
+	if err != nil {
+		return hash, err
+	}
+
+	err = pub.checkComplete()
+	if err != nil {
+		return hash, err
+	}
+
+	err = pub.checkGroup()
+	if err != nil {
+		return hash, err
+	}
+
+	if len(message) != hash.Size() {
+		return hash, fmt.Errorf("%w: must be %d bytes; got %d", ErrMessageLength, hash.Size(), len(message))
+	}
+
+	return hash, nil
+}
+
+// EncExact encrypts a message using hashed ElGamal, requiring the message to
+// be exactly pub.BlockSize() bytes.
+//
+// This is the strict contract of Enc(), but a message of the wrong length
+// yields an error wrapping ErrNotBlockAligned, allowing callers who pre-pad
+// their messages to branch on it.
+func EncExact(pub PublicKey, message []byte) (Ciphertext, error) {
+	blockSize := pub.BlockSize()
+	if blockSize != 0 && len(message) != blockSize {
+		return Ciphertext{}, fmt.Errorf("%w: must be %d bytes; got %d", ErrNotBlockAligned, blockSize, len(message))
+	}
+
+	return Enc(pub, message)
+}
+
+// Dec creates a single decryption share of a ciphertext based on the passed
+// share of the private key.
+//
+// t of these can be passed to Recover() to decrypt the ciphertext.
+//
+// An error wrapping ErrInvalidCiphertext is returned if R is not an element
+// of the order-q subgroup, see DecWithSubgroupCheck().
+func Dec(pub PublicKey, keyShare PrivateKeyShare, ctxt Ciphertext) (DecryptionShare, error) {
+	return DecWithSubgroupCheck(pub, keyShare, ctxt, true)
+}
+
+// DecWithSubgroupCheck creates a single decryption share like Dec(), allowing
+// to skip the check that R is an element of the order-q subgroup.
+//
+// Without this check, a malicious encryptor may choose R of small order, e.g.
+// p-1 of order 2, in which case the decryption share R^{x_i} leaks x_i mod the
+// order of R. As the check costs an exponentiation R^q, about doubling the
+// cost of a decryption share, it may be disabled if ciphertexts are known to
+// be well-formed, e.g. as they are authenticated.
+//
+// If checkSubgroup is set, an error wrapping ErrInvalidCiphertext is returned
+// if R is not in [1, p) or R^q mod p != 1.
+func DecWithSubgroupCheck(pub PublicKey, keyShare PrivateKeyShare, ctxt Ciphertext, checkSubgroup bool) (DecryptionShare, error) {
+	decryptionShare := DecryptionShare(
+		secretshare.Share{
+			ID: keyShare.ID,
+		},
+	)
+
+	err := pub.checkComplete()
+	if err != nil {
+		return decryptionShare, err
+	}
+	if ctxt.R == nil {
+		return decryptionShare, fmt.Errorf("%w: R is nil", ErrInvalidCiphertext)
+	}
+	if keyShare.Value == nil {
+		return decryptionShare, fmt.Errorf("Private key share %d is nil", keyShare.ID)
+	}
+
+	err = pub.checkGroup()
+	if err != nil {
+		return decryptionShare, err
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return decryptionShare, err
+	}
+
+	if checkSubgroup {
+		err = checkSubgroupElement(zp, pub.Q, ctxt.R)
+		if err != nil {
+			return decryptionShare, err
+		}
+	}
+
+	// While the coefficients of the secret sharing polynomials are over
+	// (Z/qZ), the values (by virtue of being a power of a generator of G)
+	// are in (Z/pZ)
+	decryptionShare.Value = zp.Exp(ctxt.R, keyShare.Value) // R^{x_i} mod p
+
+	return decryptionShare, nil
+}
+
+// checkSubgroupElement returns an error wrapping ErrInvalidCiphertext unless R
+// is in [1, p) and of order dividing q, that is R^q mod p = 1.
+func checkSubgroupElement(zp gf.GF, q *big.Int, r *big.Int) error {
+	if r == nil {
+		return fmt.Errorf("%w: missing R", ErrInvalidCiphertext)
+	}
+	if r.Sign() <= 0 || r.Cmp(zp.P) >= 0 {
+		return fmt.Errorf("%w: R must be in [1, p)", ErrInvalidCiphertext)
+	}
+	if zp.Exp(r, q).Cmp(big.NewInt(1)) != 0 {
+		return fmt.Errorf("%w: R is not in the order-q subgroup", ErrInvalidCiphertext)
+	}
+
+	return nil
+}
+
+// DecConstantTime creates a single decryption share like Dec(), but hardens
+// the exponentiation R^{x_i} with the secret exponent x_i against timing side
+// channels.
+//
+// Rather than the sliding-window exponentiation of math/big, whose running
+// time depends on the exponent, it uses a Montgomery ladder which runs over
+// the full bit length of q and performs the same operations for every bit.
+// As math/big does not guarantee constant-time arithmetic, this is a
+// best-effort mitigation, and considerably slower than Dec().
+//
+// As with Dec(), an error wrapping ErrInvalidCiphertext is returned unless R
+// is an element of the subgroup of order q, such that no information about
+// x_i leaks through R of small order. An error is also returned if the public
+// key is incomplete or its group inconsistent, or if the key share is not in
+// [0, q).
+func DecConstantTime(pub PublicKey, keyShare PrivateKeyShare, ctxt Ciphertext) (DecryptionShare, error) {
+	decryptionShare := DecryptionShare(
+		secretshare.Share{
+			ID: keyShare.ID,
+		},
+	)
+
+	err := pub.checkComplete()
+	if err != nil {
+		return decryptionShare, err
+	}
+	err = pub.checkGroup()
+	if err != nil {
+		return decryptionShare, err
+	}
+
+	if keyShare.Value == nil || keyShare.Value.Sign() < 0 || keyShare.Value.Cmp(pub.Q) >= 0 {
+		return decryptionShare, fmt.Errorf("Key share must be in [0, q)")
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return decryptionShare, err
+	}
+	err = checkSubgroupElement(zp, pub.Q, ctxt.R)
+	if err != nil {
+		return decryptionShare, err
+	}
+
+	decryptionShare.Value = expLadder(ctxt.R, keyShare.Value, pub.P, pub.Q.BitLen()) // R^{x_i} mod p
+
+	return decryptionShare, nil
+}
+
+// DecAll creates the decryption shares of a ciphertext for all passed shares
+// of the private key, as if calling Dec() for each of them. The returned
+// decryption shares are in the same order as the key shares.
+//
+// Shares are computed concurrently by a pool of at most GOMAXPROCS workers,
+// which is useful when simulating many parties within a single process.
+//
+// If Dec() fails for any key share, e.g. as the ciphertext is invalid, the
+// error of the first such key share is returned, and no decryption shares.
+func DecAll(pub PublicKey, keyShares []PrivateKeyShare, ctxt Ciphertext) ([]DecryptionShare, error) {
+	decryptionShares := make([]DecryptionShare, len(keyShares))
+	errs := make([]error, len(keyShares))
+
+	// Each call only writes to its own elements of the outputs, and the
+	// memoized fields of the public key are safe for concurrent use.
+	parallelFor(len(keyShares), func(i int) {
+		decryptionShares[i], errs[i] = Dec(pub, keyShares[i], ctxt)
+	})
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return decryptionShares, nil
+}
+
+// Recover decrypts a ciphertext using t decryption shares.
+//
+// More than t shares may be passed. All shares lie on the same polynomial of
+// degree t-1 (in the exponent), so interpolating over all passed shares
+// yields the same result as interpolating over any t of them; the Lagrange
+// coefficients are computed for exactly the set of passed IDs. If the
+// threshold of the public key is known, an error is returned if fewer than t
+// shares are passed. An error is also returned if multiple shares have the
+// same ID, for which interpolation is undefined.
+func Recover(pub PublicKey, decryptionShares []DecryptionShare, ctxt Ciphertext) ([]byte, error) {
+	return RecoverWithProgress(pub, decryptionShares, ctxt, nil)
+}
+
+// RecoverWithProgress decrypts a ciphertext like Recover(), invoking the
+// passed callback - if not nil - after each decryption share was incorporated
+// into the decryption factor, e.g. to display progress in a UI.
+//
+// The callback is passed the number of shares incorporated so far, and the
+// total number of shares to incorporate, which is the number of passed
+// shares. It is called synchronously from the calling goroutine, once per
+// share, in the order the shares were passed.
+func RecoverWithProgress(pub PublicKey, decryptionShares []DecryptionShare, ctxt Ciphertext, progress func(collected, needed int)) ([]byte, error) {
+	var msg []byte
+
+	hash, err := pub.hashFunc()
+	if err != nil {
+		return msg, err
+	}
+	err = pub.checkComplete()
+	if err != nil {
+		return msg, err
+	}
+	err = pub.checkGroup()
+	if err != nil {
+		return msg, err
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return msg, err
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return msg, err
+	}
+
+	return recoverInFields(zp, zq, hash, pub.Threshold, decryptionShares, ctxt, progress)
+}
+
+// CombineShares combines t decryption shares R^{x_i} into the decryption
+// factor z = R^x = y^r, by Lagrange interpolation in the exponent.
+//
+// This is the group element which Recover() hashes to obtain the key stream.
+// It is exposed for debugging and for building custom protocols on top of
+// the decryption shares.
+//
+// An error is returned if fewer than t decryption shares are passed, if
+// multiple shares have the same ID, or if the group of the public key is
+// invalid.
+func CombineShares(pub PublicKey, decryptionShares []DecryptionShare) (*big.Int, error) {
+	return combineSharesOf(pub, decryptionShares, nil)
+}
+
+// combineSharesOf implements CombineShares(), invoking the passed progress
+// callback - if not nil - after each incorporated share.
+func combineSharesOf(pub PublicKey, decryptionShares []DecryptionShare, progress func(collected, needed int)) (*big.Int, error) {
+	err := pub.checkGroup()
+	if err != nil {
+		return nil, err
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkDecryptionShares(zp, decryptionShares, pub.Threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mind that secret sharing happens over (Z/qZ)
+	zq, err := pub.Zq()
+	if err != nil {
+		return nil, err
+	}
+
+	return combineSharesWithProgress(zp, zq, decryptionShares, progress), nil
+}
+
+// checkDecryptionShares checks that at least threshold decryption shares are
+// passed, and that they have distinct IDs and values in [1, p).
+func checkDecryptionShares(zp gf.GF, decryptionShares []DecryptionShare, threshold int) error {
+	if len(decryptionShares) < threshold {
+		return fmt.Errorf("%w: need at least %d decryption shares; got %d", ErrThresholdNotMet, threshold, len(decryptionShares))
+	}
+
+	// Duplicate IDs would lead to a division by zero when computing the
+	// Lagrange coefficients
+	seen := make(map[int]bool, len(decryptionShares))
+	for _, share := range decryptionShares {
+		if seen[share.ID] {
+			return fmt.Errorf("%w: decryption share with ID %d", ErrDuplicateShare, share.ID)
+		}
+		seen[share.ID] = true
+
+		if share.Value == nil {
+			return fmt.Errorf("Decryption share %d is nil", share.ID)
+		}
+		if share.Value.Sign() <= 0 || !zp.IsGroupElement(share.Value) {
+			return fmt.Errorf("Decryption share %d must be in [1, p)", share.ID)
+		}
+	}
+
+	return nil
+}
+
+// RecoverInFields decrypts a ciphertext using t decryption shares, operating
+// over the passed, precomputed, fields (Z/pZ) and (Z/qZ), and the passed hash
+// algorithm of the public key. As with PublicKey.Hash, a hash algorithm of 0
+// selects SHA512.
+//
+// Callers decrypting many ciphertexts under the same key may use this to
+// avoid constructing the fields on every call, as Recover() does. As the
+// threshold is not known, at least one decryption share is required. An
+// error is returned if multiple shares have the same ID.
+func RecoverInFields(zp gf.GF, zq gf.GF, hash crypto.Hash, decryptionShares []DecryptionShare, ctxt Ciphertext) ([]byte, error) {
+	return recoverInFields(zp, zq, hash, 1, decryptionShares, ctxt, nil)
+}
+
+// recoverInFields implements RecoverInFields() and Recover(), requiring at
+// least threshold decryption shares, and invoking the passed progress
+// callback - if not nil - after each incorporated share.
+func recoverInFields(zp gf.GF, zq gf.GF, hash crypto.Hash, threshold int, decryptionShares []DecryptionShare, ctxt Ciphertext, progress func(collected, needed int)) ([]byte, error) {
+	var msg []byte
+
+	if hash == 0 {
+		hash = defaultHash
+	}
+	if !hash.Available() {
+		return msg, fmt.Errorf("Unsupported hash algorithm %v", hash)
+	}
+	err := ctxt.checkComplete()
+	if err != nil {
+		return msg, err
+	}
+	if len(ctxt.C) != hash.Size() {
+		return msg, fmt.Errorf("Ciphertext must be %d bytes; got %d", hash.Size(), len(ctxt.C))
+	}
+
+	err = checkDecryptionShares(zp, decryptionShares, threshold)
+	if err != nil {
+		return msg, err
+	}
+
+	z := combineSharesWithProgress(zp, zq, decryptionShares, progress)
+	err = checkSharedSecret(z)
+	if err != nil {
+		return msg, err
+	}
+
+	return hashedXOR(hash, z, zp.P, ctxt.Label, ctxt.C), nil
+}
+
+// combineShares combines the passed decryption shares R^{x_i} into R^x = y^r
+// by Lagrange interpolation in the exponent.
+func combineShares(zp gf.GF, zq gf.GF, decryptionShares []DecryptionShare) *big.Int {
+	return combineSharesWithProgress(zp, zq, decryptionShares, nil)
+}
+
+// combineSharesWithProgress implements combineShares(), invoking the passed
+// progress callback - if not nil - after each incorporated share.
+func combineSharesWithProgress(zp gf.GF, zq gf.GF, decryptionShares []DecryptionShare, progress func(collected, needed int)) *big.Int {
+	xs := make([]*big.Int, len(decryptionShares))
+	for i, share := range decryptionShares {
+		xs[i] = big.NewInt(int64(share.ID))
+	}
+
+	// Starting with 1, as identity of multiplication
 	z := big.NewInt(1)
 
 	for i, share := range decryptionShares {
@@ -211,13 +1293,383 @@ func Recover(pub PublicKey, decryptionShares []DecryptionShare, ctxt Ciphertext)
 		// But the value we reconstruct is in G, so we operate over (Z/pZ)
 		factor := zp.Exp(share.Value, bp)
 		z = zp.Mul(z, factor)
+
+		if progress != nil {
+			progress(i+1, len(decryptionShares))
+		}
+	}
+
+	return z
+}
+
+// RecoverLabeled decrypts a ciphertext using t decryption shares, ensuring it
+// is bound to the expected label.
+//
+// An error is returned if the label of the ciphertext differs from the
+// expected one. As the label is hashed into the key stream, tampering with
+// the label stored in the ciphertext does not help either, as decryption
+// would then yield garbage.
+func RecoverLabeled(pub PublicKey, decryptionShares []DecryptionShare, ctxt Ciphertext, label []byte) ([]byte, error) {
+	if !bytes.Equal(ctxt.Label, label) {
+		return nil, fmt.Errorf("Ciphertext is bound to label %q; expected %q", ctxt.Label, label)
+	}
+
+	return Recover(pub, decryptionShares, ctxt)
+}
+
+// RecoverWithSelection decrypts a ciphertext like Recover(), additionally
+// returning the IDs of the decryption shares which were combined, in the
+// order they were passed in.
+//
+// As Recover() combines all passed shares, rather than only t of them, these
+// are the IDs of all passed shares. This is useful for debugging when more
+// than t shares are available.
+func RecoverWithSelection(pub PublicKey, decryptionShares []DecryptionShare, ctxt Ciphertext) ([]byte, []int, error) {
+	msg, err := Recover(pub, decryptionShares, ctxt)
+	if err != nil {
+		return msg, nil, err
+	}
+
+	return msg, shareIDs(decryptionShares), nil
+}
+
+// RecoverMinimal decrypts a ciphertext like Recover(), but only combines the
+// pub.Threshold decryption shares with the smallest IDs, ignoring any
+// further ones.
+//
+// As combining a share costs one exponentiation in (Z/pZ), this saves work
+// when more than t shares are available, e.g. two fifths of the
+// exponentiations when passing all shares of a 3-out-of-5 setup. Given
+// consistent shares, the plaintext is the same as recovered by Recover().
+//
+// If the public key does not specify a threshold, all shares are combined.
+func RecoverMinimal(pub PublicKey, decryptionShares []DecryptionShare, ctxt Ciphertext) ([]byte, error) {
+	return Recover(pub, minimalShares(pub.Threshold, decryptionShares), ctxt)
+}
+
+// minimalShares returns the t decryption shares with the smallest IDs, in
+// ascending order of their IDs. If t < 1, or if no more than t shares are
+// passed, the shares are returned as they are.
+func minimalShares(t int, decryptionShares []DecryptionShare) []DecryptionShare {
+	if t < 1 || len(decryptionShares) <= t {
+		return decryptionShares
+	}
+
+	sorted := make([]DecryptionShare, len(decryptionShares))
+	copy(sorted, decryptionShares)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	return sorted[:t]
+}
+
+// MinimalSubsets returns all subsets of the passed party IDs of size
+// threshold, that is all minimal sets of parties able to recover a
+// ciphertext. This is useful to reason about fault tolerance, e.g. which
+// parties must stay available.
+//
+// IDs passed more than once are only considered once. Each subset is in the
+// order of the passed IDs, and the subsets are in lexicographic order of
+// their positions. There are C(n, threshold) subsets for n distinct IDs, and
+// none if threshold < 1 or threshold > n.
+func MinimalSubsets(availableIDs []int, threshold int) [][]int {
+	if threshold < 1 {
+		return nil
+	}
+
+	seen := make(map[int]bool, len(availableIDs))
+	var ids []int
+	for _, id := range availableIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	combs := combinations(len(ids), threshold)
+	subsets := make([][]int, len(combs))
+	for i, comb := range combs {
+		subsets[i] = make([]int, len(comb))
+		for j, idx := range comb {
+			subsets[i][j] = ids[idx]
+		}
+	}
+
+	return subsets
+}
+
+// RecoverVerified decrypts a ciphertext like Recover(), but exploits
+// redundant decryption shares to detect parties submitting bogus shares.
+//
+// The ciphertext is decrypted using every subset of pub.Threshold of the
+// passed shares. If all subsets agree, the plaintext is returned. Otherwise
+// the plaintext recovered by the most subsets is returned along with an
+// *InconsistentShareError naming the shares which are not part of any such
+// subset. With t+1 honest shares and one bogus one, the honest shares
+// outvote the bogus one.
+//
+// An error is returned if the public key does not specify a threshold, if
+// fewer than pub.Threshold or duplicate shares are passed, or if there is no
+// unique majority. With exactly pub.Threshold shares, inconsistencies cannot
+// be detected. As every subset is checked, this is intended for moderate
+// numbers of shares.
+func RecoverVerified(pub PublicKey, decryptionShares []DecryptionShare, ctxt Ciphertext) ([]byte, error) {
+	t := pub.Threshold
+	if t < 1 {
+		return nil, fmt.Errorf("Public key does not specify a threshold")
+	}
+	if len(decryptionShares) < t {
+		return nil, fmt.Errorf("%w: need at least %d decryption shares; got %d", ErrThresholdNotMet, t, len(decryptionShares))
+	}
+
+	seen := make(map[int]bool)
+	for _, share := range decryptionShares {
+		if seen[share.ID] {
+			return nil, fmt.Errorf("%w: decryption share with ID %d", ErrDuplicateShare, share.ID)
+		}
+		seen[share.ID] = true
+	}
+
+	hash, err := pub.hashFunc()
+	if err != nil {
+		return nil, err
+	}
+	if len(ctxt.C) != hash.Size() {
+		return nil, fmt.Errorf("Ciphertext must be %d bytes; got %d", hash.Size(), len(ctxt.C))
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return nil, err
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return nil, err
+	}
+
+	// Group subsets by the value y^r they reconstruct
+	values := make(map[string]*big.Int)
+	votes := make(map[string][][]int)
+	for _, subset := range combinations(len(decryptionShares), t) {
+		subShares := make([]DecryptionShare, t)
+		for i, idx := range subset {
+			subShares[i] = decryptionShares[idx]
+		}
+
+		z := combineShares(zp, zq, subShares)
+		key := string(z.Bytes())
+		values[key] = z
+		votes[key] = append(votes[key], subset)
+	}
+
+	var majority string
+	tie := false
+	for key, subsets := range votes {
+		switch {
+		case len(votes[majority]) < len(subsets):
+			majority = key
+			tie = false
+		case len(votes[majority]) == len(subsets):
+			tie = true
+		}
+	}
+	if tie {
+		return nil, fmt.Errorf("Decryption shares are inconsistent, and no majority exists")
+	}
+
+	err = checkSharedSecret(values[majority])
+	if err != nil {
+		return nil, err
+	}
+	msg := hashedXOR(hash, values[majority], pub.P, ctxt.Label, ctxt.C)
+	if len(votes) == 1 {
+		return msg, nil
+	}
+
+	honest := make([]bool, len(decryptionShares))
+	for _, subset := range votes[majority] {
+		for _, idx := range subset {
+			honest[idx] = true
+		}
+	}
+	inconsistent := &InconsistentShareError{}
+	for i, share := range decryptionShares {
+		if !honest[i] {
+			inconsistent.IDs = append(inconsistent.IDs, share.ID)
+		}
+	}
+
+	return msg, inconsistent
+}
+
+// shareIDs returns the IDs of the passed decryption shares, in order.
+func shareIDs(decryptionShares []DecryptionShare) []int {
+	ids := make([]int, len(decryptionShares))
+	for i, share := range decryptionShares {
+		ids[i] = share.ID
 	}
 
-	key := sha512.Sum512(z.Bytes())
+	return ids
+}
+
+// RecoverPrivateKey reconstructs the full private key from a threshold of
+// private key shares, by Lagrange interpolation over (Z/qZ). This is useful
+// for key escrow, or to migrate away from a threshold setup.
+//
+// An error is returned if fewer than pub.Threshold shares are passed, or if
+// their IDs are not distinct. Whether the reconstructed key matches the
+// public key is not checked.
+func RecoverPrivateKey(pub PublicKey, shares []PrivateKeyShare) (PrivateKey, error) {
+	var priv PrivateKey
+
+	if len(shares) < pub.Threshold {
+		return priv, fmt.Errorf("%w: need at least %d private key shares; got %d", ErrThresholdNotMet, pub.Threshold, len(shares))
+	}
+	if len(shares) == 0 {
+		return priv, fmt.Errorf("Need at least one private key share")
+	}
+	err := checkDistinctIDs(shares)
+	if err != nil {
+		return priv, err
+	}
+
+	zq, err := pub.Zq()
+	if err != nil {
+		return priv, err
+	}
+
+	recoverShares := make([]secretshare.Share, len(shares))
+	for i, share := range shares {
+		recoverShares[i] = secretshare.Share(share)
+	}
+
+	x, err := secretshare.TOutOfNRecover(recoverShares, zq)
+	if err != nil {
+		return priv, err
+	}
+	priv.X = x
+
+	return priv, nil
+}
+
+// SplitPrivateKey splits an existing private key into n shares, any t of which
+// can decrypt ciphertexts encrypted under the public key. This allows to
+// distribute a key generated elsewhere under a threshold policy, without
+// generating a new group.
+//
+// Unlike KeyGen(), no Feldman commitments or verification keys are produced,
+// and the threshold of the public key is not updated.
+//
+// An error is returned if g^x mod p differs from y, if x is not in [0, q), or
+// if t < 1, n < 1 or t > n.
+func SplitPrivateKey(pub PublicKey, priv PrivateKey, t int, n int) ([]PrivateKeyShare, error) {
+	err := checkThreshold(t, n)
+	if err != nil {
+		return nil, err
+	}
+
+	if pub.P == nil || pub.G == nil || pub.Y == nil || priv.X == nil {
+		return nil, fmt.Errorf("Public key or private key is incomplete")
+	}
+
+	err = pub.checkGroup()
+	if err != nil {
+		return nil, err
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return nil, err
+	}
+	if !zq.IsGroupElement(priv.X) {
+		return nil, fmt.Errorf("Private key must be in [0, q)")
+	}
+
+	if new(big.Int).Exp(pub.G, priv.X, pub.P).Cmp(pub.Y) != 0 {
+		return nil, fmt.Errorf("Private key does not match public key")
+	}
+
+	shares := make([]PrivateKeyShare, n)
+
+	// secretshare.TOutOfN() requires t > 1. With t = 1, the sharing
+	// polynomial is constant, so every share is x itself.
+	if t == 1 {
+		for i := range shares {
+			shares[i] = PrivateKeyShare{ID: i + 1, Value: new(big.Int).Set(priv.X)}
+		}
+		return shares, nil
+	}
+
+	// Mind that secret sharing happens over (Z/qZ)
+	secretShares, _, err := secretshare.TOutOfN(priv.X, t, n, zq)
+	if err != nil {
+		return nil, err
+	}
+	for i, share := range secretShares {
+		shares[i] = PrivateKeyShare(share)
+	}
+
+	return shares, nil
+}
+
+// DecryptWhole decrypts a ciphertext using the full private key, rather than
+// decryption shares. This is useful if the private key was reconstructed from
+// a threshold of private key shares, e.g. during a migration.
+func DecryptWhole(pub PublicKey, priv PrivateKey, ctxt Ciphertext) ([]byte, error) {
+	if ctxt.R == nil {
+		return nil, fmt.Errorf("%w: R is nil", ErrInvalidCiphertext)
+	}
+	if priv.X == nil {
+		return nil, fmt.Errorf("Private key is missing x")
+	}
+
+	hash, err := pub.hashFunc()
+	if err != nil {
+		return nil, err
+	}
+	if len(ctxt.C) != hash.Size() {
+		return nil, fmt.Errorf("Ciphertext must be %d bytes; got %d", hash.Size(), len(ctxt.C))
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return nil, err
+	}
+
+	z := zp.Exp(ctxt.R, priv.X) // R^x = y^r mod p
+	err = checkSharedSecret(z)
+	if err != nil {
+		return nil, err
+	}
+
+	return hashedXOR(hash, z, pub.P, ctxt.Label, ctxt.C), nil
+}
+
+// hashedXOR XORs the passed input with H(z), where H is the passed hash
+// algorithm. Input must be of the hash algorithm's output size.
+//
+// z is encoded big-endian, left-padded with zeros to the byte length of the
+// modulus p, such that the encoding does not depend on the magnitude of z.
+//
+// If a label is passed, the input is instead XORed with H(|z| || z || label),
+// where |z| is the length of z as a 4-byte big-endian integer, ensuring that
+// z and label cannot be shifted against each other.
+func hashedXOR(hash crypto.Hash, z *big.Int, p *big.Int, label []byte, in []byte) []byte {
+	zBytes := fixedBytes(z, (p.BitLen()+7)/8)
+
+	h := hash.New()
+	if len(label) > 0 {
+		h.Write(appendLengthPrefixed(nil, zBytes))
+		h.Write(label)
+	} else {
+		h.Write(zBytes)
+	}
+	key := h.Sum(nil)
 
+	out := make([]byte, len(key))
 	for i, keyByte := range key {
-		msg[i] = ctxt.C[i] ^ keyByte
+		out[i] = in[i] ^ keyByte
 	}
 
-	return msg, nil
+	return out
 }
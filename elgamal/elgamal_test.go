@@ -2,10 +2,20 @@ package elgamal
 
 import (
 	"bytes"
+	"context"
+	"crypto"
 	"crypto/sha512"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/lavode/secret-sharing/secretshare"
 	"math/big"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestPublicKeyField(t *testing.T) {
@@ -55,6 +65,10 @@ func TestKeyGen(t *testing.T) {
 		t.Errorf("Expected 5 shares; got %d", len(shares))
 	}
 
+	if pub.Threshold != 3 {
+		t.Errorf("Expected threshold of 3; got %d", pub.Threshold)
+	}
+
 	field, err := pub.Zq()
 	if err != nil {
 		t.Fatalf("Error generating field: %v", err)
@@ -74,6 +88,272 @@ func TestKeyGen(t *testing.T) {
 	}
 }
 
+func TestRecoverPrivateKey(t *testing.T) {
+	pub, priv, shares, err := KeyGen(20, 10, 3, 5)
+	if err != nil {
+		t.Fatalf("Error in KeyGen: %v", err)
+	}
+
+	recovered, err := RecoverPrivateKey(pub, []PrivateKeyShare{shares[0], shares[2], shares[3]})
+	if err != nil {
+		t.Fatalf("RecoverPrivateKey returned error: %v", err)
+	}
+	if recovered.X.Cmp(priv.X) != 0 {
+		t.Errorf("Expected recovered private key %d; got %d", priv.X, recovered.X)
+	}
+
+	// More than t shares work too
+	recovered, err = RecoverPrivateKey(pub, shares)
+	if err != nil {
+		t.Fatalf("RecoverPrivateKey returned error: %v", err)
+	}
+	if recovered.X.Cmp(priv.X) != 0 {
+		t.Errorf("Expected recovered private key %d; got %d", priv.X, recovered.X)
+	}
+
+	_, err = RecoverPrivateKey(pub, shares[:2])
+	if err == nil {
+		t.Errorf("Expected error with fewer than t shares; got none")
+	}
+
+	_, err = RecoverPrivateKey(pub, []PrivateKeyShare{shares[0], shares[1], shares[0]})
+	if err == nil {
+		t.Errorf("Expected error with duplicate shares; got none")
+	}
+}
+
+func TestSplitPrivateKey(t *testing.T) {
+	pub := PublicKey{
+		SchnorrGroup: SchnorrGroup{
+			P: big.NewInt(23),
+			Q: big.NewInt(11),
+			G: big.NewInt(4),
+		},
+		Y: big.NewInt(16), // x = 2
+	}
+	priv := PrivateKey{X: big.NewInt(2)}
+
+	shares, err := SplitPrivateKey(pub, priv, 3, 5)
+	if err != nil {
+		t.Fatalf("SplitPrivateKey returned error: %v", err)
+	}
+	if len(shares) != 5 {
+		t.Fatalf("Expected 5 shares; got %d", len(shares))
+	}
+
+	for _, subset := range [][]PrivateKeyShare{shares[:3], shares[2:], {shares[4], shares[0], shares[2]}} {
+		recovered, err := RecoverPrivateKey(pub, subset)
+		if err != nil {
+			t.Fatalf("RecoverPrivateKey returned error: %v", err)
+		}
+		if recovered.X.Cmp(priv.X) != 0 {
+			t.Errorf("Expected recovered private key %d; got %d", priv.X, recovered.X)
+		}
+	}
+
+	// Shares decrypt the ciphertext of TestRecover
+	msg, err := PadMessage([]byte("Hello world"))
+	if err != nil {
+		t.Fatalf("PadMessage returned error: %v", err)
+	}
+	ctxt, err := EncWithRandomness(pub, msg, big.NewInt(4))
+	if err != nil {
+		t.Fatalf("EncWithRandomness returned error: %v", err)
+	}
+	decShares, err := DecAll(pub, shares[1:4], ctxt)
+	if err != nil {
+		t.Fatalf("DecAll returned error: %v", err)
+	}
+	recovered, err := Recover(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recovered)
+	}
+
+	shares, err = SplitPrivateKey(pub, priv, 1, 2)
+	if err != nil {
+		t.Fatalf("SplitPrivateKey returned error: %v", err)
+	}
+	for _, share := range shares {
+		if share.Value.Cmp(priv.X) != 0 {
+			t.Errorf("Expected 1-out-of-n share to equal private key %d; got %d", priv.X, share.Value)
+		}
+	}
+
+	_, err = SplitPrivateKey(pub, PrivateKey{X: big.NewInt(3)}, 3, 5)
+	if err == nil {
+		t.Errorf("Expected error with mismatching private key; got none")
+	}
+	_, err = SplitPrivateKey(pub, PrivateKey{X: big.NewInt(13)}, 3, 5)
+	if err == nil {
+		t.Errorf("Expected error with private key outside of [0, q); got none")
+	}
+	_, err = SplitPrivateKey(pub, priv, 6, 5)
+	if err == nil {
+		t.Errorf("Expected error with t > n; got none")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := PublicKey{
+		SchnorrGroup: SchnorrGroup{
+			P: big.NewInt(23),
+			Q: big.NewInt(11),
+			G: big.NewInt(4),
+		},
+		Y: big.NewInt(8),
+	}
+
+	// Same values, with big.Ints of larger capacity, and further fields
+	// set
+	large := new(big.Int).Lsh(big.NewInt(1), 4096)
+	b := PublicKey{
+		SchnorrGroup: SchnorrGroup{
+			P: new(big.Int).Set(large).SetInt64(23),
+			Q: new(big.Int).Set(large).SetInt64(11),
+			G: new(big.Int).Set(large).SetInt64(4),
+		},
+		Y:         new(big.Int).Set(large).SetInt64(8),
+		Hash:      crypto.SHA256,
+		Threshold: 2,
+	}
+
+	expected := "c1290e5fe9fec6d8fe626cc3715e744acb28fc6c1488efcde5d6e71dd1392727"
+	if a.Fingerprint() != expected {
+		t.Errorf("Expected fingerprint %s; got %s", expected, a.Fingerprint())
+	}
+	if b.Fingerprint() != expected {
+		t.Errorf("Expected fingerprint %s; got %s", expected, b.Fingerprint())
+	}
+
+	b.Y = big.NewInt(16)
+	if b.Fingerprint() == expected {
+		t.Errorf("Expected fingerprint to change with y")
+	}
+}
+
+func TestMatchesPrivate(t *testing.T) {
+	pub, priv, _, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("Error in KeyGen: %v", err)
+	}
+
+	ok, err := pub.MatchesPrivate(priv)
+	if err != nil {
+		t.Fatalf("MatchesPrivate returned error: %v", err)
+	}
+	if !ok {
+		t.Errorf("Expected private key from KeyGen to match public key")
+	}
+
+	wrong := PrivateKey{X: new(big.Int).Add(priv.X, big.NewInt(1))}
+	ok, err = pub.MatchesPrivate(wrong)
+	if err != nil {
+		t.Fatalf("MatchesPrivate returned error: %v", err)
+	}
+	if ok {
+		t.Errorf("Expected private key off by one not to match public key")
+	}
+
+	_, err = pub.MatchesPrivate(PrivateKey{})
+	if err == nil {
+		t.Errorf("Expected error with missing x; got none")
+	}
+}
+
+func TestKeyGenParameters(t *testing.T) {
+	tests := []struct {
+		name  string
+		t     int
+		n     int
+		field string
+	}{
+		{"t zero", 0, 5, "t"},
+		{"t negative", -1, 5, "t"},
+		{"n zero", 3, 0, "n"},
+		{"n negative", 3, -2, "n"},
+		{"both zero", 0, 0, "t"},
+		{"t exceeds n", 6, 5, "t"},
+	}
+
+	for _, test := range tests {
+		_, _, _, err := KeyGen(20, 10, test.t, test.n)
+		if err == nil {
+			t.Errorf("%s: Expected error for t = %d, n = %d; got none", test.name, test.t, test.n)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), test.field+" must be") {
+			t.Errorf("%s: Expected error naming %s; got %v", test.name, test.field, err)
+		}
+	}
+
+	// Boundary of t == n is valid
+	_, _, shares, err := KeyGen(20, 10, 5, 5)
+	if err != nil {
+		t.Errorf("Expected no error for t = n; got %v", err)
+	}
+	if len(shares) != 5 {
+		t.Errorf("Expected 5 shares; got %d", len(shares))
+	}
+}
+
+func TestKeyGenContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, _, err := KeyGenContext(ctx, 20, 10, 3, 5)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled with canceled context; got %v", err)
+	}
+
+	// Searching for a prime of this size takes far longer than the
+	// timeout, so cancellation must interrupt the search.
+	ctx, cancel = context.WithCancel(context.Background())
+	timer := time.AfterFunc(10*time.Millisecond, cancel)
+	defer timer.Stop()
+
+	_, _, _, err = KeyGenContext(ctx, 8192, 256, 3, 5)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled after cancellation; got %v", err)
+	}
+
+	_, _, _, err = KeyGenContext(context.Background(), 20, 10, 3, 5)
+	if err != nil {
+		t.Errorf("KeyGenContext returned error: %v", err)
+	}
+}
+
+func TestKeyGenVerificationKeys(t *testing.T) {
+	pub, _, shares, err := KeyGen(20, 10, 3, 5)
+	if err != nil {
+		t.Fatalf("Error in KeyGen: %v", err)
+	}
+
+	if len(pub.VerificationKeys) != len(shares) {
+		t.Fatalf("Expected %d verification keys; got %d", len(shares), len(pub.VerificationKeys))
+	}
+
+	for _, share := range shares {
+		vk, err := pub.VerificationKey(share.ID)
+		if err != nil {
+			t.Fatalf("Error looking up verification key: %v", err)
+		}
+
+		var expected = &big.Int{}
+		expected.Exp(pub.G, share.Value, pub.P) // g^{x_i} mod p
+		if vk.ID != share.ID || vk.Value.Cmp(expected) != 0 {
+			t.Errorf("Expected verification key {ID: %d, Value: %d}; got %+v", share.ID, expected, vk)
+		}
+	}
+
+	_, err = pub.VerificationKey(6)
+	if err == nil {
+		t.Errorf("Expected error for unknown verification key ID; got none")
+	}
+}
+
 func TestEnc(t *testing.T) {
 	pub := PublicKey{
 		SchnorrGroup: SchnorrGroup{
@@ -124,7 +404,7 @@ func TestEnc(t *testing.T) {
 	}
 }
 
-func TestDec(t *testing.T) {
+func TestEncWithRandomness(t *testing.T) {
 	pub := PublicKey{
 		SchnorrGroup: SchnorrGroup{
 			P: big.NewInt(23),
@@ -134,51 +414,103 @@ func TestDec(t *testing.T) {
 		Y: big.NewInt(16), // x = 2
 	}
 
-	// Ciphertext encoding of message 'Hello world' padded to 64 bytes
-	// Only the `R` component is relevant for this test
-	ctxt := Ciphertext{
+	// 'Hello world', padded to 64 bytes
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+
+	// Handcrafted ciphertext of TestDec
+	expected := Ciphertext{
 		R: big.NewInt(3), // r = 4
 		C: []byte{0xBA, 0x1E, 0x37, 0x94, 0xBC, 0x7E, 0xD5, 0xD4, 0xC9, 0x0, 0x6B, 0x9F, 0xEF, 0x89, 0xD8, 0x83, 0x41, 0x5B, 0x5A, 0xDB, 0xD6, 0xA8, 0x40, 0x30, 0xCB, 0x1F, 0x35, 0xE6, 0xA6, 0xC0, 0x26, 0xE6, 0x5C, 0x60, 0xFB, 0x99, 0xF5, 0x62, 0xF7, 0xEB, 0x9F, 0x77, 0xF3, 0xDE, 0xC5, 0x0, 0x14, 0x73, 0x44, 0x1D, 0x2C, 0x55, 0x86, 0xB5, 0x4D, 0x9B, 0x99, 0x9C, 0xF4, 0xBD, 0x79, 0xE, 0x4C, 0x56},
 	}
 
-	// Three keyshares out of a 3-out-of-5 secret share of x and their
-	// corresponding (expected) decryption shares.
-	k1 := PrivateKeyShare(secretshare.Share{ID: 1, Value: big.NewInt(4)})
-	k3 := PrivateKeyShare(secretshare.Share{ID: 3, Value: big.NewInt(14)})
-	k4 := PrivateKeyShare(secretshare.Share{ID: 4, Value: big.NewInt(22)})
+	ctxt, err := EncWithRandomness(pub, msg, big.NewInt(4))
+	if err != nil {
+		t.Fatalf("EncWithRandomness returned error: %v", err)
+	}
+	if !ctxt.Equal(expected) {
+		t.Errorf("Expected ciphertext %+v; got %+v", expected, ctxt)
+	}
 
-	ed1 := DecryptionShare(secretshare.Share{ID: 1, Value: big.NewInt(12)})
-	ed3 := DecryptionShare(secretshare.Share{ID: 3, Value: big.NewInt(4)})
-	ed4 := DecryptionShare(secretshare.Share{ID: 4, Value: big.NewInt(1)})
+	for _, r := range []*big.Int{nil, big.NewInt(0), big.NewInt(-1), big.NewInt(11), big.NewInt(12)} {
+		_, err = EncWithRandomness(pub, msg, r)
+		if err == nil {
+			t.Errorf("Expected error for r = %v; got none", r)
+		}
+	}
+}
 
-	d1, err := Dec(pub, k1, ctxt)
+func TestEncWithEphemeral(t *testing.T) {
+	pub, _, _, err := KeyGen(512, 128, 3, 5)
 	if err != nil {
-		t.Fatalf("Dec returned error: %v", err)
+		t.Fatalf("KeyGen returned error: %v", err)
 	}
-	if d1.ID != ed1.ID || d1.Value.Cmp(ed1.Value) != 0 {
-		t.Errorf("Expected decryption share %+v; got %+v", ed1, d1)
+	zp, err := pub.Zp()
+	if err != nil {
+		t.Fatalf("Error creating field: %v", err)
 	}
 
-	d3, err := Dec(pub, k3, ctxt)
+	msg := make([]byte, 64)
+	ctxt, r, err := EncWithEphemeral(pub, msg)
 	if err != nil {
-		t.Fatalf("Dec returned error: %v", err)
+		t.Fatalf("EncWithEphemeral returned error: %v", err)
 	}
-	if d3.ID != ed3.ID || d3.Value.Cmp(ed3.Value) != 0 {
-		t.Errorf("Expected decryption share %+v; got %+v", ed3, d3)
+
+	if zp.Exp(pub.G, r).Cmp(ctxt.R) != 0 {
+		t.Errorf("Expected g^r = R; got g^r = %d, R = %d", zp.Exp(pub.G, r), ctxt.R)
 	}
 
-	d4, err := Dec(pub, k4, ctxt)
+	// Same exponent must reproduce the same ciphertext
+	expected, err := EncWithRandomness(pub, msg, r)
 	if err != nil {
-		t.Fatalf("Dec returned error: %v", err)
+		t.Fatalf("EncWithRandomness returned error: %v", err)
 	}
-	if d4.ID != ed4.ID || d4.Value.Cmp(ed4.Value) != 0 {
-		t.Errorf("Expected decryption share %+v; got %+v", ed4, d4)
+	if !ctxt.Equal(expected) {
+		t.Errorf("Expected ciphertext %+v; got %+v", expected, ctxt)
+	}
+
+	// Mutating the returned exponent must not affect the ciphertext
+	R := new(big.Int).Set(ctxt.R)
+	r.SetInt64(0)
+	if ctxt.R.Cmp(R) != 0 {
+		t.Errorf("Expected R to be unaffected by mutation of r")
+	}
+
+	_, _, err = EncWithEphemeral(pub, make([]byte, 63))
+	if err == nil {
+		t.Errorf("Expected error with message of wrong length; got none")
 	}
 }
 
-func TestRecover(t *testing.T) {
+// deterministicReader is an io.Reader producing a deterministic stream of
+// bytes, by hashing a seed along with a counter.
+type deterministicReader struct {
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func (r *deterministicReader) Read(p []byte) (int, error) {
+	for len(r.buf) < len(p) {
+		var counter [8]byte
+		binary.BigEndian.PutUint64(counter[:], r.counter)
+		r.counter++
+
+		h := sha512.New()
+		h.Write(r.seed)
+		h.Write(counter[:])
+		r.buf = h.Sum(r.buf)
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func TestEncWithReader(t *testing.T) {
 	// 'Hello world', padded to 64 bytes
-	msg := []byte{0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
 
 	pub := PublicKey{
 		SchnorrGroup: SchnorrGroup{
@@ -189,86 +521,1608 @@ func TestRecover(t *testing.T) {
 		Y: big.NewInt(16), // x = 2
 	}
 
-	// // Three keyshares out of a 3-out-of-5 secret share of x
-	// keyShares := []PrivateKeyShare{
-	// 	PrivateKeyShare(secretshare.Share{ID: 1, Value: 4}}),
-	// 	PrivateKeyShare(secretshare.Share{ID: 3, Value: 14}}),
-	// 	PrivateKeyShare(secretshare.Share{ID: 4, Value: 22}}),
-	// }
+	// Exponents are drawn as 1 + [0, q-1), so a byte of 3 yields r = 4.
+	ctxt, err := EncWithReader(bytes.NewReader([]byte{0x03}), pub, msg)
+	if err != nil {
+		t.Fatalf("EncWithReader returned error: %v", err)
+	}
 
-	// Ciphertext encoding of message above
-	ctxt := Ciphertext{
+	// Handcrafted ciphertext from TestDec
+	expected := Ciphertext{
 		R: big.NewInt(3), // r = 4
 		C: []byte{0xBA, 0x1E, 0x37, 0x94, 0xBC, 0x7E, 0xD5, 0xD4, 0xC9, 0x0, 0x6B, 0x9F, 0xEF, 0x89, 0xD8, 0x83, 0x41, 0x5B, 0x5A, 0xDB, 0xD6, 0xA8, 0x40, 0x30, 0xCB, 0x1F, 0x35, 0xE6, 0xA6, 0xC0, 0x26, 0xE6, 0x5C, 0x60, 0xFB, 0x99, 0xF5, 0x62, 0xF7, 0xEB, 0x9F, 0x77, 0xF3, 0xDE, 0xC5, 0x0, 0x14, 0x73, 0x44, 0x1D, 0x2C, 0x55, 0x86, 0xB5, 0x4D, 0x9B, 0x99, 0x9C, 0xF4, 0xBD, 0x79, 0xE, 0x4C, 0x56},
 	}
+	if !ctxt.Equal(expected) {
+		t.Errorf("Expected ciphertext %+v; got %+v", expected, ctxt)
+	}
 
-	// 3-out-of-5 decryption shares of ciphertext above
-	decryptionShares := []DecryptionShare{
-		DecryptionShare(secretshare.Share{ID: 1, Value: big.NewInt(4)}),
-		DecryptionShare(secretshare.Share{ID: 3, Value: big.NewInt(4)}),
-		DecryptionShare(secretshare.Share{ID: 4, Value: big.NewInt(9)}),
+	// Exhausted reader
+	_, err = EncWithReader(bytes.NewReader(nil), pub, msg)
+	if err == nil {
+		t.Errorf("Expected error with exhausted reader; got none")
 	}
 
-	recovered, err := Recover(pub, decryptionShares, ctxt)
+	// Nil reader falls back to crypto/rand
+	_, err = EncWithReader(nil, pub, msg)
 	if err != nil {
-		t.Fatalf("Recover returned error: %v", err)
+		t.Errorf("EncWithReader with nil reader returned error: %v", err)
 	}
+}
 
-	if !bytes.Equal(recovered, msg) {
-		t.Errorf("Recovered message did not match actual message; got %x; expected %x", recovered, msg)
+func TestKeyGenWithReader(t *testing.T) {
+	pub1, priv1, shares1, err := KeyGenWithReader(&deterministicReader{seed: []byte("seed")}, 256, 64, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGenWithReader returned error: %v", err)
+	}
+	pub2, priv2, shares2, err := KeyGenWithReader(&deterministicReader{seed: []byte("seed")}, 256, 64, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGenWithReader returned error: %v", err)
 	}
 
-}
-
-// This tests the whole thing end-to-end, with real-world keys.
-// Hopefully catching any issues which might be the result of the
-// handcrafted values above.
-func TestIntegration(t *testing.T) {
-	// 'Hello world', padded to 64 bytes
-	msg := []byte{0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}
+	if pub1.P.Cmp(pub2.P) != 0 || pub1.Q.Cmp(pub2.Q) != 0 || pub1.G.Cmp(pub2.G) != 0 || pub1.Y.Cmp(pub2.Y) != 0 {
+		t.Errorf("Expected identical public keys from identical readers; got %+v and %+v", pub1, pub2)
+	}
+	if priv1.X.Cmp(priv2.X) != 0 {
+		t.Errorf("Expected identical private keys from identical readers")
+	}
+	for i := range shares1 {
+		if shares1[i].ID != shares2[i].ID || shares1[i].Value.Cmp(shares2[i].Value) != 0 {
+			t.Errorf("Expected identical shares from identical readers; got %+v and %+v", shares1[i], shares2[i])
+		}
+	}
 
-	pub, _, privShares, err := KeyGen(1024, 256, 4, 6)
+	err = ValidateSetup(pub1, shares1, 3)
 	if err != nil {
-		t.Fatalf("KeyGen returned error: %v", err)
+		t.Errorf("Expected valid setup from KeyGenWithReader; got %v", err)
 	}
 
-	ctxt, err := Enc(pub, msg)
+	pub3, _, _, err := KeyGenWithReader(&deterministicReader{seed: []byte("other seed")}, 256, 64, 3, 5)
 	if err != nil {
-		t.Fatalf("Enc returned error: %v", err)
+		t.Fatalf("KeyGenWithReader returned error: %v", err)
+	}
+	if pub3.Y.Cmp(pub1.Y) == 0 {
+		t.Errorf("Expected different public keys from different readers")
 	}
+}
 
-	decShare1, err := Dec(pub, privShares[0], ctxt)
+func TestKeyGenSeeded(t *testing.T) {
+	seed := []byte("test vector seed")
+
+	pub1, priv1, shares1, err := KeyGenSeeded(seed, 256, 64, 3, 5)
 	if err != nil {
-		t.Fatalf("Dec returned error: %v", err)
+		t.Fatalf("KeyGenSeeded returned error: %v", err)
+	}
+	pub2, priv2, shares2, err := KeyGenSeeded(seed, 256, 64, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGenSeeded returned error: %v", err)
 	}
 
-	decShare3, err := Dec(pub, privShares[2], ctxt)
+	// The PEM encoding covers the group, y, the threshold, the
+	// verification keys and the commitments.
+	pem1, err := pub1.MarshalPEM()
 	if err != nil {
-		t.Fatalf("Dec returned error: %v", err)
+		t.Fatalf("MarshalPEM returned error: %v", err)
+	}
+	pem2, err := pub2.MarshalPEM()
+	if err != nil {
+		t.Fatalf("MarshalPEM returned error: %v", err)
+	}
+	if !bytes.Equal(pem1, pem2) {
+		t.Errorf("Expected identical public keys from identical seeds; got\n%s\nand\n%s", pem1, pem2)
 	}
 
-	decShare4, err := Dec(pub, privShares[3], ctxt)
+	if priv1.X.Cmp(priv2.X) != 0 {
+		t.Errorf("Expected identical private keys from identical seeds")
+	}
+	if len(shares1) != len(shares2) {
+		t.Fatalf("Expected %d shares; got %d", len(shares1), len(shares2))
+	}
+	for i := range shares1 {
+		b1, err := shares1[i].GobEncode()
+		if err != nil {
+			t.Fatalf("GobEncode returned error: %v", err)
+		}
+		b2, err := shares2[i].GobEncode()
+		if err != nil {
+			t.Fatalf("GobEncode returned error: %v", err)
+		}
+		if !bytes.Equal(b1, b2) {
+			t.Errorf("Expected identical shares from identical seeds; got %x and %x", b1, b2)
+		}
+	}
+
+	err = ValidateSetup(pub1, shares1, 3)
 	if err != nil {
-		t.Fatalf("Dec returned error: %v", err)
+		t.Errorf("Expected valid setup from KeyGenSeeded; got %v", err)
 	}
 
-	decShare5, err := Dec(pub, privShares[4], ctxt)
+	pub3, _, _, err := KeyGenSeeded([]byte("other seed"), 256, 64, 3, 5)
 	if err != nil {
-		t.Fatalf("Dec returned error: %v", err)
+		t.Fatalf("KeyGenSeeded returned error: %v", err)
+	}
+	if pub3.Y.Cmp(pub1.Y) == 0 {
+		t.Errorf("Expected different public keys from different seeds")
 	}
 
-	decShares := []DecryptionShare{
-		decShare1,
-		decShare3,
-		decShare4,
-		decShare5,
+	_, _, _, err = KeyGenSeeded(nil, 256, 64, 3, 5)
+	if err == nil {
+		t.Errorf("Expected error with empty seed; got none")
 	}
+}
 
-	recov, err := Recover(pub, decShares, ctxt)
+func TestEncMulti(t *testing.T) {
+	msg, err := PadMessage([]byte("Hello world"))
 	if err != nil {
-		t.Fatalf("Recover returned error: %v", err)
+		t.Fatalf("PadMessage returned error: %v", err)
 	}
-	if !bytes.Equal(msg, recov) {
-		t.Errorf("Expected recovered message %x; got %x", msg, recov)
+
+	pub1, _, shares1, err := KeyGen(512, 128, 2, 3)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	pub2, _, shares2, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	ctxts, err := EncMulti([]PublicKey{pub1, pub2}, msg)
+	if err != nil {
+		t.Fatalf("EncMulti returned error: %v", err)
+	}
+	if len(ctxts) != 2 {
+		t.Fatalf("Expected 2 ciphertexts; got %d", len(ctxts))
+	}
+
+	recipients := []struct {
+		pub    PublicKey
+		shares []PrivateKeyShare
+	}{
+		{pub1, shares1[:2]},
+		{pub2, shares2[:3]},
+	}
+	for i, recipient := range recipients {
+		decShares, err := DecAll(recipient.pub, recipient.shares, ctxts[i])
+		if err != nil {
+			t.Fatalf("DecAll returned error: %v", err)
+		}
+		recovered, err := Recover(recipient.pub, decShares, ctxts[i])
+		if err != nil {
+			t.Fatalf("Recover returned error: %v", err)
+		}
+		if !bytes.Equal(recovered, msg) {
+			t.Errorf("Expected recipient %d to recover %x; got %x", i, msg, recovered)
+		}
+	}
+
+	// A recipient with a different hash algorithm requires a different
+	// message length
+	pub3 := pub2
+	pub3.Hash = crypto.SHA256
+	_, err = EncMulti([]PublicKey{pub1, pub3}, msg)
+	if !errors.Is(err, ErrMessageLength) {
+		t.Errorf("Expected ErrMessageLength; got %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "Recipient 1") {
+		t.Errorf("Expected error identifying recipient 1; got %v", err)
+	}
+}
+
+// TestPublicKeyConcurrent shares a single public key - including its
+// memoized fields, which are populated on first use - between many
+// goroutines. Run with -race to detect unsynchronized access.
+func TestPublicKeyConcurrent(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	// Fresh cache, such that goroutines race to populate it
+	pub.fields = newFieldCache()
+
+	const goroutines = 100
+	errs := make(chan error, goroutines)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			msg := make([]byte, 64)
+			msg[0] = byte(i)
+			ctxt, err := Enc(pub, msg)
+			if err != nil {
+				errs <- fmt.Errorf("Enc returned error: %w", err)
+				return
+			}
+
+			decShares := make([]DecryptionShare, 3)
+			for j := range decShares {
+				decShares[j], err = Dec(pub, shares[j], ctxt)
+				if err != nil {
+					errs <- fmt.Errorf("Dec returned error: %w", err)
+					return
+				}
+			}
+
+			recovered, err := Recover(pub, decShares, ctxt)
+			if err != nil {
+				errs <- fmt.Errorf("Recover returned error: %w", err)
+				return
+			}
+			if !bytes.Equal(recovered, msg) {
+				errs <- fmt.Errorf("Expected recovered message %x; got %x", msg, recovered)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestKeyGenRange(t *testing.T) {
+	pub, priv, shares, err := KeyGenRange(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGenRange returned error: %v", err)
+	}
+	if priv.X.BitLen() != 128 {
+		t.Errorf("Expected private key of 128 bits; got %d", priv.X.BitLen())
+	}
+	err = ValidateSetup(pub, shares, 3)
+	if err != nil {
+		t.Errorf("Expected valid setup from KeyGenRange; got %v", err)
+	}
+
+	// Unrestricted, x < 2^127 in about a third of all cases
+	minX := new(big.Int).Lsh(big.NewInt(1), 127)
+	for i := 0; i < 200; i++ {
+		_, priv, _, err := keyGenInGroupFrom(nil, pub.SchnorrGroup, 2, 3, minX)
+		if err != nil {
+			t.Fatalf("keyGenInGroupFrom returned error: %v", err)
+		}
+		if priv.X.Cmp(minX) < 0 || priv.X.Cmp(pub.Q) >= 0 {
+			t.Fatalf("Expected private key in [2^127, q); got %d", priv.X)
+		}
+	}
+}
+
+func TestRekeyInGroup(t *testing.T) {
+	group, err := GenerateSchnorrGroup(512, 128)
+	if err != nil {
+		t.Fatalf("GenerateSchnorrGroup returned error: %v", err)
+	}
+
+	pub1, _, shares1, err := RekeyInGroup(group, 3, 5)
+	if err != nil {
+		t.Fatalf("RekeyInGroup returned error: %v", err)
+	}
+	pub2, priv2, shares2, err := RekeyInGroup(group, 2, 4)
+	if err != nil {
+		t.Fatalf("RekeyInGroup returned error: %v", err)
+	}
+
+	for _, pub := range []PublicKey{pub1, pub2} {
+		if pub.P.Cmp(group.P) != 0 || pub.Q.Cmp(group.Q) != 0 || pub.G.Cmp(group.G) != 0 {
+			t.Errorf("Expected key in group %+v; got %+v", group, pub.SchnorrGroup)
+		}
+	}
+	if pub1.Y.Cmp(pub2.Y) == 0 {
+		t.Errorf("Expected different public keys from two rekeys; got y = %d twice", pub1.Y)
+	}
+
+	err = ValidateSetup(pub1, shares1, 3)
+	if err != nil {
+		t.Errorf("Expected valid setup from RekeyInGroup; got %v", err)
+	}
+	err = ValidateSetup(pub2, shares2, 2)
+	if err != nil {
+		t.Errorf("Expected valid setup from RekeyInGroup; got %v", err)
+	}
+	ok, err := pub2.MatchesPrivate(priv2)
+	if err != nil {
+		t.Fatalf("MatchesPrivate returned error: %v", err)
+	}
+	if !ok {
+		t.Errorf("Expected private key to match public key")
+	}
+
+	invalid := group
+	invalid.G = big.NewInt(1)
+	_, _, _, err = RekeyInGroup(invalid, 3, 5)
+	if err == nil {
+		t.Errorf("Expected error with invalid group; got none")
+	}
+	_, _, _, err = RekeyInGroup(group, 6, 5)
+	if err == nil {
+		t.Errorf("Expected error with t > n; got none")
+	}
+}
+
+func TestEncBatch(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	messages := make([][]byte, 20)
+	for i := range messages {
+		messages[i] = make([]byte, 64)
+		messages[i][0] = byte(i)
+	}
+
+	ctxts, err := EncBatch(pub, messages)
+	if err != nil {
+		t.Fatalf("EncBatch returned error: %v", err)
+	}
+	if len(ctxts) != len(messages) {
+		t.Fatalf("Expected %d ciphertexts; got %d", len(messages), len(ctxts))
+	}
+
+	seen := make(map[string]bool)
+	for i, ctxt := range ctxts {
+		if seen[ctxt.R.String()] {
+			t.Errorf("Expected fresh r for every message; got repeated R = %d", ctxt.R)
+		}
+		seen[ctxt.R.String()] = true
+
+		decShares, err := DecAll(pub, shares[:3], ctxt)
+		if err != nil {
+			t.Fatalf("DecAll returned error: %v", err)
+		}
+		recovered, err := Recover(pub, decShares, ctxt)
+		if err != nil {
+			t.Fatalf("Recover returned error: %v", err)
+		}
+		if !bytes.Equal(recovered, messages[i]) {
+			t.Errorf("Expected message %d to be %x; got %x", i, messages[i], recovered)
+		}
+	}
+
+	messages[7] = make([]byte, 63)
+	_, err = EncBatch(pub, messages)
+	if err == nil || !strings.Contains(err.Error(), "message 7") {
+		t.Errorf("Expected error identifying message 7; got %v", err)
+	}
+}
+
+func TestDec(t *testing.T) {
+	pub := PublicKey{
+		SchnorrGroup: SchnorrGroup{
+			P: big.NewInt(23),
+			Q: big.NewInt(11),
+			G: big.NewInt(4),
+		},
+		Y: big.NewInt(16), // x = 2
+	}
+
+	// Ciphertext encoding of message 'Hello world' padded to 64 bytes
+	// Only the `R` component is relevant for this test
+	ctxt := Ciphertext{
+		R: big.NewInt(3), // r = 4
+		C: []byte{0xBA, 0x1E, 0x37, 0x94, 0xBC, 0x7E, 0xD5, 0xD4, 0xC9, 0x0, 0x6B, 0x9F, 0xEF, 0x89, 0xD8, 0x83, 0x41, 0x5B, 0x5A, 0xDB, 0xD6, 0xA8, 0x40, 0x30, 0xCB, 0x1F, 0x35, 0xE6, 0xA6, 0xC0, 0x26, 0xE6, 0x5C, 0x60, 0xFB, 0x99, 0xF5, 0x62, 0xF7, 0xEB, 0x9F, 0x77, 0xF3, 0xDE, 0xC5, 0x0, 0x14, 0x73, 0x44, 0x1D, 0x2C, 0x55, 0x86, 0xB5, 0x4D, 0x9B, 0x99, 0x9C, 0xF4, 0xBD, 0x79, 0xE, 0x4C, 0x56},
+	}
+
+	// Three keyshares out of a 3-out-of-5 secret share of x and their
+	// corresponding (expected) decryption shares.
+	k1 := PrivateKeyShare(secretshare.Share{ID: 1, Value: big.NewInt(4)})
+	k3 := PrivateKeyShare(secretshare.Share{ID: 3, Value: big.NewInt(14)})
+	k4 := PrivateKeyShare(secretshare.Share{ID: 4, Value: big.NewInt(22)})
+
+	ed1 := DecryptionShare(secretshare.Share{ID: 1, Value: big.NewInt(12)})
+	ed3 := DecryptionShare(secretshare.Share{ID: 3, Value: big.NewInt(4)})
+	ed4 := DecryptionShare(secretshare.Share{ID: 4, Value: big.NewInt(1)})
+
+	d1, err := Dec(pub, k1, ctxt)
+	if err != nil {
+		t.Fatalf("Dec returned error: %v", err)
+	}
+	if d1.ID != ed1.ID || d1.Value.Cmp(ed1.Value) != 0 {
+		t.Errorf("Expected decryption share %+v; got %+v", ed1, d1)
+	}
+
+	d3, err := Dec(pub, k3, ctxt)
+	if err != nil {
+		t.Fatalf("Dec returned error: %v", err)
+	}
+	if d3.ID != ed3.ID || d3.Value.Cmp(ed3.Value) != 0 {
+		t.Errorf("Expected decryption share %+v; got %+v", ed3, d3)
+	}
+
+	d4, err := Dec(pub, k4, ctxt)
+	if err != nil {
+		t.Fatalf("Dec returned error: %v", err)
+	}
+	if d4.ID != ed4.ID || d4.Value.Cmp(ed4.Value) != 0 {
+		t.Errorf("Expected decryption share %+v; got %+v", ed4, d4)
+	}
+}
+
+func TestDecSubgroupCheck(t *testing.T) {
+	pub := PublicKey{
+		SchnorrGroup: SchnorrGroup{
+			P: big.NewInt(23),
+			Q: big.NewInt(11),
+			G: big.NewInt(4),
+		},
+		Y: big.NewInt(16), // x = 2
+	}
+	k1 := PrivateKeyShare(secretshare.Share{ID: 1, Value: big.NewInt(4)})
+
+	// p-1 = 22 is of order 2, and 5 is a generator of all of (Z/23Z)*
+	for _, r := range []int64{22, 5, 0, 23} {
+		ctxt := Ciphertext{R: big.NewInt(r), C: make([]byte, 64)}
+
+		_, err := Dec(pub, k1, ctxt)
+		if !errors.Is(err, ErrInvalidCiphertext) {
+			t.Errorf("Expected ErrInvalidCiphertext for R = %d; got %v", r, err)
+		}
+		_, err = DecWithSubgroupCheck(pub, k1, ctxt, true)
+		if !errors.Is(err, ErrInvalidCiphertext) {
+			t.Errorf("Expected ErrInvalidCiphertext for R = %d; got %v", r, err)
+		}
+	}
+
+	// Without the check, R^{x_i} of order 2 leaks the parity of x_i
+	ctxt := Ciphertext{R: big.NewInt(22), C: make([]byte, 64)}
+	d1, err := DecWithSubgroupCheck(pub, k1, ctxt, false)
+	if err != nil {
+		t.Fatalf("DecWithSubgroupCheck returned error: %v", err)
+	}
+	if d1.Value.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("Expected decryption share 1 for even key share; got %d", d1.Value)
+	}
+
+	// R = 3 is of order 11
+	ctxt = Ciphertext{R: big.NewInt(3), C: make([]byte, 64)}
+	_, err = DecWithSubgroupCheck(pub, k1, ctxt, true)
+	if err != nil {
+		t.Errorf("DecWithSubgroupCheck returned error: %v", err)
+	}
+}
+
+func TestDecAll(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 5, 16)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	ctxt, err := Enc(pub, make([]byte, 64))
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	decShares, err := DecAll(pub, shares, ctxt)
+	if err != nil {
+		t.Fatalf("DecAll returned error: %v", err)
+	}
+	if len(decShares) != len(shares) {
+		t.Fatalf("Expected %d decryption shares; got %d", len(shares), len(decShares))
+	}
+
+	for i, share := range shares {
+		expected, err := Dec(pub, share, ctxt)
+		if err != nil {
+			t.Fatalf("Dec returned error: %v", err)
+		}
+
+		if decShares[i].ID != expected.ID || decShares[i].Value.Cmp(expected.Value) != 0 {
+			t.Errorf("Expected decryption share %d to be %+v; got %+v", i, expected, decShares[i])
+		}
+	}
+
+	decShares, err = DecAll(pub, nil, ctxt)
+	if err != nil {
+		t.Errorf("DecAll without shares returned error: %v", err)
+	}
+	if len(decShares) != 0 {
+		t.Errorf("Expected no decryption shares; got %d", len(decShares))
+	}
+
+	// Invalid ciphertexts are rejected as by Dec(), rather than panicking
+	// within a worker
+	_, err = DecAll(pub, shares, Ciphertext{C: ctxt.C})
+	if !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("Expected ErrInvalidCiphertext with nil R; got %v", err)
+	}
+	minusOne := new(big.Int).Sub(pub.P, big.NewInt(1))
+	_, err = DecAll(pub, shares, Ciphertext{R: minusOne, C: ctxt.C})
+	if !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("Expected ErrInvalidCiphertext with R of order 2; got %v", err)
+	}
+}
+
+func TestDecConstantTime(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	ctxt, err := Enc(pub, make([]byte, 64))
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	for _, share := range shares {
+		expected, err := Dec(pub, share, ctxt)
+		if err != nil {
+			t.Fatalf("Dec returned error: %v", err)
+		}
+
+		got, err := DecConstantTime(pub, share, ctxt)
+		if err != nil {
+			t.Fatalf("DecConstantTime returned error: %v", err)
+		}
+
+		if got.ID != expected.ID || got.Value.Cmp(expected.Value) != 0 {
+			t.Errorf("Expected decryption share %+v; got %+v", expected, got)
+		}
+	}
+
+	invalid := PrivateKeyShare(secretshare.Share{ID: 1, Value: pub.Q})
+	_, err = DecConstantTime(pub, invalid, ctxt)
+	if err == nil {
+		t.Errorf("Expected error with key share out of range; got none")
+	}
+
+	// R = p-1 is of order 2, and would leak x_i mod 2
+	minusOne := new(big.Int).Sub(pub.P, big.NewInt(1))
+	_, err = DecConstantTime(pub, shares[0], Ciphertext{R: minusOne, C: ctxt.C})
+	if !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("Expected ErrInvalidCiphertext with R of order 2; got %v", err)
+	}
+	_, err = DecConstantTime(pub, shares[0], Ciphertext{C: ctxt.C})
+	if !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("Expected ErrInvalidCiphertext with nil R; got %v", err)
+	}
+
+	incomplete := pub
+	incomplete.Q = nil
+	_, err = DecConstantTime(incomplete, shares[0], ctxt)
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup with nil Q; got %v", err)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	// 'Hello world', padded to 64 bytes
+	msg := []byte{0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}
+
+	pub := PublicKey{
+		SchnorrGroup: SchnorrGroup{
+			P: big.NewInt(23),
+			Q: big.NewInt(11),
+			G: big.NewInt(4),
+		},
+		Y: big.NewInt(16), // x = 2
+	}
+
+	// // Three keyshares out of a 3-out-of-5 secret share of x
+	// keyShares := []PrivateKeyShare{
+	// 	PrivateKeyShare(secretshare.Share{ID: 1, Value: 4}}),
+	// 	PrivateKeyShare(secretshare.Share{ID: 3, Value: 14}}),
+	// 	PrivateKeyShare(secretshare.Share{ID: 4, Value: 22}}),
+	// }
+
+	// Ciphertext encoding of message above
+	ctxt := Ciphertext{
+		R: big.NewInt(3), // r = 4
+		C: []byte{0xBA, 0x1E, 0x37, 0x94, 0xBC, 0x7E, 0xD5, 0xD4, 0xC9, 0x0, 0x6B, 0x9F, 0xEF, 0x89, 0xD8, 0x83, 0x41, 0x5B, 0x5A, 0xDB, 0xD6, 0xA8, 0x40, 0x30, 0xCB, 0x1F, 0x35, 0xE6, 0xA6, 0xC0, 0x26, 0xE6, 0x5C, 0x60, 0xFB, 0x99, 0xF5, 0x62, 0xF7, 0xEB, 0x9F, 0x77, 0xF3, 0xDE, 0xC5, 0x0, 0x14, 0x73, 0x44, 0x1D, 0x2C, 0x55, 0x86, 0xB5, 0x4D, 0x9B, 0x99, 0x9C, 0xF4, 0xBD, 0x79, 0xE, 0x4C, 0x56},
+	}
+
+	// 3-out-of-5 decryption shares of ciphertext above
+	decryptionShares := []DecryptionShare{
+		DecryptionShare(secretshare.Share{ID: 1, Value: big.NewInt(4)}),
+		DecryptionShare(secretshare.Share{ID: 3, Value: big.NewInt(4)}),
+		DecryptionShare(secretshare.Share{ID: 4, Value: big.NewInt(9)}),
+	}
+
+	recovered, err := Recover(pub, decryptionShares, ctxt)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Recovered message did not match actual message; got %x; expected %x", recovered, msg)
+	}
+
+}
+
+func TestRecoverMoreThanThreshold(t *testing.T) {
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+
+	pub, _, privShares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	decShares, err := DecAll(pub, privShares, ctxt)
+	if err != nil {
+		t.Fatalf("DecAll returned error: %v", err)
+	}
+
+	// Interpolating over 4 or 5 points of a polynomial of degree 2 yields
+	// the same result as over 3 of them.
+	subsets := [][]DecryptionShare{
+		decShares[:3],
+		decShares[:4],
+		decShares[1:],
+		decShares,
+		{decShares[4], decShares[0], decShares[3], decShares[2]},
+	}
+	for _, subset := range subsets {
+		recovered, err := Recover(pub, subset, ctxt)
+		if err != nil {
+			t.Fatalf("Recover returned error: %v", err)
+		}
+		if !bytes.Equal(recovered, msg) {
+			t.Errorf("Expected message %x from %d shares; got %x", msg, len(subset), recovered)
+		}
+	}
+
+	duplicate := []DecryptionShare{decShares[0], decShares[1], decShares[2], decShares[1]}
+	_, err = Recover(pub, duplicate, ctxt)
+	if !errors.Is(err, ErrDuplicateShare) {
+		t.Errorf("Expected ErrDuplicateShare with duplicate share; got %v", err)
+	}
+}
+
+func TestRecoverWithProgress(t *testing.T) {
+	msg, err := PadMessage([]byte("Hello world"))
+	if err != nil {
+		t.Fatalf("PadMessage returned error: %v", err)
+	}
+
+	pub, _, privShares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+	decShares, err := DecAll(pub, privShares[:4], ctxt)
+	if err != nil {
+		t.Fatalf("DecAll returned error: %v", err)
+	}
+
+	calls := 0
+	recovered, err := RecoverWithProgress(pub, decShares, ctxt, func(collected, needed int) {
+		calls++
+		if collected != calls {
+			t.Errorf("Expected %d collected shares; got %d", calls, collected)
+		}
+		if needed != len(decShares) {
+			t.Errorf("Expected %d needed shares; got %d", len(decShares), needed)
+		}
+	})
+	if err != nil {
+		t.Fatalf("RecoverWithProgress returned error: %v", err)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recovered)
+	}
+	if calls != len(decShares) {
+		t.Errorf("Expected %d callback invocations; got %d", len(decShares), calls)
+	}
+
+	// Nil callback
+	_, err = RecoverWithProgress(pub, decShares, ctxt, nil)
+	if err != nil {
+		t.Errorf("RecoverWithProgress returned error with nil callback: %v", err)
+	}
+}
+
+func TestDegenerateSecret(t *testing.T) {
+	msg, err := PadMessage([]byte("Hello world"))
+	if err != nil {
+		t.Fatalf("PadMessage returned error: %v", err)
+	}
+
+	// y = 1 yields y^r = 1 for any r
+	pub := PublicKey{
+		SchnorrGroup: SchnorrGroup{
+			P: big.NewInt(23),
+			Q: big.NewInt(11),
+			G: big.NewInt(4),
+		},
+		Y: big.NewInt(1),
+	}
+
+	_, err = Enc(pub, msg)
+	if !errors.Is(err, ErrDegenerateSecret) {
+		t.Errorf("Expected ErrDegenerateSecret from Enc with y = 1; got %v", err)
+	}
+	_, err = EncBatch(pub, [][]byte{msg})
+	if !errors.Is(err, ErrDegenerateSecret) {
+		t.Errorf("Expected ErrDegenerateSecret from EncBatch with y = 1; got %v", err)
+	}
+	_, err = EncAD(pub, msg, nil)
+	if !errors.Is(err, ErrDegenerateSecret) {
+		t.Errorf("Expected ErrDegenerateSecret from EncAD with y = 1; got %v", err)
+	}
+
+	// R = 1 yields decryption shares R^{x_i} = 1, and as such R^x = 1
+	pub.Y = big.NewInt(16) // x = 2
+	ctxt := Ciphertext{R: big.NewInt(1), C: make([]byte, 64)}
+	decryptionShares := []DecryptionShare{
+		DecryptionShare(secretshare.Share{ID: 1, Value: big.NewInt(1)}),
+		DecryptionShare(secretshare.Share{ID: 3, Value: big.NewInt(1)}),
+		DecryptionShare(secretshare.Share{ID: 4, Value: big.NewInt(1)}),
+	}
+	_, err = Recover(pub, decryptionShares, ctxt)
+	if !errors.Is(err, ErrDegenerateSecret) {
+		t.Errorf("Expected ErrDegenerateSecret from Recover with R = 1; got %v", err)
+	}
+	_, err = DecryptWhole(pub, PrivateKey{X: big.NewInt(2)}, ctxt)
+	if !errors.Is(err, ErrDegenerateSecret) {
+		t.Errorf("Expected ErrDegenerateSecret from DecryptWhole with R = 1; got %v", err)
+	}
+}
+
+func TestCombineShares(t *testing.T) {
+	pub := PublicKey{
+		SchnorrGroup: SchnorrGroup{
+			P: big.NewInt(23),
+			Q: big.NewInt(11),
+			G: big.NewInt(4),
+		},
+		Y:         big.NewInt(16), // x = 2
+		Threshold: 3,
+	}
+
+	// Decryption shares of TestRecover, for R = 3 with r = 4
+	decryptionShares := []DecryptionShare{
+		DecryptionShare(secretshare.Share{ID: 1, Value: big.NewInt(4)}),
+		DecryptionShare(secretshare.Share{ID: 3, Value: big.NewInt(4)}),
+		DecryptionShare(secretshare.Share{ID: 4, Value: big.NewInt(9)}),
+	}
+
+	z, err := CombineShares(pub, decryptionShares)
+	if err != nil {
+		t.Fatalf("CombineShares returned error: %v", err)
+	}
+
+	// y^r = 16^4 = 9 = g^{rx} = 4^8 mod 23
+	expected := new(big.Int).Exp(pub.Y, big.NewInt(4), pub.P)
+	if z.Cmp(expected) != 0 {
+		t.Errorf("Expected combined shares to be %d; got %d", expected, z)
+	}
+
+	_, err = CombineShares(pub, decryptionShares[:2])
+	if !errors.Is(err, ErrThresholdNotMet) {
+		t.Errorf("Expected ErrThresholdNotMet with too few shares; got %v", err)
+	}
+}
+
+// This tests the whole thing end-to-end, with real-world keys.
+// Hopefully catching any issues which might be the result of the
+// handcrafted values above.
+func TestIntegration(t *testing.T) {
+	// 'Hello world', padded to 64 bytes
+	msg := []byte{0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}
+
+	pub, _, privShares, err := KeyGen(1024, 256, 4, 6)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	decShare1, err := Dec(pub, privShares[0], ctxt)
+	if err != nil {
+		t.Fatalf("Dec returned error: %v", err)
+	}
+
+	decShare3, err := Dec(pub, privShares[2], ctxt)
+	if err != nil {
+		t.Fatalf("Dec returned error: %v", err)
+	}
+
+	decShare4, err := Dec(pub, privShares[3], ctxt)
+	if err != nil {
+		t.Fatalf("Dec returned error: %v", err)
+	}
+
+	decShare5, err := Dec(pub, privShares[4], ctxt)
+	if err != nil {
+		t.Fatalf("Dec returned error: %v", err)
+	}
+
+	decShares := []DecryptionShare{
+		decShare1,
+		decShare3,
+		decShare4,
+		decShare5,
+	}
+
+	recov, err := Recover(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if !bytes.Equal(msg, recov) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recov)
+	}
+}
+
+// This tests temporarily centralizing a threshold key and re-distributing it
+// under a new threshold.
+func TestReconstructDecryptReshare(t *testing.T) {
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+
+	pub, _, privShares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	zq, err := pub.Zq()
+	if err != nil {
+		t.Fatalf("Error generating field: %v", err)
+	}
+
+	// Reconstruct the private key from a threshold of shares
+	priv, err := RecoverPrivateKey(pub, []PrivateKeyShare{privShares[0], privShares[2], privShares[4]})
+	if err != nil {
+		t.Fatalf("Error recovering private key: %v", err)
+	}
+
+	recov, err := DecryptWhole(pub, priv, ctxt)
+	if err != nil {
+		t.Fatalf("DecryptWhole returned error: %v", err)
+	}
+	if !bytes.Equal(recov, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recov)
+	}
+
+	_, err = DecryptWhole(pub, priv, Ciphertext{C: ctxt.C})
+	if !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("Expected ErrInvalidCiphertext from DecryptWhole with nil R; got %v", err)
+	}
+	_, err = DecryptWhole(pub, PrivateKey{}, ctxt)
+	if err == nil {
+		t.Errorf("Expected error from DecryptWhole with nil x; got none")
+	}
+
+	// Re-split the private key under a 2-out-of-4 policy
+	newShares, _, err := secretshare.TOutOfN(priv.X, 2, 4, zq)
+	if err != nil {
+		t.Fatalf("Error re-sharing private key: %v", err)
+	}
+	pub.Threshold = 2
+
+	decShares := make([]DecryptionShare, 2)
+	for i, share := range []secretshare.Share{newShares[1], newShares[3]} {
+		decShares[i], err = Dec(pub, PrivateKeyShare(share), ctxt)
+		if err != nil {
+			t.Fatalf("Dec returned error: %v", err)
+		}
+	}
+
+	recov, err = Recover(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if !bytes.Equal(recov, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recov)
+	}
+}
+
+func TestBlockSize(t *testing.T) {
+	pub := PublicKey{}
+	if pub.BlockSize() != 64 {
+		t.Errorf("Expected default block size of 64; got %d", pub.BlockSize())
+	}
+
+	pub.Hash = crypto.SHA256
+	if pub.BlockSize() != 32 {
+		t.Errorf("Expected block size of 32 with SHA256; got %d", pub.BlockSize())
+	}
+
+	pub.Hash = crypto.MD5
+	if pub.BlockSize() != 0 {
+		t.Errorf("Expected block size of 0 with unsupported hash; got %d", pub.BlockSize())
+	}
+}
+
+func TestMessageSize(t *testing.T) {
+	pub, _, _, err := KeyGen(512, 128, 2, 3)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	if MessageSize(pub) != 64 {
+		t.Errorf("Expected message size of 64; got %d", MessageSize(pub))
+	}
+
+	_, err = Enc(pub, make([]byte, MessageSize(pub)))
+	if err != nil {
+		t.Errorf("Enc returned error for message of %d bytes: %v", MessageSize(pub), err)
+	}
+	_, err = Enc(pub, make([]byte, MessageSize(pub)-1))
+	if !errors.Is(err, ErrMessageLength) {
+		t.Errorf("Expected ErrMessageLength for message of %d bytes; got %v", MessageSize(pub)-1, err)
+	}
+
+	pub.Hash = crypto.SHA256
+	if MessageSize(pub) != 32 {
+		t.Errorf("Expected message size of 32 with SHA256; got %d", MessageSize(pub))
+	}
+}
+
+func TestIntegrationSHA256(t *testing.T) {
+	msg := make([]byte, 32)
+	copy(msg, []byte("Hello world"))
+
+	pub, _, privShares, err := KeyGen(512, 128, 2, 3)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	pub.Hash = crypto.SHA256
+
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+	if len(ctxt.C) != 32 {
+		t.Errorf("Expected ciphertext of 32 bytes; got %d", len(ctxt.C))
+	}
+
+	decShares := make([]DecryptionShare, 2)
+	for i := range decShares {
+		decShares[i], err = Dec(pub, privShares[i], ctxt)
+		if err != nil {
+			t.Fatalf("Dec returned error: %v", err)
+		}
+	}
+
+	recov, err := Recover(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if !bytes.Equal(msg, recov) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recov)
+	}
+
+	_, err = Enc(pub, make([]byte, 64))
+	if err == nil {
+		t.Errorf("Expected error if message is not 32 bytes; got none")
+	}
+
+	pub.Hash = crypto.MD5
+	_, err = Enc(pub, msg)
+	if err == nil {
+		t.Errorf("Expected error with unsupported hash; got none")
+	}
+}
+
+func TestRecoverInFields(t *testing.T) {
+	pub := PublicKey{
+		SchnorrGroup: SchnorrGroup{
+			P: big.NewInt(23),
+			Q: big.NewInt(11),
+			G: big.NewInt(4),
+		},
+		Y: big.NewInt(16), // x = 2
+	}
+
+	// 'Hello world', padded to 64 bytes
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+
+	// Ciphertext and decryption shares from TestRecover
+	ctxt := Ciphertext{
+		R: big.NewInt(3), // r = 4
+		C: []byte{0xBA, 0x1E, 0x37, 0x94, 0xBC, 0x7E, 0xD5, 0xD4, 0xC9, 0x0, 0x6B, 0x9F, 0xEF, 0x89, 0xD8, 0x83, 0x41, 0x5B, 0x5A, 0xDB, 0xD6, 0xA8, 0x40, 0x30, 0xCB, 0x1F, 0x35, 0xE6, 0xA6, 0xC0, 0x26, 0xE6, 0x5C, 0x60, 0xFB, 0x99, 0xF5, 0x62, 0xF7, 0xEB, 0x9F, 0x77, 0xF3, 0xDE, 0xC5, 0x0, 0x14, 0x73, 0x44, 0x1D, 0x2C, 0x55, 0x86, 0xB5, 0x4D, 0x9B, 0x99, 0x9C, 0xF4, 0xBD, 0x79, 0xE, 0x4C, 0x56},
+	}
+	decryptionShares := []DecryptionShare{
+		DecryptionShare(secretshare.Share{ID: 1, Value: big.NewInt(4)}),
+		DecryptionShare(secretshare.Share{ID: 3, Value: big.NewInt(4)}),
+		DecryptionShare(secretshare.Share{ID: 4, Value: big.NewInt(9)}),
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		t.Fatalf("Error generating field: %v", err)
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		t.Fatalf("Error generating field: %v", err)
+	}
+
+	recovered, err := RecoverInFields(zp, zq, crypto.SHA512, decryptionShares, ctxt)
+	if err != nil {
+		t.Fatalf("RecoverInFields returned error: %v", err)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recovered)
+	}
+
+	_, err = RecoverInFields(zp, zq, crypto.SHA256, decryptionShares, ctxt)
+	if err == nil {
+		t.Errorf("Expected error if ciphertext does not match hash size; got none")
+	}
+
+	// The zero value selects the default hash algorithm, as for public keys
+	recovered, err = RecoverInFields(zp, zq, 0, decryptionShares, ctxt)
+	if err != nil {
+		t.Fatalf("RecoverInFields returned error with hash algorithm 0: %v", err)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected recovered message %x with hash algorithm 0; got %x", msg, recovered)
+	}
+
+	duplicate := []DecryptionShare{decryptionShares[0], decryptionShares[0], decryptionShares[1]}
+	_, err = RecoverInFields(zp, zq, crypto.SHA512, duplicate, ctxt)
+	if !errors.Is(err, ErrDuplicateShare) {
+		t.Errorf("Expected ErrDuplicateShare; got %v", err)
+	}
+	_, err = RecoverInFields(zp, zq, crypto.SHA512, []DecryptionShare{{ID: 1}}, ctxt)
+	if err == nil {
+		t.Errorf("Expected error with nil decryption share; got none")
+	}
+	_, err = RecoverInFields(zp, zq, crypto.SHA512, nil, ctxt)
+	if !errors.Is(err, ErrThresholdNotMet) {
+		t.Errorf("Expected ErrThresholdNotMet without decryption shares; got %v", err)
+	}
+}
+
+func BenchmarkRecoverNewFields(b *testing.B) {
+	f := newBenchmarkFixture(b, 1024, 256, 3, 5)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := Recover(f.pub, f.decShares[:3], f.ctxt)
+		if err != nil {
+			b.Fatalf("Recover returned error: %v", err)
+		}
+	}
+}
+
+func BenchmarkRecoverInFields(b *testing.B) {
+	f := newBenchmarkFixture(b, 1024, 256, 3, 5)
+	zp, err := f.pub.Zp()
+	if err != nil {
+		b.Fatalf("Error generating field: %v", err)
+	}
+	zq, err := f.pub.Zq()
+	if err != nil {
+		b.Fatalf("Error generating field: %v", err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := RecoverInFields(zp, zq, crypto.SHA512, f.decShares[:3], f.ctxt)
+		if err != nil {
+			b.Fatalf("RecoverInFields returned error: %v", err)
+		}
+	}
+}
+
+func TestEncLabeled(t *testing.T) {
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+
+	pub, _, privShares, err := KeyGen(512, 128, 2, 3)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	ctxt, err := EncLabeled(pub, msg, []byte("tenant-1"))
+	if err != nil {
+		t.Fatalf("EncLabeled returned error: %v", err)
+	}
+	if !bytes.Equal(ctxt.Label, []byte("tenant-1")) {
+		t.Errorf("Expected label %q; got %q", "tenant-1", ctxt.Label)
+	}
+
+	decShares := make([]DecryptionShare, 2)
+	for i := range decShares {
+		decShares[i], err = Dec(pub, privShares[i], ctxt)
+		if err != nil {
+			t.Fatalf("Dec returned error: %v", err)
+		}
+	}
+
+	recov, err := RecoverLabeled(pub, decShares, ctxt, []byte("tenant-1"))
+	if err != nil {
+		t.Fatalf("RecoverLabeled returned error: %v", err)
+	}
+	if !bytes.Equal(msg, recov) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recov)
+	}
+
+	_, err = RecoverLabeled(pub, decShares, ctxt, []byte("tenant-2"))
+	if err == nil {
+		t.Errorf("Expected error when recovering under wrong label; got none")
+	}
+
+	// Replaying the ciphertext under a different label yields garbage
+	replayed := ctxt
+	replayed.Label = []byte("tenant-2")
+	recov, err = RecoverLabeled(pub, decShares, replayed, []byte("tenant-2"))
+	if err != nil {
+		t.Fatalf("RecoverLabeled returned error: %v", err)
+	}
+	if bytes.Equal(msg, recov) {
+		t.Errorf("Expected relabeled ciphertext not to decrypt to original message")
+	}
+
+	// Stripping the label yields garbage too
+	stripped := ctxt
+	stripped.Label = nil
+	recov, err = Recover(pub, decShares, stripped)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if bytes.Equal(msg, recov) {
+		t.Errorf("Expected unlabeled ciphertext not to decrypt to original message")
+	}
+}
+
+func TestRecoverTooFewShares(t *testing.T) {
+	pub, _, privShares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	ctxt, err := Enc(pub, make([]byte, 64))
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	decShares := make([]DecryptionShare, 2)
+	for i := range decShares {
+		decShares[i], err = Dec(pub, privShares[i], ctxt)
+		if err != nil {
+			t.Fatalf("Dec returned error: %v", err)
+		}
+	}
+
+	_, err = Recover(pub, decShares, ctxt)
+	if err == nil {
+		t.Errorf("Expected error when passing 2 shares where 3 are needed; got none")
+	}
+}
+
+func TestMinimalSubsets(t *testing.T) {
+	subsets := MinimalSubsets([]int{1, 2, 3, 4, 5}, 3)
+	if len(subsets) != 10 {
+		t.Fatalf("Expected C(5, 3) = 10 subsets; got %d", len(subsets))
+	}
+
+	seen := make(map[string]bool)
+	for _, subset := range subsets {
+		if len(subset) != 3 {
+			t.Errorf("Expected subset of size 3; got %v", subset)
+		}
+		key := fmt.Sprint(subset)
+		if seen[key] {
+			t.Errorf("Expected distinct subsets; got %v twice", subset)
+		}
+		seen[key] = true
+	}
+
+	subsets = MinimalSubsets([]int{7, 2, 9, 2}, 2)
+	expected := [][]int{{7, 2}, {7, 9}, {2, 9}}
+	if !reflect.DeepEqual(subsets, expected) {
+		t.Errorf("Expected subsets %v; got %v", expected, subsets)
+	}
+
+	if len(MinimalSubsets([]int{1, 2}, 3)) != 0 {
+		t.Errorf("Expected no subsets with threshold above number of IDs")
+	}
+	if len(MinimalSubsets([]int{1, 2}, 0)) != 0 {
+		t.Errorf("Expected no subsets with threshold 0")
+	}
+}
+
+func TestRecoverMinimal(t *testing.T) {
+	pub, _, privShares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	// All shares, in non-sorted order
+	decShares := make([]DecryptionShare, 0, 5)
+	for _, i := range []int{4, 0, 2, 3, 1} {
+		share, err := Dec(pub, privShares[i], ctxt)
+		if err != nil {
+			t.Fatalf("Dec returned error: %v", err)
+		}
+		decShares = append(decShares, share)
+	}
+
+	full, err := Recover(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	minimal, err := RecoverMinimal(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("RecoverMinimal returned error: %v", err)
+	}
+	if !bytes.Equal(minimal, full) || !bytes.Equal(minimal, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, minimal)
+	}
+
+	ids := shareIDs(minimalShares(pub.Threshold, decShares))
+	expected := []int{1, 2, 3}
+	if len(ids) != len(expected) {
+		t.Fatalf("Expected IDs %v; got %v", expected, ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Errorf("Expected IDs %v; got %v", expected, ids)
+			break
+		}
+	}
+
+	// The share with ID 5 is not combined, so corrupting it goes
+	// unnoticed
+	decShares[0].Value = big.NewInt(1)
+	minimal, err = RecoverMinimal(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("RecoverMinimal returned error: %v", err)
+	}
+	if !bytes.Equal(minimal, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, minimal)
+	}
+	if decShares[0].ID != 5 {
+		t.Errorf("Expected passed shares to be left in order; got %v", shareIDs(decShares))
+	}
+
+	_, err = RecoverMinimal(pub, decShares[:2], ctxt)
+	if err == nil {
+		t.Errorf("Expected error with fewer than t shares; got none")
+	}
+}
+
+func TestRecoverWithSelection(t *testing.T) {
+	pub, _, privShares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	// More than t shares, in non-sorted order
+	decShares := make([]DecryptionShare, 0, 4)
+	for _, i := range []int{4, 0, 2, 3} {
+		share, err := Dec(pub, privShares[i], ctxt)
+		if err != nil {
+			t.Fatalf("Dec returned error: %v", err)
+		}
+		decShares = append(decShares, share)
+	}
+
+	recovered, ids, err := RecoverWithSelection(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("RecoverWithSelection returned error: %v", err)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recovered)
+	}
+
+	expected := []int{5, 1, 3, 4}
+	if len(ids) != len(expected) {
+		t.Fatalf("Expected IDs %v; got %v", expected, ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Errorf("Expected IDs %v; got %v", expected, ids)
+			break
+		}
+	}
+
+	_, ids, err = RecoverWithSelection(pub, decShares[:2], ctxt)
+	if err == nil {
+		t.Errorf("Expected error with fewer than t shares; got none")
+	}
+	if ids != nil {
+		t.Errorf("Expected no IDs on error; got %v", ids)
+	}
+}
+
+func TestRecoverVerified(t *testing.T) {
+	pub, _, privShares, err := KeyGen(512, 128, 3, 6)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	decShares, err := DecAll(pub, privShares[:5], ctxt)
+	if err != nil {
+		t.Fatalf("DecAll returned error: %v", err)
+	}
+
+	// All honest
+	recovered, err := RecoverVerified(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("RecoverVerified returned error: %v", err)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recovered)
+	}
+
+	// t+1 honest shares and a single liar
+	lying := make([]DecryptionShare, len(decShares))
+	copy(lying, decShares)
+	lying[2].Value = new(big.Int).Mul(lying[2].Value, pub.G)
+	lying[2].Value.Mod(lying[2].Value, pub.P)
+
+	recovered, err = RecoverVerified(pub, lying, ctxt)
+	var inconsistent *InconsistentShareError
+	if !errors.As(err, &inconsistent) {
+		t.Fatalf("Expected InconsistentShareError; got %v", err)
+	}
+	if len(inconsistent.IDs) != 1 || inconsistent.IDs[0] != lying[2].ID {
+		t.Errorf("Expected inconsistent share IDs [%d]; got %v", lying[2].ID, inconsistent.IDs)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected majority to recover message %x; got %x", msg, recovered)
+	}
+
+	// With only t honest shares, there is no majority
+	_, err = RecoverVerified(pub, lying[:4], ctxt)
+	if err == nil || errors.As(err, &inconsistent) {
+		t.Errorf("Expected error without majority; got %v", err)
+	}
+
+	// Duplicate shares
+	_, err = RecoverVerified(pub, append(decShares[:3:3], decShares[0]), ctxt)
+	if err == nil {
+		t.Errorf("Expected error with duplicate shares; got none")
+	}
+
+	// Too few shares
+	_, err = RecoverVerified(pub, decShares[:2], ctxt)
+	if err == nil {
+		t.Errorf("Expected error with fewer than t shares; got none")
+	}
+}
+
+func TestCiphertextEqual(t *testing.T) {
+	a := Ciphertext{R: big.NewInt(3), C: []byte{0x01, 0x02}}
+
+	tests := []struct {
+		name     string
+		other    Ciphertext
+		expected bool
+	}{
+		{"identical", Ciphertext{R: big.NewInt(3), C: []byte{0x01, 0x02}}, true},
+		{"different R", Ciphertext{R: big.NewInt(4), C: []byte{0x01, 0x02}}, false},
+		{"nil R", Ciphertext{C: []byte{0x01, 0x02}}, false},
+		{"different C", Ciphertext{R: big.NewInt(3), C: []byte{0x01, 0x03}}, false},
+		{"shorter C", Ciphertext{R: big.NewInt(3), C: []byte{0x01}}, false},
+		{"different label", Ciphertext{R: big.NewInt(3), C: []byte{0x01, 0x02}, Label: []byte("a")}, false},
+	}
+
+	for _, test := range tests {
+		if a.Equal(test.other) != test.expected {
+			t.Errorf("%s: Expected Equal() = %v; got %v", test.name, test.expected, !test.expected)
+		}
+	}
+
+	if !(Ciphertext{}).Equal(Ciphertext{}) {
+		t.Errorf("Expected zero-value ciphertexts to be equal")
+	}
+}
+
+func TestCiphertextClone(t *testing.T) {
+	ctxt := Ciphertext{R: big.NewInt(3), C: []byte{0x01, 0x02}, Label: []byte("a")}
+
+	clone := ctxt.Clone()
+	if !clone.Equal(ctxt) {
+		t.Errorf("Expected clone %+v to equal original %+v", clone, ctxt)
+	}
+
+	clone.R.SetInt64(4)
+	clone.C[0] = 0xFF
+	clone.Label[0] = 'b'
+
+	if ctxt.R.Cmp(big.NewInt(3)) != 0 || ctxt.C[0] != 0x01 || ctxt.Label[0] != 'a' {
+		t.Errorf("Expected mutating clone not to affect original; got %+v", ctxt)
+	}
+
+	empty := Ciphertext{}.Clone()
+	if empty.R != nil || empty.C != nil || empty.Label != nil {
+		t.Errorf("Expected clone of zero value to be zero value; got %+v", empty)
+	}
+}
+
+func TestPublicKeyFieldCache(t *testing.T) {
+	pub, _, _, err := KeyGen(20, 10, 3, 5)
+	if err != nil {
+		t.Fatalf("Error in KeyGen: %v", err)
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		t.Fatalf("Error generating field: %v", err)
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		t.Fatalf("Error generating field: %v", err)
+	}
+	if zp.P.Cmp(pub.P) != 0 || zq.P.Cmp(pub.Q) != 0 {
+		t.Errorf("Expected GF(%d) and GF(%d); got GF(%d) and GF(%d)", pub.P, pub.Q, zp.P, zq.P)
+	}
+
+	// Copies share the cache
+	cpy := pub
+	zp2, err := cpy.Zp()
+	if err != nil {
+		t.Fatalf("Error generating field: %v", err)
+	}
+	if zp2.P != zp.P {
+		t.Errorf("Expected copy of key to return memoized field")
+	}
+
+	// Changing the parameters must not return stale fields
+	cpy.SchnorrGroup = SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(11), G: big.NewInt(4)}
+	zp3, err := cpy.Zp()
+	if err != nil {
+		t.Fatalf("Error generating field: %v", err)
+	}
+	if zp3.P.Cmp(big.NewInt(23)) != 0 {
+		t.Errorf("Expected GF(23) after changing parameters; got GF(%d)", zp3.P)
+	}
+
+	// Decoding from JSON resets the cache
+	data, err := json.Marshal(cpy)
+	if err != nil {
+		t.Fatalf("Error marshalling public key: %v", err)
+	}
+	err = json.Unmarshal(data, &pub)
+	if err != nil {
+		t.Fatalf("Error unmarshalling public key: %v", err)
+	}
+	zq4, err := pub.Zq()
+	if err != nil {
+		t.Fatalf("Error generating field: %v", err)
+	}
+	if zq4.P.Cmp(big.NewInt(11)) != 0 {
+		t.Errorf("Expected GF(11) after decoding; got GF(%d)", zq4.P)
+	}
+}
+
+// benchmarkDec measures Dec() with a key which memoizes its fields if cached
+// is true. Run with -benchtime=10000x to compare 10000 calls.
+func benchmarkDec(b *testing.B, cached bool) {
+	f := newBenchmarkFixture(b, 1024, 256, 3, 5)
+	if !cached {
+		f.pub.fields = nil
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := Dec(f.pub, f.keyShares[0], f.ctxt)
+		if err != nil {
+			b.Fatalf("Dec returned error: %v", err)
+		}
+	}
+}
+
+func BenchmarkDecUncachedFields(b *testing.B) {
+	benchmarkDec(b, false)
+}
+
+func BenchmarkDecCachedFields(b *testing.B) {
+	benchmarkDec(b, true)
+}
+
+func BenchmarkDecSerial(b *testing.B) {
+	f := newBenchmarkFixture(b, 1024, 256, 5, 16)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, share := range f.keyShares {
+			_, err := Dec(f.pub, share, f.ctxt)
+			if err != nil {
+				b.Fatalf("Dec returned error: %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkDecAll(b *testing.B) {
+	f := newBenchmarkFixture(b, 1024, 256, 5, 16)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := DecAll(f.pub, f.keyShares, f.ctxt)
+		if err != nil {
+			b.Fatalf("DecAll returned error: %v", err)
+		}
+	}
+}
+
+func benchmarkEncBatchSetup(b *testing.B) (PublicKey, [][]byte) {
+	pub := newBenchmarkFixture(b, 1024, 256, 3, 5).pub
+	// As with a public key decoded from its serialized form
+	pub.fields = nil
+
+	return pub, benchmarkMessages(pub, 64)
+}
+
+func BenchmarkEncLoop(b *testing.B) {
+	pub, messages := benchmarkEncBatchSetup(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, message := range messages {
+			_, err := Enc(pub, message)
+			if err != nil {
+				b.Fatalf("Enc returned error: %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkEncBatch(b *testing.B) {
+	pub, messages := benchmarkEncBatchSetup(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := EncBatch(pub, messages)
+		if err != nil {
+			b.Fatalf("EncBatch returned error: %v", err)
+		}
+	}
+}
+
+func BenchmarkRecoverAllShares(b *testing.B) {
+	f := newBenchmarkFixture(b, 1024, 256, 3, 5)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := Recover(f.pub, f.decShares, f.ctxt)
+		if err != nil {
+			b.Fatalf("Recover returned error: %v", err)
+		}
+	}
+}
+
+func BenchmarkRecoverMinimal(b *testing.B) {
+	f := newBenchmarkFixture(b, 1024, 256, 3, 5)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := RecoverMinimal(f.pub, f.decShares, f.ctxt)
+		if err != nil {
+			b.Fatalf("RecoverMinimal returned error: %v", err)
+		}
 	}
 }
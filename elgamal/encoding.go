@@ -0,0 +1,652 @@
+package elgamal
+
+import (
+	"crypto"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/lavode/secret-sharing/secretshare"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// lengthPrefixSize is the size - in bytes - of the length prefixes used in
+// the binary encoding of ciphertexts.
+const lengthPrefixSize int = 4
+
+// extensionFlag is set in the length prefix of R if the binary encoding of a
+// ciphertext contains a label or tag. As R is never longer than p, the most
+// significant bit of its length is otherwise unused.
+const extensionFlag uint32 = 1 << 31
+
+// MarshalBinary encodes the ciphertext into a binary form.
+//
+// The encoding consists of the length of R - as a 4-byte big-endian unsigned
+// integer - followed by the big-endian bytes of R, followed by C, which is
+// exactly one block. For ciphertexts created by this package, R is
+// left-padded with zeros to the byte length of p, such that the encoding is
+// of a fixed size under a given public key, as returned by CiphertextSize().
+//
+// Labeled ciphertexts and ciphertexts with an integrity tag additionally have
+// their label and tag inserted between R and C, each prefixed with its length
+// in the same way, and with a length of 0 if absent. This is signalled by
+// setting the most significant bit of the length prefix of R, such that
+// unlabeled ciphertexts without tag encode exactly as before labels were
+// introduced, while decoders predating labels reject the others.
+//
+// As Ciphertext implements encoding.BinaryMarshaler, encoding/gob uses this
+// encoding too.
+//
+// An error is returned if R is missing, or if C is not exactly one block
+// under any of the supported hash algorithms.
+func (ctxt Ciphertext) MarshalBinary() ([]byte, error) {
+	if ctxt.R == nil {
+		return nil, fmt.Errorf("Ciphertext is missing R")
+	}
+	if !isBlockSize(len(ctxt.C)) {
+		return nil, fmt.Errorf("C must be exactly one block; got %d bytes", len(ctxt.C))
+	}
+
+	r := fixedBytes(ctxt.R, ctxt.rSize)
+
+	out := make([]byte, 0, 3*lengthPrefixSize+len(r)+len(ctxt.Label)+len(ctxt.Tag)+len(ctxt.C))
+	out = appendLengthPrefixed(out, r)
+	if len(ctxt.Label) > 0 || len(ctxt.Tag) > 0 {
+		binary.BigEndian.PutUint32(out, uint32(len(r))|extensionFlag)
+		out = appendLengthPrefixed(out, ctxt.Label)
+		out = appendLengthPrefixed(out, ctxt.Tag)
+	}
+	out = append(out, ctxt.C...)
+
+	return out, nil
+}
+
+// CiphertextSize returns the size - in bytes - of the binary encoding, as
+// produced by MarshalBinary(), of unlabeled ciphertexts without tag which
+// were created under this public key. This allows to preallocate buffers or
+// size network frames.
+//
+// The size is that of the length prefix of R, plus the byte length of p, plus
+// pub.BlockSize(). Zero is returned if P is missing or the hash algorithm is
+// not supported.
+func (pk PublicKey) CiphertextSize() int {
+	blockSize := pk.BlockSize()
+	if pk.P == nil || blockSize == 0 {
+		return 0
+	}
+
+	return lengthPrefixSize + (pk.P.BitLen()+7)/8 + blockSize
+}
+
+// UnmarshalBinary decodes a ciphertext which was encoded using
+// MarshalBinary().
+//
+// An error is returned if a length prefix overruns the input, if the label
+// and tag are flagged as present but both are empty, or if C is not exactly
+// one block under any of the supported hash algorithms. Whether C is one
+// block under a given public key is only checked during decryption.
+func (ctxt *Ciphertext) UnmarshalBinary(data []byte) error {
+	if len(data) < lengthPrefixSize {
+		return fmt.Errorf("Length prefix of R must be %d bytes; got %d", lengthPrefixSize, len(data))
+	}
+
+	prefix := binary.BigEndian.Uint32(data)
+	rLen := prefix &^ extensionFlag
+	data = data[lengthPrefixSize:]
+	if uint64(rLen) > uint64(len(data)) {
+		return fmt.Errorf("Length of R (%d bytes) exceeds remaining %d bytes", rLen, len(data))
+	}
+	r, data := data[:rLen], data[rLen:]
+
+	var label, tag []byte
+	if prefix&extensionFlag != 0 {
+		var err error
+		label, data, err = readLengthPrefixed("label", data)
+		if err != nil {
+			return err
+		}
+		tag, data, err = readLengthPrefixed("tag", data)
+		if err != nil {
+			return err
+		}
+		// Only ciphertexts with a label or tag are flagged
+		if len(label) == 0 && len(tag) == 0 {
+			return fmt.Errorf("Ciphertext is flagged as having a label or tag, but has neither")
+		}
+	}
+
+	c := data
+	if !isBlockSize(len(c)) {
+		return fmt.Errorf("C must be exactly one block; got %d bytes", len(c))
+	}
+
+	ctxt.R = new(big.Int).SetBytes(r)
+	ctxt.rSize = len(r)
+	ctxt.C = make([]byte, len(c))
+	copy(ctxt.C, c)
+	ctxt.Label = nil
+	if len(label) > 0 {
+		ctxt.Label = make([]byte, len(label))
+		copy(ctxt.Label, label)
+	}
+	ctxt.Tag = nil
+	if len(tag) > 0 {
+		ctxt.Tag = make([]byte, len(tag))
+		copy(ctxt.Tag, tag)
+	}
+
+	return nil
+}
+
+// isBlockSize returns whether n is the output size of one of the supported
+// hash algorithms, and thus the length of C under some public key.
+func isBlockSize(n int) bool {
+	for _, hash := range supportedHashes {
+		if n == hash.Size() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// appendLengthPrefixed appends field to out, prefixed with its length as a
+// 4-byte big-endian unsigned integer.
+func appendLengthPrefixed(out []byte, field []byte) []byte {
+	var prefix [lengthPrefixSize]byte
+	binary.BigEndian.PutUint32(prefix[:], uint32(len(field)))
+
+	out = append(out, prefix[:]...)
+	return append(out, field...)
+}
+
+// fixedBytes returns the big-endian encoding of the non-negative integer x,
+// left-padded with zeros to size bytes. Unlike x.Bytes(), the length of the
+// encoding is thus independent of the magnitude of x, as long as x fits into
+// size bytes. If it does not, the minimal encoding is returned.
+func fixedBytes(x *big.Int, size int) []byte {
+	n := (x.BitLen() + 7) / 8
+	if n > size {
+		return x.Bytes()
+	}
+
+	return x.FillBytes(make([]byte, size))
+}
+
+// readLengthPrefixed reads a field which was encoded by
+// appendLengthPrefixed(), returning the field and the remaining data. The
+// name of the field is used in error messages.
+func readLengthPrefixed(name string, data []byte) ([]byte, []byte, error) {
+	if len(data) < lengthPrefixSize {
+		return nil, data, fmt.Errorf("Length prefix of %s must be %d bytes; got %d", name, lengthPrefixSize, len(data))
+	}
+
+	length := binary.BigEndian.Uint32(data)
+	data = data[lengthPrefixSize:]
+	if uint64(length) > uint64(len(data)) {
+		return nil, data, fmt.Errorf("Length of %s (%d bytes) exceeds remaining %d bytes", name, length, len(data))
+	}
+
+	return data[:length], data[length:], nil
+}
+
+// schnorrGroupJSON is the JSON representation of a SchnorrGroup.
+type schnorrGroupJSON struct {
+	P string `json:"p"`
+	Q string `json:"q"`
+	G string `json:"g"`
+}
+
+// publicKeyJSON is the JSON representation of a PublicKey.
+type publicKeyJSON struct {
+	schnorrGroupJSON
+	Y                string      `json:"y"`
+	Hash             string      `json:"hash,omitempty"`
+	VerificationKeys []shareJSON `json:"verification_keys,omitempty"`
+	Threshold        int         `json:"threshold,omitempty"`
+	Commitments      []string    `json:"commitments,omitempty"`
+}
+
+// shareJSON is the JSON representation of a secret share, such as a
+// VerificationKey.
+type shareJSON struct {
+	ID    int    `json:"id"`
+	Value string `json:"value"`
+}
+
+// encodeHex encodes x as a base-16 string with a "0x" prefix.
+func encodeHex(x *big.Int) string {
+	if x == nil {
+		return ""
+	}
+
+	return "0x" + x.Text(16)
+}
+
+// decodeHex decodes a base-16 string with a "0x" prefix, as produced by
+// encodeHex(). The name of the field is used in error messages.
+func decodeHex(name string, s string) (*big.Int, error) {
+	if s == "" {
+		return nil, fmt.Errorf("Field %s is missing", name)
+	}
+
+	if len(s) < 3 || s[:2] != "0x" {
+		return nil, fmt.Errorf("Field %s must be a hex string with 0x prefix; got %q", name, s)
+	}
+
+	return parseHex(name, s)
+}
+
+// parseHex decodes a base-16 string, with or without a "0x" prefix. The name
+// of the value is used in error messages.
+func parseHex(name string, s string) (*big.Int, error) {
+	digits := s
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		digits = s[2:]
+	}
+	if digits == "" {
+		return nil, fmt.Errorf("Value of %s is missing", name)
+	}
+	// SetString would accept further prefixes and underscores otherwise
+	for _, c := range digits {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return nil, fmt.Errorf("Value of %s is not valid hex; got %q", name, s)
+		}
+	}
+
+	x, ok := new(big.Int).SetString(digits, 16)
+	if !ok {
+		return nil, fmt.Errorf("Value of %s is not valid hex; got %q", name, s)
+	}
+
+	return x, nil
+}
+
+// ParseSchnorrGroup decodes a Schnorr group from P, Q and G given as base-16
+// strings, each with or without a "0x" prefix, as is convenient for command
+// line tools.
+//
+// An error is returned if any value is not valid hex, or if the group is not
+// valid as per Validate().
+func ParseSchnorrGroup(pHex string, qHex string, gHex string) (SchnorrGroup, error) {
+	var group SchnorrGroup
+
+	p, err := parseHex("p", pHex)
+	if err != nil {
+		return group, err
+	}
+	q, err := parseHex("q", qHex)
+	if err != nil {
+		return group, err
+	}
+	g, err := parseHex("g", gHex)
+	if err != nil {
+		return group, err
+	}
+
+	group = SchnorrGroup{P: p, Q: q, G: g}
+	err = group.Validate()
+	if err != nil {
+		return SchnorrGroup{}, err
+	}
+
+	return group, nil
+}
+
+// ParsePublicKey decodes a public key from P, Q, G and Y given as base-16
+// strings, like ParseSchnorrGroup().
+//
+// An error is returned if any value is not valid hex, if the group is not
+// valid as per Validate(), or if y is 1 or not an element of the subgroup of
+// order q.
+func ParsePublicKey(pHex string, qHex string, gHex string, yHex string) (PublicKey, error) {
+	var pub PublicKey
+
+	group, err := ParseSchnorrGroup(pHex, qHex, gHex)
+	if err != nil {
+		return pub, err
+	}
+	y, err := parseHex("y", yHex)
+	if err != nil {
+		return pub, err
+	}
+
+	err = checkPublicY(group, y)
+	if err != nil {
+		return pub, err
+	}
+
+	pub.SchnorrGroup = group
+	pub.Y = y
+	pub.fields = newFieldCache()
+
+	return pub, nil
+}
+
+// checkPublicY checks that y is an element of the subgroup of order q of the
+// passed group, other than 1, which would correspond to the private key 0.
+func checkPublicY(group SchnorrGroup, y *big.Int) error {
+	if y == nil {
+		return fmt.Errorf("Public key is missing y")
+	}
+	if y.Sign() <= 0 || y.Cmp(group.P) >= 0 {
+		return fmt.Errorf("y must be in [1, p)")
+	}
+	if y.Cmp(big.NewInt(1)) == 0 {
+		return fmt.Errorf("y must not be 1")
+	}
+	if new(big.Int).Exp(y, group.Q, group.P).Cmp(big.NewInt(1)) != 0 {
+		return fmt.Errorf("y is not of order q")
+	}
+
+	return nil
+}
+
+func (sg SchnorrGroup) toJSON() schnorrGroupJSON {
+	return schnorrGroupJSON{
+		P: encodeHex(sg.P),
+		Q: encodeHex(sg.Q),
+		G: encodeHex(sg.G),
+	}
+}
+
+func (sg *SchnorrGroup) fromJSON(enc schnorrGroupJSON) error {
+	p, err := decodeHex("p", enc.P)
+	if err != nil {
+		return err
+	}
+	q, err := decodeHex("q", enc.Q)
+	if err != nil {
+		return err
+	}
+	g, err := decodeHex("g", enc.G)
+	if err != nil {
+		return err
+	}
+
+	sg.P = p
+	sg.Q = q
+	sg.G = g
+
+	return nil
+}
+
+// MarshalJSON encodes the group as a JSON object, with P, Q and G encoded as
+// base-16 strings with a "0x" prefix.
+func (sg SchnorrGroup) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sg.toJSON())
+}
+
+// UnmarshalJSON decodes a group which was encoded using MarshalJSON().
+//
+// An error is returned if any field is missing or not valid hex.
+func (sg *SchnorrGroup) UnmarshalJSON(data []byte) error {
+	var enc schnorrGroupJSON
+	err := json.Unmarshal(data, &enc)
+	if err != nil {
+		return err
+	}
+
+	return sg.fromJSON(enc)
+}
+
+// MarshalJSON encodes the public key as a JSON object, with P, Q, G and Y
+// encoded as base-16 strings with a "0x" prefix. The hash algorithm is
+// included by name, unless it is the default.
+func (pk PublicKey) MarshalJSON() ([]byte, error) {
+	enc := publicKeyJSON{
+		schnorrGroupJSON: pk.SchnorrGroup.toJSON(),
+		Y:                encodeHex(pk.Y),
+		Threshold:        pk.Threshold,
+	}
+	if pk.Hash != 0 {
+		enc.Hash = pk.Hash.String()
+	}
+	for _, vk := range pk.VerificationKeys {
+		enc.VerificationKeys = append(enc.VerificationKeys, encodeShare(secretshare.Share(vk)))
+	}
+	for _, commitment := range pk.Commitments {
+		enc.Commitments = append(enc.Commitments, encodeHex(commitment))
+	}
+
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON decodes a public key which was encoded using MarshalJSON().
+//
+// An error is returned if any field is missing or not valid hex.
+func (pk *PublicKey) UnmarshalJSON(data []byte) error {
+	var enc publicKeyJSON
+	err := json.Unmarshal(data, &enc)
+	if err != nil {
+		return err
+	}
+
+	var group SchnorrGroup
+	err = group.fromJSON(enc.schnorrGroupJSON)
+	if err != nil {
+		return err
+	}
+	y, err := decodeHex("y", enc.Y)
+	if err != nil {
+		return err
+	}
+	hash, err := decodeHash(enc.Hash)
+	if err != nil {
+		return err
+	}
+	var vks []VerificationKey
+	for _, encVK := range enc.VerificationKeys {
+		vk, err := decodeShare("verification key", encVK)
+		if err != nil {
+			return err
+		}
+		vks = append(vks, VerificationKey(vk))
+	}
+
+	var commitments []*big.Int
+	for _, encCommitment := range enc.Commitments {
+		commitment, err := decodeHex("commitment", encCommitment)
+		if err != nil {
+			return err
+		}
+		commitments = append(commitments, commitment)
+	}
+	if enc.Threshold < 0 {
+		return fmt.Errorf("Threshold must not be negative; got %d", enc.Threshold)
+	}
+
+	pk.SchnorrGroup = group
+	pk.Y = y
+	pk.Hash = hash
+	pk.VerificationKeys = vks
+	pk.Threshold = enc.Threshold
+	pk.Commitments = commitments
+	// Parameters changed, so any memoized fields must be recomputed
+	pk.fields = newFieldCache()
+
+	return nil
+}
+
+// MarshalJSON encodes the private key share as a JSON object, with its value
+// encoded as a base-16 string with a "0x" prefix.
+func (share PrivateKeyShare) MarshalJSON() ([]byte, error) {
+	return json.Marshal(encodeShare(secretshare.Share(share)))
+}
+
+// UnmarshalJSON decodes a private key share which was encoded using
+// MarshalJSON().
+//
+// An error is returned if the ID is not positive, or if the value is missing
+// or not valid hex.
+func (share *PrivateKeyShare) UnmarshalJSON(data []byte) error {
+	decoded, err := unmarshalShare("private key share", data)
+	if err != nil {
+		return err
+	}
+
+	*share = PrivateKeyShare(decoded)
+	return nil
+}
+
+// MarshalJSON encodes the decryption share as a JSON object, with its value
+// encoded as a base-16 string with a "0x" prefix.
+func (share DecryptionShare) MarshalJSON() ([]byte, error) {
+	return json.Marshal(encodeShare(secretshare.Share(share)))
+}
+
+// UnmarshalJSON decodes a decryption share which was encoded using
+// MarshalJSON().
+//
+// An error is returned if the ID is not positive, or if the value is missing
+// or not valid hex.
+func (share *DecryptionShare) UnmarshalJSON(data []byte) error {
+	decoded, err := unmarshalShare("decryption share", data)
+	if err != nil {
+		return err
+	}
+
+	*share = DecryptionShare(decoded)
+	return nil
+}
+
+// unmarshalShare decodes the JSON representation of a secret share. The name
+// of the share is used in error messages.
+func unmarshalShare(name string, data []byte) (secretshare.Share, error) {
+	var enc shareJSON
+	err := json.Unmarshal(data, &enc)
+	if err != nil {
+		return secretshare.Share{}, err
+	}
+
+	return decodeShare(name, enc)
+}
+
+// encodeShare converts a secret share into its JSON representation.
+func encodeShare(share secretshare.Share) shareJSON {
+	return shareJSON{
+		ID:    share.ID,
+		Value: encodeHex(share.Value),
+	}
+}
+
+// decodeShare converts the JSON representation of a secret share back into a
+// share. The name of the share is used in error messages.
+func decodeShare(name string, enc shareJSON) (secretshare.Share, error) {
+	var share secretshare.Share
+
+	if enc.ID <= 0 {
+		return share, fmt.Errorf("ID of %s must be positive; got %d", name, enc.ID)
+	}
+	value, err := decodeHex(name, enc.Value)
+	if err != nil {
+		return share, err
+	}
+
+	share.ID = enc.ID
+	share.Value = value
+
+	return share, nil
+}
+
+// GobEncode encodes the private key share for use with encoding/gob, as its
+// ID as a 4-byte big-endian unsigned integer, followed by the big-endian bytes
+// of its value.
+//
+// Unlike gob's default encoding of structs, this does not depend on the field
+// names of the underlying share type.
+func (share PrivateKeyShare) GobEncode() ([]byte, error) {
+	return encodeShareBinary("private key share", secretshare.Share(share))
+}
+
+// GobDecode decodes a private key share which was encoded using GobEncode().
+//
+// An error is returned if the data is too short, or if the ID is not
+// positive.
+func (share *PrivateKeyShare) GobDecode(data []byte) error {
+	decoded, err := decodeShareBinary("private key share", data)
+	if err != nil {
+		return err
+	}
+
+	*share = PrivateKeyShare(decoded)
+	return nil
+}
+
+// GobEncode encodes the decryption share for use with encoding/gob, in the
+// same way as PrivateKeyShare.GobEncode().
+func (share DecryptionShare) GobEncode() ([]byte, error) {
+	return encodeShareBinary("decryption share", secretshare.Share(share))
+}
+
+// GobDecode decodes a decryption share which was encoded using GobEncode().
+//
+// An error is returned if the data is too short, or if the ID is not
+// positive.
+func (share *DecryptionShare) GobDecode(data []byte) error {
+	decoded, err := decodeShareBinary("decryption share", data)
+	if err != nil {
+		return err
+	}
+
+	*share = DecryptionShare(decoded)
+	return nil
+}
+
+// encodeShareBinary encodes a secret share as its ID as a 4-byte big-endian
+// unsigned integer, followed by the big-endian bytes of its value. The name
+// of the share is used in error messages.
+func encodeShareBinary(name string, share secretshare.Share) ([]byte, error) {
+	if share.ID <= 0 || uint64(share.ID) > math.MaxUint32 {
+		return nil, fmt.Errorf("ID of %s must be in [1, 2^32); got %d", name, share.ID)
+	}
+	if share.Value == nil || share.Value.Sign() < 0 {
+		return nil, fmt.Errorf("Value of %s must be non-negative; got %v", name, share.Value)
+	}
+
+	value := share.Value.Bytes()
+	out := make([]byte, lengthPrefixSize, lengthPrefixSize+len(value))
+	binary.BigEndian.PutUint32(out, uint32(share.ID))
+
+	return append(out, value...), nil
+}
+
+// decodeShareBinary decodes a secret share which was encoded using
+// encodeShareBinary(). The name of the share is used in error messages.
+func decodeShareBinary(name string, data []byte) (secretshare.Share, error) {
+	var share secretshare.Share
+
+	if len(data) < lengthPrefixSize {
+		return share, fmt.Errorf("Encoding of %s must be at least %d bytes; got %d", name, lengthPrefixSize, len(data))
+	}
+
+	// IDs beyond the range of int wrap around to negative values on
+	// 32-bit platforms
+	id := int(binary.BigEndian.Uint32(data))
+	if id <= 0 {
+		return share, fmt.Errorf("ID of %s must be positive; got %d", name, id)
+	}
+
+	share.ID = id
+	share.Value = new(big.Int).SetBytes(data[lengthPrefixSize:])
+
+	return share, nil
+}
+
+// decodeHash returns the supported hash algorithm with the passed name. The
+// empty name maps to the zero value, which selects the default hash
+// algorithm.
+func decodeHash(name string) (crypto.Hash, error) {
+	if name == "" {
+		return 0, nil
+	}
+
+	for _, hash := range supportedHashes {
+		if hash.String() == name {
+			return hash, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unsupported hash algorithm %q", name)
+}
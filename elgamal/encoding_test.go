@@ -0,0 +1,620 @@
+package elgamal
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha512"
+	"encoding/gob"
+	"encoding/json"
+	"github.com/lavode/secret-sharing/secretshare"
+	"math/big"
+	"testing"
+)
+
+func TestCiphertextMarshalBinary(t *testing.T) {
+	// Handcrafted ciphertext from TestDec
+	ctxt := Ciphertext{
+		R: big.NewInt(3),
+		C: []byte{0xBA, 0x1E, 0x37, 0x94, 0xBC, 0x7E, 0xD5, 0xD4, 0xC9, 0x0, 0x6B, 0x9F, 0xEF, 0x89, 0xD8, 0x83, 0x41, 0x5B, 0x5A, 0xDB, 0xD6, 0xA8, 0x40, 0x30, 0xCB, 0x1F, 0x35, 0xE6, 0xA6, 0xC0, 0x26, 0xE6, 0x5C, 0x60, 0xFB, 0x99, 0xF5, 0x62, 0xF7, 0xEB, 0x9F, 0x77, 0xF3, 0xDE, 0xC5, 0x0, 0x14, 0x73, 0x44, 0x1D, 0x2C, 0x55, 0x86, 0xB5, 0x4D, 0x9B, 0x99, 0x9C, 0xF4, 0xBD, 0x79, 0xE, 0x4C, 0x56},
+	}
+
+	data, err := ctxt.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary returned error: %v", err)
+	}
+	// 4 bytes length prefix, 1 byte R, 64 bytes C
+	if len(data) != 69 {
+		t.Errorf("Expected encoding of 69 bytes; got %d", len(data))
+	}
+
+	var decoded Ciphertext
+	err = decoded.UnmarshalBinary(data)
+	if err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+	if decoded.R.Cmp(ctxt.R) != 0 || !bytes.Equal(decoded.C, ctxt.C) {
+		t.Errorf("Expected decoded ciphertext %+v; got %+v", ctxt, decoded)
+	}
+
+	// C one byte too short
+	err = decoded.UnmarshalBinary(data[:len(data)-1])
+	if err == nil {
+		t.Errorf("Expected error if C is too short; got none")
+	}
+
+	// Trailing data
+	err = decoded.UnmarshalBinary(append(data, 0x00))
+	if err == nil {
+		t.Errorf("Expected error if there is trailing data; got none")
+	}
+
+	// C of a block size other than SHA512's
+	short := append(data[:5:5], ctxt.C[:32]...)
+	err = decoded.UnmarshalBinary(short)
+	if err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+	if !bytes.Equal(decoded.C, ctxt.C[:32]) {
+		t.Errorf("Expected decoded C %x; got %x", ctxt.C[:32], decoded.C)
+	}
+	_, err = Ciphertext{R: ctxt.R, C: ctxt.C[:40]}.MarshalBinary()
+	if err == nil {
+		t.Errorf("Expected error marshalling C which is not one block; got none")
+	}
+
+	// Labeled ciphertext
+	ctxt.Label = []byte("tenant-1")
+	labeled, err := ctxt.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary returned error: %v", err)
+	}
+	if len(labeled) != len(data)+4+len(ctxt.Label)+4 {
+		t.Errorf("Expected labeled encoding of %d bytes; got %d", len(data)+4+len(ctxt.Label)+4, len(labeled))
+	}
+	if labeled[0]&0x80 == 0 {
+		t.Errorf("Expected length prefix of R to be flagged in labeled encoding")
+	}
+	err = decoded.UnmarshalBinary(labeled)
+	if err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+	if !bytes.Equal(decoded.Label, ctxt.Label) {
+		t.Errorf("Expected decoded label %q; got %q", ctxt.Label, decoded.Label)
+	}
+
+	// Flagged, but with neither label nor tag
+	flagged := append([]byte{0x80, 0x00, 0x00, 0x01, 0x03}, make([]byte, 8)...)
+	err = decoded.UnmarshalBinary(append(flagged, ctxt.C...))
+	if err == nil {
+		t.Errorf("Expected error if label and tag are empty; got none")
+	}
+
+	// Tagged ciphertext, without label
+	ctxt.Label = nil
+	ctxt.Tag = bytes.Repeat([]byte{0xAA}, 64)
+	tagged, err := ctxt.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary returned error: %v", err)
+	}
+	if len(tagged) != len(data)+4+4+len(ctxt.Tag) {
+		t.Errorf("Expected tagged encoding of %d bytes; got %d", len(data)+4+4+len(ctxt.Tag), len(tagged))
+	}
+	err = decoded.UnmarshalBinary(tagged)
+	if err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+	if !decoded.Equal(ctxt) {
+		t.Errorf("Expected decoded ciphertext %+v; got %+v", ctxt, decoded)
+	}
+
+	// Tagged ciphertext, with label
+	ctxt.Label = []byte("tenant-1")
+	tagged, err = ctxt.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary returned error: %v", err)
+	}
+	err = decoded.UnmarshalBinary(tagged)
+	if err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+	if !decoded.Equal(ctxt) {
+		t.Errorf("Expected decoded ciphertext %+v; got %+v", ctxt, decoded)
+	}
+
+	// Label overrunning the buffer
+	err = decoded.UnmarshalBinary(labeled[:5+4+len(ctxt.Label)-1])
+	if err == nil {
+		t.Errorf("Expected error if label overruns buffer; got none")
+	}
+
+	// Length prefix of R overrunning the buffer
+	overrun := []byte{0x00, 0x00, 0x01, 0x00, 0x03}
+	err = decoded.UnmarshalBinary(overrun)
+	if err == nil {
+		t.Errorf("Expected error if length prefix overruns buffer; got none")
+	}
+
+	// Truncated length prefix
+	err = decoded.UnmarshalBinary([]byte{0x00, 0x00})
+	if err == nil {
+		t.Errorf("Expected error if length prefix is truncated; got none")
+	}
+
+	// Empty C
+	err = decoded.UnmarshalBinary([]byte{0x00, 0x00, 0x00, 0x01, 0x03})
+	if err == nil {
+		t.Errorf("Expected error if C is empty; got none")
+	}
+}
+
+func TestCiphertextSize(t *testing.T) {
+	pub, _, _, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	size := pub.CiphertextSize()
+	if size != 4+64+64 {
+		t.Errorf("Expected ciphertext size of %d bytes; got %d", 4+64+64, size)
+	}
+
+	for i := 0; i < 16; i++ {
+		ctxt, err := Enc(pub, make([]byte, 64))
+		if err != nil {
+			t.Fatalf("Enc returned error: %v", err)
+		}
+		// R with leading zero bytes is padded to the length of p
+		if i == 0 {
+			ctxt.R = big.NewInt(3)
+		}
+
+		data, err := ctxt.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary returned error: %v", err)
+		}
+		if len(data) != size {
+			t.Errorf("Expected encoding of %d bytes; got %d", size, len(data))
+		}
+
+		var decoded Ciphertext
+		err = decoded.UnmarshalBinary(data)
+		if err != nil {
+			t.Fatalf("UnmarshalBinary returned error: %v", err)
+		}
+		if !decoded.Equal(ctxt) {
+			t.Errorf("Expected decoded ciphertext %+v; got %+v", ctxt, decoded)
+		}
+		reencoded, err := decoded.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary returned error: %v", err)
+		}
+		if !bytes.Equal(reencoded, data) {
+			t.Errorf("Expected re-encoding %x; got %x", data, reencoded)
+		}
+	}
+
+	if (PublicKey{}).CiphertextSize() != 0 {
+		t.Errorf("Expected ciphertext size of 0 for empty public key")
+	}
+}
+
+func TestPublicKeyJSON(t *testing.T) {
+	pub, _, _, err := KeyGen(20, 10, 3, 5)
+	if err != nil {
+		t.Fatalf("Error in KeyGen: %v", err)
+	}
+
+	data, err := json.Marshal(pub)
+	if err != nil {
+		t.Fatalf("Error marshalling public key: %v", err)
+	}
+
+	var decoded PublicKey
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatalf("Error unmarshalling public key: %v", err)
+	}
+
+	if decoded.P.Cmp(pub.P) != 0 || decoded.Q.Cmp(pub.Q) != 0 || decoded.G.Cmp(pub.G) != 0 || decoded.Y.Cmp(pub.Y) != 0 {
+		t.Errorf("Expected decoded public key %+v; got %+v", pub, decoded)
+	}
+
+	if decoded.Threshold != pub.Threshold {
+		t.Errorf("Expected threshold %d; got %d", pub.Threshold, decoded.Threshold)
+	}
+
+	if len(decoded.Commitments) != len(pub.Commitments) {
+		t.Fatalf("Expected %d commitments; got %d", len(pub.Commitments), len(decoded.Commitments))
+	}
+	for i, commitment := range pub.Commitments {
+		if decoded.Commitments[i].Cmp(commitment) != 0 {
+			t.Errorf("Expected commitment %d; got %d", commitment, decoded.Commitments[i])
+		}
+	}
+
+	if len(decoded.VerificationKeys) != len(pub.VerificationKeys) {
+		t.Fatalf("Expected %d verification keys; got %d", len(pub.VerificationKeys), len(decoded.VerificationKeys))
+	}
+	for i, vk := range pub.VerificationKeys {
+		got := decoded.VerificationKeys[i]
+		if got.ID != vk.ID || got.Value.Cmp(vk.Value) != 0 {
+			t.Errorf("Expected verification key %+v; got %+v", vk, got)
+		}
+	}
+}
+
+func TestPublicKeyJSONEncoding(t *testing.T) {
+	pub := PublicKey{
+		SchnorrGroup: SchnorrGroup{
+			P: big.NewInt(23),
+			Q: big.NewInt(11),
+			G: big.NewInt(4),
+		},
+		Y: big.NewInt(16),
+	}
+
+	data, err := json.Marshal(pub)
+	if err != nil {
+		t.Fatalf("Error marshalling public key: %v", err)
+	}
+	expected := `{"p":"0x17","q":"0xb","g":"0x4","y":"0x10"}`
+	if string(data) != expected {
+		t.Errorf("Expected JSON %s; got %s", expected, data)
+	}
+
+	pub.Hash = crypto.SHA256
+	data, err = json.Marshal(pub)
+	if err != nil {
+		t.Fatalf("Error marshalling public key: %v", err)
+	}
+	expected = `{"p":"0x17","q":"0xb","g":"0x4","y":"0x10","hash":"SHA-256"}`
+	if string(data) != expected {
+		t.Errorf("Expected JSON %s; got %s", expected, data)
+	}
+
+	var decoded PublicKey
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatalf("Error unmarshalling public key: %v", err)
+	}
+	if decoded.Hash != crypto.SHA256 {
+		t.Errorf("Expected hash %v; got %v", crypto.SHA256, decoded.Hash)
+	}
+
+	invalid := []string{
+		// Missing field
+		`{"p":"0x17","q":"0xb","g":"0x4"}`,
+		`{"q":"0xb","g":"0x4","y":"0x10"}`,
+		// Malformed hex
+		`{"p":"0x1z","q":"0xb","g":"0x4","y":"0x10"}`,
+		// Missing prefix
+		`{"p":"17","q":"0xb","g":"0x4","y":"0x10"}`,
+		// Unsupported hash
+		`{"p":"0x17","q":"0xb","g":"0x4","y":"0x10","hash":"MD5"}`,
+		// Invalid verification keys
+		`{"p":"0x17","q":"0xb","g":"0x4","y":"0x10","verification_keys":[{"id":0,"value":"0x3"}]}`,
+		`{"p":"0x17","q":"0xb","g":"0x4","y":"0x10","verification_keys":[{"id":1}]}`,
+		// Malformed commitment
+		`{"p":"0x17","q":"0xb","g":"0x4","y":"0x10","commitments":["0x1z"]}`,
+		// Signed values
+		`{"p":"0x17","q":"0xb","g":"0x4","y":"0x10","commitments":["0x-5"]}`,
+		`{"p":"0x17","q":"0xb","g":"0x4","y":"0x10","commitments":["0x+5"]}`,
+		// Negative threshold
+		`{"p":"0x17","q":"0xb","g":"0x4","y":"0x10","threshold":-1}`,
+	}
+	for _, input := range invalid {
+		err = json.Unmarshal([]byte(input), &decoded)
+		if err == nil {
+			t.Errorf("Expected error when decoding %s; got none", input)
+		}
+	}
+}
+
+func TestSchnorrGroupJSON(t *testing.T) {
+	group := SchnorrGroup{
+		P: big.NewInt(23),
+		Q: big.NewInt(11),
+		G: big.NewInt(4),
+	}
+
+	data, err := json.Marshal(group)
+	if err != nil {
+		t.Fatalf("Error marshalling group: %v", err)
+	}
+
+	var decoded SchnorrGroup
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatalf("Error unmarshalling group: %v", err)
+	}
+	if decoded.P.Cmp(group.P) != 0 || decoded.Q.Cmp(group.Q) != 0 || decoded.G.Cmp(group.G) != 0 {
+		t.Errorf("Expected decoded group %+v; got %+v", group, decoded)
+	}
+}
+
+func TestShareJSON(t *testing.T) {
+	// Shares from TestDec
+	keyShares := []PrivateKeyShare{
+		PrivateKeyShare(secretshare.Share{ID: 1, Value: big.NewInt(4)}),
+		PrivateKeyShare(secretshare.Share{ID: 3, Value: big.NewInt(14)}),
+		PrivateKeyShare(secretshare.Share{ID: 4, Value: big.NewInt(22)}),
+	}
+	decShares := []DecryptionShare{
+		DecryptionShare(secretshare.Share{ID: 1, Value: big.NewInt(12)}),
+		DecryptionShare(secretshare.Share{ID: 3, Value: big.NewInt(4)}),
+		DecryptionShare(secretshare.Share{ID: 4, Value: big.NewInt(1)}),
+	}
+
+	data, err := json.Marshal(keyShares[1])
+	if err != nil {
+		t.Fatalf("Error marshalling private key share: %v", err)
+	}
+	expected := `{"id":3,"value":"0xe"}`
+	if string(data) != expected {
+		t.Errorf("Expected JSON %s; got %s", expected, data)
+	}
+
+	data, err = json.Marshal(keyShares)
+	if err != nil {
+		t.Fatalf("Error marshalling private key shares: %v", err)
+	}
+	var decodedKeyShares []PrivateKeyShare
+	err = json.Unmarshal(data, &decodedKeyShares)
+	if err != nil {
+		t.Fatalf("Error unmarshalling private key shares: %v", err)
+	}
+	if len(decodedKeyShares) != len(keyShares) {
+		t.Fatalf("Expected %d private key shares; got %d", len(keyShares), len(decodedKeyShares))
+	}
+	for i, share := range keyShares {
+		got := decodedKeyShares[i]
+		if got.ID != share.ID || got.Value.Cmp(share.Value) != 0 {
+			t.Errorf("Expected private key share %+v; got %+v", share, got)
+		}
+	}
+
+	data, err = json.Marshal(decShares)
+	if err != nil {
+		t.Fatalf("Error marshalling decryption shares: %v", err)
+	}
+	var decodedDecShares []DecryptionShare
+	err = json.Unmarshal(data, &decodedDecShares)
+	if err != nil {
+		t.Fatalf("Error unmarshalling decryption shares: %v", err)
+	}
+	if len(decodedDecShares) != len(decShares) {
+		t.Fatalf("Expected %d decryption shares; got %d", len(decShares), len(decodedDecShares))
+	}
+	for i, share := range decShares {
+		got := decodedDecShares[i]
+		if got.ID != share.ID || got.Value.Cmp(share.Value) != 0 {
+			t.Errorf("Expected decryption share %+v; got %+v", share, got)
+		}
+	}
+
+	invalid := []string{
+		`{"id":0,"value":"0x4"}`,
+		`{"id":-1,"value":"0x4"}`,
+		`{"id":1}`,
+		`{"id":1,"value":"4"}`,
+	}
+	for _, input := range invalid {
+		var keyShare PrivateKeyShare
+		err = json.Unmarshal([]byte(input), &keyShare)
+		if err == nil {
+			t.Errorf("Expected error when decoding private key share %s; got none", input)
+		}
+
+		var decShare DecryptionShare
+		err = json.Unmarshal([]byte(input), &decShare)
+		if err == nil {
+			t.Errorf("Expected error when decoding decryption share %s; got none", input)
+		}
+	}
+}
+
+func TestShareGob(t *testing.T) {
+	// Shares from TestDec
+	keyShares := []PrivateKeyShare{
+		PrivateKeyShare(secretshare.Share{ID: 1, Value: big.NewInt(4)}),
+		PrivateKeyShare(secretshare.Share{ID: 3, Value: big.NewInt(14)}),
+		PrivateKeyShare(secretshare.Share{ID: 4, Value: big.NewInt(22)}),
+	}
+	decShares := []DecryptionShare{
+		DecryptionShare(secretshare.Share{ID: 1, Value: big.NewInt(12)}),
+		DecryptionShare(secretshare.Share{ID: 3, Value: big.NewInt(4)}),
+		DecryptionShare(secretshare.Share{ID: 4, Value: big.NewInt(0)}),
+	}
+	ctxts := []Ciphertext{
+		{R: big.NewInt(3), C: bytes.Repeat([]byte{0x01}, 64)},
+		{R: big.NewInt(3), C: bytes.Repeat([]byte{0x02}, 64), Label: []byte("label"), Tag: []byte("tag")},
+	}
+
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	for _, v := range []interface{}{keyShares, decShares, ctxts} {
+		err := enc.Encode(v)
+		if err != nil {
+			t.Fatalf("Error gob-encoding %T: %v", v, err)
+		}
+	}
+
+	var decodedKeyShares []PrivateKeyShare
+	var decodedDecShares []DecryptionShare
+	var decodedCtxts []Ciphertext
+	dec := gob.NewDecoder(&buf)
+	for _, v := range []interface{}{&decodedKeyShares, &decodedDecShares, &decodedCtxts} {
+		err := dec.Decode(v)
+		if err != nil {
+			t.Fatalf("Error gob-decoding %T: %v", v, err)
+		}
+	}
+
+	if len(decodedKeyShares) != len(keyShares) {
+		t.Fatalf("Expected %d private key shares; got %d", len(keyShares), len(decodedKeyShares))
+	}
+	for i, share := range keyShares {
+		got := decodedKeyShares[i]
+		if got.ID != share.ID || got.Value.Cmp(share.Value) != 0 {
+			t.Errorf("Expected private key share %+v; got %+v", share, got)
+		}
+	}
+
+	if len(decodedDecShares) != len(decShares) {
+		t.Fatalf("Expected %d decryption shares; got %d", len(decShares), len(decodedDecShares))
+	}
+	for i, share := range decShares {
+		got := decodedDecShares[i]
+		if got.ID != share.ID || got.Value.Cmp(share.Value) != 0 {
+			t.Errorf("Expected decryption share %+v; got %+v", share, got)
+		}
+	}
+
+	if len(decodedCtxts) != len(ctxts) {
+		t.Fatalf("Expected %d ciphertexts; got %d", len(ctxts), len(decodedCtxts))
+	}
+	for i, ctxt := range ctxts {
+		if !decodedCtxts[i].Equal(ctxt) {
+			t.Errorf("Expected ciphertext %+v; got %+v", ctxt, decodedCtxts[i])
+		}
+	}
+
+	var share DecryptionShare
+	for _, input := range [][]byte{{}, {0x00, 0x00, 0x01}, {0x00, 0x00, 0x00, 0x00, 0x04}} {
+		err := share.GobDecode(input)
+		if err == nil {
+			t.Errorf("Expected error when decoding decryption share %x; got none", input)
+		}
+	}
+
+	_, err := DecryptionShare{ID: 1}.GobEncode()
+	if err == nil {
+		t.Errorf("Expected error when encoding share without value; got none")
+	}
+}
+
+func TestParseSchnorrGroup(t *testing.T) {
+	pub, _, _, err := KeyGen(512, 128, 2, 3)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	// With and without prefix
+	for _, prefix := range []string{"", "0x", "0X"} {
+		group, err := ParseSchnorrGroup(prefix+pub.P.Text(16), prefix+pub.Q.Text(16), prefix+pub.G.Text(16))
+		if err != nil {
+			t.Fatalf("ParseSchnorrGroup returned error: %v", err)
+		}
+		if group.P.Cmp(pub.P) != 0 || group.Q.Cmp(pub.Q) != 0 || group.G.Cmp(pub.G) != 0 {
+			t.Errorf("Expected group %+v; got %+v", pub.SchnorrGroup, group)
+		}
+	}
+
+	parsed, err := ParsePublicKey(encodeHex(pub.P), encodeHex(pub.Q), encodeHex(pub.G), encodeHex(pub.Y))
+	if err != nil {
+		t.Fatalf("ParsePublicKey returned error: %v", err)
+	}
+	if parsed.Y.Cmp(pub.Y) != 0 {
+		t.Errorf("Expected y = %d; got %d", pub.Y, parsed.Y)
+	}
+
+	invalid := []struct {
+		name string
+		p    string
+		q    string
+		g    string
+	}{
+		{"malformed p", "0xzz", "b", "4"},
+		{"empty q", "17", "", "4"},
+		{"bare prefix", "17", "0x", "4"},
+		{"underscore", "1_7", "b", "4"},
+		{"sign", "17", "-b", "4"},
+		{"g of wrong order", "17", "b", "5"},
+		{"q not dividing p-1", "17", "7", "4"},
+	}
+	for _, test := range invalid {
+		_, err := ParseSchnorrGroup(test.p, test.q, test.g)
+		if err == nil {
+			t.Errorf("%s: Expected error; got none", test.name)
+		}
+	}
+
+	// y = 5 is not of order 11 in (Z/23Z)*
+	_, err = ParsePublicKey("17", "b", "4", "5")
+	if err == nil {
+		t.Errorf("Expected error with y not of order q; got none")
+	}
+	_, err = ParsePublicKey("17", "b", "4", "10")
+	if err != nil {
+		t.Errorf("ParsePublicKey returned error: %v", err)
+	}
+	_, err = ParsePublicKey("17", "b", "4", "1")
+	if err == nil {
+		t.Errorf("Expected error with y = 1; got none")
+	}
+}
+
+func TestFixedBytes(t *testing.T) {
+	cases := []struct {
+		x        *big.Int
+		size     int
+		expected []byte
+	}{
+		{big.NewInt(5), 4, []byte{0x00, 0x00, 0x00, 0x05}},
+		{big.NewInt(0x0102), 2, []byte{0x01, 0x02}},
+		{big.NewInt(0), 2, []byte{0x00, 0x00}},
+		// Too large for size, so minimal encoding
+		{big.NewInt(0x010203), 2, []byte{0x01, 0x02, 0x03}},
+	}
+
+	for _, c := range cases {
+		got := fixedBytes(c.x, c.size)
+		if !bytes.Equal(got, c.expected) {
+			t.Errorf("Expected fixedBytes(%d, %d) = %x; got %x", c.x, c.size, c.expected, got)
+		}
+	}
+}
+
+func TestHashedXORPadding(t *testing.T) {
+	// A small y^r with a modulus of 3 bytes is hashed as 3 bytes
+	p := big.NewInt(65537)
+	z := big.NewInt(5)
+
+	key := sha512.Sum512([]byte{0x00, 0x00, 0x05})
+	got := hashedXOR(crypto.SHA512, z, p, nil, make([]byte, 64))
+	if !bytes.Equal(got, key[:]) {
+		t.Errorf("Expected key stream H(00 00 05) = %x; got %x", key, got)
+	}
+
+	// Encryption and recovery still round-trip
+	msg, err := PadMessage([]byte("Hello world"))
+	if err != nil {
+		t.Fatalf("PadMessage returned error: %v", err)
+	}
+	pub, priv, shares, err := KeyGen(512, 128, 2, 3)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+	decShares, err := DecAll(pub, shares[:2], ctxt)
+	if err != nil {
+		t.Fatalf("DecAll returned error: %v", err)
+	}
+	recovered, err := Recover(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recovered)
+	}
+	whole, err := DecryptWhole(pub, priv, ctxt)
+	if err != nil {
+		t.Fatalf("DecryptWhole returned error: %v", err)
+	}
+	if !bytes.Equal(whole, msg) {
+		t.Errorf("Expected decrypted message %x; got %x", msg, whole)
+	}
+}
@@ -0,0 +1,88 @@
+package elgamal
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotBlockAligned is returned by EncExact() if a message is not exactly
+// one block in size.
+var ErrNotBlockAligned = errors.New("Message is not block aligned")
+
+// Errors which may be returned during key generation, encryption and
+// decryption, wrapped with further details. Use errors.Is() to check for
+// them.
+var (
+	// ErrMessageLength is returned if a message is not of the length
+	// required by the public key.
+	ErrMessageLength = errors.New("Message has invalid length")
+	// ErrInvalidGroup is returned if the parameters of a Schnorr group
+	// are missing or unsuitable, e.g. as p or q is not prime.
+	ErrInvalidGroup = errors.New("Invalid group")
+	// ErrThresholdNotMet is returned if fewer shares are passed than
+	// required to reach the threshold.
+	ErrThresholdNotMet = errors.New("Threshold not met")
+	// ErrDuplicateShare is returned if multiple shares with the same ID
+	// are passed.
+	ErrDuplicateShare = errors.New("Duplicate share")
+	// ErrInvalidCiphertext is returned if the component R of a
+	// ciphertext is not an element of the order-q subgroup.
+	ErrInvalidCiphertext = errors.New("Invalid ciphertext")
+	// ErrDegenerateSecret is returned if the shared secret y^r = R^x,
+	// from which the key stream is derived, is 0 or 1. This indicates a
+	// broken group, key or ciphertext.
+	ErrDegenerateSecret = errors.New("Degenerate shared secret")
+	// ErrPrimeSearchExhausted is returned if generation of a Schnorr
+	// group gives up on finding a prime p, as no candidate was prime
+	// within the iteration limit.
+	ErrPrimeSearchExhausted = errors.New("Prime search exhausted")
+	// ErrInconsistentDealerOutput is returned by VerifyDealerOutput() if
+	// verification keys, commitments and public key do not agree.
+	ErrInconsistentDealerOutput = errors.New("Inconsistent dealer output")
+)
+
+// Errors returned by SchnorrGroup.Validate(), one per failed condition. Each
+// of them also matches ErrInvalidGroup when checked with errors.Is().
+var (
+	// ErrGroupIncomplete is returned if any of P, Q or G is missing.
+	ErrGroupIncomplete error = &groupError{"Group is missing parameters"}
+	// ErrPNotPrime is returned if P is not a probable prime.
+	ErrPNotPrime error = &groupError{"p is not prime"}
+	// ErrQNotPrime is returned if Q is not a probable prime.
+	ErrQNotPrime error = &groupError{"q is not prime"}
+	// ErrQNotDivisor is returned if q does not divide p-1.
+	ErrQNotDivisor error = &groupError{"q does not divide p-1"}
+	// ErrGeneratorOutOfRange is returned if g is not in the range [1, p).
+	ErrGeneratorOutOfRange error = &groupError{"g is not in the range [1, p)"}
+	// ErrTrivialGenerator is returned if g is 1.
+	ErrTrivialGenerator error = &groupError{"g must not be 1"}
+	// ErrGeneratorOrder is returned if g is not of order q.
+	ErrGeneratorOrder error = &groupError{"g is not of order q"}
+)
+
+// groupError is the type of the errors returned by SchnorrGroup.Validate().
+// Besides itself, each matches ErrInvalidGroup, such that callers need not
+// distinguish the failed condition.
+type groupError struct {
+	msg string
+}
+
+func (e *groupError) Error() string {
+	return e.msg
+}
+
+// Is reports whether target is ErrInvalidGroup, for use by errors.Is().
+func (e *groupError) Is(target error) bool {
+	return target == ErrInvalidGroup
+}
+
+// InconsistentShareError is returned by RecoverVerified() if some decryption
+// shares disagree with the majority of threshold-sized subsets.
+type InconsistentShareError struct {
+	// IDs of the decryption shares which appear to be inconsistent
+	IDs []int
+}
+
+func (e *InconsistentShareError) Error() string {
+	return fmt.Sprintf("Decryption shares %v are inconsistent with the majority", e.IDs)
+}
@@ -0,0 +1,213 @@
+package elgamal
+
+import (
+	"errors"
+	"github.com/lavode/secret-sharing/secretshare"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestErrNotBlockAligned(t *testing.T) {
+	pub := PublicKey{
+		SchnorrGroup: SchnorrGroup{
+			P: big.NewInt(23),
+			Q: big.NewInt(11),
+			G: big.NewInt(4),
+		},
+		Y: big.NewInt(16),
+	}
+
+	_, err := EncExact(pub, make([]byte, 64))
+	if err != nil {
+		t.Errorf("EncExact returned error: %v", err)
+	}
+
+	for _, size := range []int{0, 11, 63, 65} {
+		_, err = EncExact(pub, make([]byte, size))
+		if !errors.Is(err, ErrNotBlockAligned) {
+			t.Errorf("Expected ErrNotBlockAligned for message of %d bytes; got %v", size, err)
+		}
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	pub := PublicKey{
+		SchnorrGroup: SchnorrGroup{
+			P: big.NewInt(23),
+			Q: big.NewInt(11),
+			G: big.NewInt(4),
+		},
+		Y:         big.NewInt(16),
+		Threshold: 3,
+	}
+
+	_, err := Enc(pub, make([]byte, 65))
+	if !errors.Is(err, ErrMessageLength) {
+		t.Errorf("Expected ErrMessageLength for message of 65 bytes; got %v", err)
+	}
+
+	_, err = EncBatch(pub, [][]byte{make([]byte, 64), make([]byte, 63)})
+	if !errors.Is(err, ErrMessageLength) {
+		t.Errorf("Expected ErrMessageLength for batch with message of 63 bytes; got %v", err)
+	}
+
+	// Shares from TestDec
+	ctxt := Ciphertext{R: big.NewInt(3), C: make([]byte, 64)}
+	decShares := []DecryptionShare{
+		{ID: 1, Value: big.NewInt(12)},
+		{ID: 3, Value: big.NewInt(4)},
+		{ID: 4, Value: big.NewInt(1)},
+	}
+
+	_, err = Recover(pub, decShares[:2], ctxt)
+	if !errors.Is(err, ErrThresholdNotMet) {
+		t.Errorf("Expected ErrThresholdNotMet with 2 of 3 shares; got %v", err)
+	}
+
+	_, err = RecoverVerified(pub, []DecryptionShare{decShares[0], decShares[1], decShares[1]}, ctxt)
+	if !errors.Is(err, ErrDuplicateShare) {
+		t.Errorf("Expected ErrDuplicateShare; got %v", err)
+	}
+
+	invalid := pub
+	invalid.SchnorrGroup.Q = big.NewInt(10)
+	_, err = Recover(invalid, decShares, ctxt)
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup with q = 10; got %v", err)
+	}
+
+	_, err = Dec(PublicKey{}, PrivateKeyShare{ID: 1, Value: big.NewInt(4)}, ctxt)
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup with missing group; got %v", err)
+	}
+
+	_, _, _, err = KeyGen(20, 20, 2, 3)
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup with qBits = pBits; got %v", err)
+	}
+}
+
+func TestInconsistentGroup(t *testing.T) {
+	// q = 7 does not divide p-1 = 22
+	pub := PublicKey{
+		SchnorrGroup: SchnorrGroup{
+			P: big.NewInt(23),
+			Q: big.NewInt(7),
+			G: big.NewInt(4),
+		},
+		Y: big.NewInt(16),
+	}
+	ctxt := Ciphertext{R: big.NewInt(3), C: make([]byte, 64)}
+
+	_, err := Enc(pub, make([]byte, 64))
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup from Enc; got %v", err)
+	}
+
+	_, err = EncWithRandomness(pub, make([]byte, 64), big.NewInt(4))
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup from EncWithRandomness; got %v", err)
+	}
+
+	_, err = EncBatch(pub, [][]byte{make([]byte, 64)})
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup from EncBatch; got %v", err)
+	}
+
+	_, err = Dec(pub, PrivateKeyShare{ID: 1, Value: big.NewInt(4)}, ctxt)
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup from Dec; got %v", err)
+	}
+
+	_, err = Recover(pub, []DecryptionShare{{ID: 1, Value: big.NewInt(12)}}, ctxt)
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup from Recover; got %v", err)
+	}
+
+	// Memoized check of a consistent group
+	pub.Q = big.NewInt(11)
+	pub.fields = newFieldCache()
+	for i := 0; i < 2; i++ {
+		err = pub.checkGroup()
+		if err != nil {
+			t.Errorf("Expected consistent group to pass check; got %v", err)
+		}
+	}
+	if !pub.fields.divides {
+		t.Errorf("Expected check to be memoized")
+	}
+}
+
+func TestNilFields(t *testing.T) {
+	msg := make([]byte, 64)
+	keyShare := PrivateKeyShare(secretshare.Share{ID: 1, Value: big.NewInt(4)})
+	decShares := []DecryptionShare{DecryptionShare(secretshare.Share{ID: 1, Value: big.NewInt(4)})}
+	ctxt := Ciphertext{R: big.NewInt(3), C: make([]byte, 64)}
+
+	// Zero-value public key
+	var zero PublicKey
+	_, err := Enc(zero, msg)
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup from Enc with zero-value key; got %v", err)
+	}
+	_, err = Dec(zero, keyShare, ctxt)
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup from Dec with zero-value key; got %v", err)
+	}
+	_, err = Recover(zero, decShares, ctxt)
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup from Recover with zero-value key; got %v", err)
+	}
+
+	group := SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(11), G: big.NewInt(4)}
+	for _, name := range []string{"P", "Q", "G", "Y"} {
+		pub := PublicKey{SchnorrGroup: group, Y: big.NewInt(16)}
+		switch name {
+		case "P":
+			pub.P = nil
+		case "Q":
+			pub.Q = nil
+		case "G":
+			pub.G = nil
+		case "Y":
+			pub.Y = nil
+		}
+
+		expected := "public key " + name + " is nil"
+		_, err = Enc(pub, msg)
+		if err == nil || !strings.Contains(strings.ToLower(err.Error()), strings.ToLower(expected)) {
+			t.Errorf("Expected error %q from Enc; got %v", expected, err)
+		}
+		_, err = Dec(pub, keyShare, ctxt)
+		if err == nil || !strings.Contains(strings.ToLower(err.Error()), strings.ToLower(expected)) {
+			t.Errorf("Expected error %q from Dec; got %v", expected, err)
+		}
+		_, err = Recover(pub, decShares, ctxt)
+		if err == nil || !strings.Contains(strings.ToLower(err.Error()), strings.ToLower(expected)) {
+			t.Errorf("Expected error %q from Recover; got %v", expected, err)
+		}
+	}
+
+	pub := PublicKey{SchnorrGroup: group, Y: big.NewInt(16)}
+	_, err = DecWithSubgroupCheck(pub, keyShare, Ciphertext{C: msg}, false)
+	if !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("Expected ErrInvalidCiphertext from Dec with nil R; got %v", err)
+	}
+	_, err = Dec(pub, PrivateKeyShare{ID: 1}, ctxt)
+	if err == nil {
+		t.Errorf("Expected error from Dec with nil key share; got none")
+	}
+	_, err = Recover(pub, decShares, Ciphertext{C: msg})
+	if !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("Expected ErrInvalidCiphertext from Recover with nil R; got %v", err)
+	}
+	_, err = Recover(pub, decShares, Ciphertext{R: big.NewInt(3)})
+	if !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("Expected ErrInvalidCiphertext from Recover with nil C; got %v", err)
+	}
+	_, err = Recover(pub, []DecryptionShare{{ID: 1}}, ctxt)
+	if err == nil {
+		t.Errorf("Expected error from Recover with nil decryption share; got none")
+	}
+}
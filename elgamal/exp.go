@@ -0,0 +1,176 @@
+package elgamal
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// ExpCiphertext represents a ciphertext of exponential ElGamal, which
+// encrypts a message m as (g^r, g^m * y^r).
+//
+// Unlike hashed ElGamal, exponential ElGamal allows to re-randomize
+// ciphertexts without knowledge of the message, at the cost of encrypting
+// integers rather than byte strings.
+type ExpCiphertext struct {
+	// R = g^r
+	R *big.Int
+	// C = g^m * y^r
+	C *big.Int
+}
+
+// EncExp encrypts an integer message m using exponential ElGamal.
+//
+// Recovery by RecoverExp() yields g^m rather than m, so m should be small
+// enough for the discrete logarithm to be feasible.
+//
+// An error is returned if m is not in [0, q), or if encryption fails.
+func EncExp(pub PublicKey, m *big.Int) (ExpCiphertext, error) {
+	var ctxt ExpCiphertext
+
+	if m == nil || m.Sign() < 0 || m.Cmp(pub.Q) >= 0 {
+		return ctxt, fmt.Errorf("m must be in [0, q)")
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return ctxt, err
+	}
+
+	r, err := randomExponent(nil, &pub)
+	if err != nil {
+		return ctxt, err
+	}
+
+	ctxt.R = zp.Exp(pub.G, r)                           // g^r
+	ctxt.C = zp.Mul(zp.Exp(pub.G, m), zp.Exp(pub.Y, r)) // g^m * y^r
+
+	return ctxt, nil
+}
+
+// ReRandomize produces a fresh ciphertext of the same message, which is
+// unlinkable to the passed one by anyone not holding the private key. It
+// multiplies in (g^r', y^r') for a random r'.
+//
+// An error is returned if the ciphertext is incomplete, or if sourcing of
+// randomness fails.
+func ReRandomize(pub PublicKey, ctxt ExpCiphertext) (ExpCiphertext, error) {
+	var out ExpCiphertext
+
+	if ctxt.R == nil || ctxt.C == nil {
+		return out, fmt.Errorf("Ciphertext is incomplete")
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return out, err
+	}
+
+	r, err := randomExponent(nil, &pub)
+	if err != nil {
+		return out, err
+	}
+
+	out.R = zp.Mul(ctxt.R, zp.Exp(pub.G, r)) // g^{r + r'}
+	out.C = zp.Mul(ctxt.C, zp.Exp(pub.Y, r)) // g^m * y^{r + r'}
+
+	return out, nil
+}
+
+// DecExp creates a single decryption share of an exponential ElGamal
+// ciphertext based on the passed share of the private key.
+//
+// t of these can be passed to RecoverExp() to decrypt the ciphertext.
+func DecExp(pub PublicKey, keyShare PrivateKeyShare, ctxt ExpCiphertext) (DecryptionShare, error) {
+	return Dec(pub, keyShare, Ciphertext{R: ctxt.R})
+}
+
+// RecoverExp decrypts an exponential ElGamal ciphertext using t decryption
+// shares, returning g^m.
+//
+// An error is returned if fewer than pub.Threshold decryption shares are
+// passed, or if multiple shares have the same ID.
+func RecoverExp(pub PublicKey, decryptionShares []DecryptionShare, ctxt ExpCiphertext) (*big.Int, error) {
+	if ctxt.C == nil {
+		return nil, fmt.Errorf("Ciphertext is missing C")
+	}
+
+	z, err := combineSharesOf(pub, decryptionShares, nil) // y^r
+	if err != nil {
+		return nil, err
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return nil, err
+	}
+
+	return zp.Div(ctxt.C, z), nil // g^m * y^r / y^r
+}
+
+// AddExp homomorphically adds two exponential ElGamal ciphertexts encrypted
+// under the same public key, by multiplying them component-wise. The result
+// encrypts the sum of the messages modulo q.
+//
+// As the sum is recovered by computing a discrete logarithm, it must stay
+// small, e.g. when tallying votes.
+//
+// An error wrapping ErrInvalidCiphertext is returned if any component of
+// either ciphertext is missing, or is not an element of the order-q
+// subgroup. An error is also returned if the group of the public key is
+// invalid.
+func AddExp(pub PublicKey, a ExpCiphertext, b ExpCiphertext) (ExpCiphertext, error) {
+	var sum ExpCiphertext
+
+	err := pub.checkGroup()
+	if err != nil {
+		return sum, err
+	}
+	zp, err := pub.Zp()
+	if err != nil {
+		return sum, err
+	}
+
+	for _, elem := range []struct {
+		name  string
+		value *big.Int
+	}{{"R", a.R}, {"C", a.C}, {"R", b.R}, {"C", b.C}} {
+		if elem.value == nil {
+			return sum, fmt.Errorf("%w: missing %s", ErrInvalidCiphertext, elem.name)
+		}
+		if !isSubgroupElement(zp, pub.Q, elem.value) {
+			return sum, fmt.Errorf("%w: %s is not in the order-q subgroup", ErrInvalidCiphertext, elem.name)
+		}
+	}
+
+	sum.R = zp.Mul(a.R, b.R) // g^{r_a + r_b}
+	sum.C = zp.Mul(a.C, b.C) // g^{m_a + m_b} * y^{r_a + r_b}
+
+	return sum, nil
+}
+
+// RecoverExpValue decrypts an exponential ElGamal ciphertext using t
+// decryption shares, returning the message m, which is assumed to be in
+// [0, bound).
+//
+// As this requires solving g^m = z for m using DiscreteLog(), the running
+// time grows with the square root of the bound. An error is returned if no
+// m < bound is found.
+func RecoverExpValue(pub PublicKey, decryptionShares []DecryptionShare, ctxt ExpCiphertext, bound int64) (*big.Int, error) {
+	gm, err := RecoverExp(pub, decryptionShares, ctxt)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiscreteLog(pub.G, gm, pub.P, bound)
+}
+
+// randomExponent draws an exponent r from [1, q), sourcing randomness from
+// the passed reader, or crypto/rand's Reader if it is nil.
+func randomExponent(random io.Reader, pub *PublicKey) (*big.Int, error) {
+	if pub.Q == nil || pub.Q.Cmp(big.NewInt(2)) < 0 {
+		return nil, fmt.Errorf("q must be >= 2")
+	}
+
+	return randomInRange(random, big.NewInt(1), pub.Q) // [1, q)
+}
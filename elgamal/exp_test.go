@@ -0,0 +1,132 @@
+package elgamal
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestReRandomize(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	zp, err := pub.Zp()
+	if err != nil {
+		t.Fatalf("Error creating field: %v", err)
+	}
+
+	m := big.NewInt(42)
+	ctxt, err := EncExp(pub, m)
+	if err != nil {
+		t.Fatalf("EncExp returned error: %v", err)
+	}
+
+	rerandomized, err := ReRandomize(pub, ctxt)
+	if err != nil {
+		t.Fatalf("ReRandomize returned error: %v", err)
+	}
+	if rerandomized.R.Cmp(ctxt.R) == 0 || rerandomized.C.Cmp(ctxt.C) == 0 {
+		t.Errorf("Expected re-randomized ciphertext to differ from original")
+	}
+
+	expected := zp.Exp(pub.G, m) // g^m
+	for _, c := range []ExpCiphertext{ctxt, rerandomized} {
+		decShares := make([]DecryptionShare, 3)
+		for i := range decShares {
+			decShares[i], err = DecExp(pub, shares[i], c)
+			if err != nil {
+				t.Fatalf("DecExp returned error: %v", err)
+			}
+		}
+
+		gm, err := RecoverExp(pub, decShares, c)
+		if err != nil {
+			t.Fatalf("RecoverExp returned error: %v", err)
+		}
+		if gm.Cmp(expected) != 0 {
+			t.Errorf("Expected recovery to yield g^m = %d; got %d", expected, gm)
+		}
+
+		_, err = RecoverExp(pub, decShares[:2], c)
+		if err == nil {
+			t.Errorf("Expected error with fewer than t shares; got none")
+		}
+	}
+
+	for _, invalid := range []*big.Int{nil, big.NewInt(-1), pub.Q} {
+		_, err = EncExp(pub, invalid)
+		if err == nil {
+			t.Errorf("Expected error with m = %v; got none", invalid)
+		}
+	}
+
+	_, err = ReRandomize(pub, ExpCiphertext{})
+	if err == nil {
+		t.Errorf("Expected error with incomplete ciphertext; got none")
+	}
+}
+
+func TestAddExp(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	a, err := EncExp(pub, big.NewInt(3))
+	if err != nil {
+		t.Fatalf("EncExp returned error: %v", err)
+	}
+	b, err := EncExp(pub, big.NewInt(4))
+	if err != nil {
+		t.Fatalf("EncExp returned error: %v", err)
+	}
+
+	sum, err := AddExp(pub, a, b)
+	if err != nil {
+		t.Fatalf("AddExp returned error: %v", err)
+	}
+
+	decShares := make([]DecryptionShare, 3)
+	for i := range decShares {
+		decShares[i], err = DecExp(pub, shares[i], sum)
+		if err != nil {
+			t.Fatalf("DecExp returned error: %v", err)
+		}
+	}
+
+	m, err := RecoverExpValue(pub, decShares, sum, 100)
+	if err != nil {
+		t.Fatalf("RecoverExpValue returned error: %v", err)
+	}
+	if m.Cmp(big.NewInt(7)) != 0 {
+		t.Errorf("Expected 3 + 4 = 7; got %d", m)
+	}
+
+	_, err = RecoverExpValue(pub, decShares, sum, 7)
+	if err == nil {
+		t.Errorf("Expected error if sum exceeds bound; got none")
+	}
+
+	duplicate := []DecryptionShare{decShares[0], decShares[0], decShares[1]}
+	_, err = RecoverExp(pub, duplicate, sum)
+	if !errors.Is(err, ErrDuplicateShare) {
+		t.Errorf("Expected ErrDuplicateShare; got %v", err)
+	}
+	_, err = RecoverExp(pub, []DecryptionShare{decShares[0], decShares[1], {ID: 3}}, sum)
+	if err == nil {
+		t.Errorf("Expected error with nil decryption share; got none")
+	}
+
+	negated := ExpCiphertext{R: a.R, C: new(big.Int).Sub(pub.P, a.C)}
+	for _, invalid := range []ExpCiphertext{{}, {R: a.R}, {C: a.C}, negated} {
+		_, err = AddExp(pub, invalid, b)
+		if !errors.Is(err, ErrInvalidCiphertext) {
+			t.Errorf("Expected ErrInvalidCiphertext with %+v; got %v", invalid, err)
+		}
+		_, err = AddExp(pub, a, invalid)
+		if !errors.Is(err, ErrInvalidCiphertext) {
+			t.Errorf("Expected ErrInvalidCiphertext with %+v; got %v", invalid, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package elgamal
+
+import (
+	"math/big"
+)
+
+// Parameters of the 2048-bit MODP group of RFC 3526, section 3. P is a safe
+// prime, and g = 2 generates the subgroup of order q = (p-1)/2.
+const (
+	rfc3526P2048 = "FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74" +
+		"020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F1437" +
+		"4FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED" +
+		"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF05" +
+		"98DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB" +
+		"9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B" +
+		"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF695581718" +
+		"3995497CEA956AE515D2261898FA051015728E5A8AACAA68FFFFFFFFFFFFFFFF"
+	rfc3526Q2048 = "7FFFFFFFFFFFFFFFE487ED5110B4611A62633145C06E0E68948127044533E63A" +
+		"0105DF531D89CD9128A5043CC71A026EF7CA8CD9E69D218D98158536F92F8A1B" +
+		"A7F09AB6B6A8E122F242DABB312F3F637A262174D31BF6B585FFAE5B7A035BF6" +
+		"F71C35FDAD44CFD2D74F9208BE258FF324943328F6722D9EE1003E5C50B1DF82" +
+		"CC6D241B0E2AE9CD348B1FD47E9267AFC1B2AE91EE51D6CB0E3179AB1042A95D" +
+		"CF6A9483B84B4B36B3861AA7255E4C0278BA3604650C10BE19482F23171B671D" +
+		"F1CF3B960C074301CD93C1D17603D147DAE2AEF837A62964EF15E5FB4AAC0B8C" +
+		"1CCAA4BE754AB5728AE9130C4C7D02880AB9472D455655347FFFFFFFFFFFFFFF"
+	rfc3526G2048 = "2"
+)
+
+// Parameters of the 2048-bit MODP group with 256-bit prime order subgroup of
+// RFC 5114, section 2.3, as specified for use with NIST SP 800-56A.
+const (
+	nistP2048Q256P = "87A8E61DB4B6663CFFBBD19C651959998CEEF608660DD0F25D2CEED4435E3B00" +
+		"E00DF8F1D61957D4FAF7DF4561B2AA3016C3D91134096FAA3BF4296D830E9A7C" +
+		"209E0C6497517ABD5A8A9D306BCF67ED91F9E6725B4758C022E0B1EF4275BF7B" +
+		"6C5BFC11D45F9088B941F54EB1E59BB8BC39A0BF12307F5C4FDB70C581B23F76" +
+		"B63ACAE1CAA6B7902D52526735488A0EF13C6D9A51BFA4AB3AD8347796524D8E" +
+		"F6A167B5A41825D967E144E5140564251CCACB83E6B486F6B3CA3F7971506026" +
+		"C0B857F689962856DED4010ABD0BE621C3A3960A54E710C375F26375D7014103" +
+		"A4B54330C198AF126116D2276E11715F693877FAD7EF09CADB094AE91E1A1597"
+	nistP2048Q256Q = "8CF83642A709A097B447997640129DA299B1A47D1EB3750BA308B0FE64F5FBD3"
+	nistP2048Q256G = "3FB32C9B73134D0B2E77506660EDBD484CA7B18F21EF205407F4793A1A0BA125" +
+		"10DBC15077BE463FFF4FED4AAC0BB555BE3A6C1B0C6B47B1BC3773BF7E8C6F62" +
+		"901228F8C28CBB18A55AE31341000A650196F931C77A57F2DDF463E5E9EC144B" +
+		"777DE62AAAB8A8628AC376D282D6ED3864E67982428EBC831D14348F6F2F9193" +
+		"B5045AF2767164E1DFC967C1FB3F2E55A4BD1BFFE83B9C80D052B985D182EA0A" +
+		"DB2A3B7313D3FE14C8484B1E052588B9B7D2BBD2DF016199ECD06E1557CD0915" +
+		"B3353BBB64E0EC377FD028370DF92B52C7891428CDC67EB6184B523D1DB246C3" +
+		"2F63078490F00EF8D647D148D47954515E2327CFEF98C582664B4C0F6CC41659"
+)
+
+// SchnorrGroupRFC3526_2048 returns the 2048-bit MODP group of RFC 3526.
+//
+// As p is a safe prime, q is of length 2047 bits, which makes exponentiations
+// considerably slower than in SchnorrGroupNIST_P2048_Q256().
+func SchnorrGroupRFC3526_2048() SchnorrGroup {
+	return SchnorrGroup{
+		P: mustParseHex(rfc3526P2048),
+		Q: mustParseHex(rfc3526Q2048),
+		G: mustParseHex(rfc3526G2048),
+	}
+}
+
+// SchnorrGroupNIST_P2048_Q256 returns the 2048-bit MODP group with a 256-bit
+// prime order subgroup of RFC 5114.
+func SchnorrGroupNIST_P2048_Q256() SchnorrGroup {
+	return SchnorrGroup{
+		P: mustParseHex(nistP2048Q256P),
+		Q: mustParseHex(nistP2048Q256Q),
+		G: mustParseHex(nistP2048Q256G),
+	}
+}
+
+// mustParseHex parses a hard-coded base-16 constant, panicking if it is
+// malformed.
+func mustParseHex(s string) *big.Int {
+	x, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("elgamal: malformed hex constant " + s)
+	}
+
+	return x
+}
@@ -0,0 +1,41 @@
+package elgamal
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestStandardGroups(t *testing.T) {
+	groups := map[string]SchnorrGroup{
+		"RFC3526_2048":    SchnorrGroupRFC3526_2048(),
+		"NIST_P2048_Q256": SchnorrGroupNIST_P2048_Q256(),
+	}
+
+	for name, group := range groups {
+		err := group.Validate()
+		if err != nil {
+			t.Errorf("Expected group %s to be valid; got %v", name, err)
+		}
+
+		if group.P.BitLen() != 2048 {
+			t.Errorf("Expected p of group %s to have bit length 2048; got %d", name, group.P.BitLen())
+		}
+
+		var elem = &big.Int{}
+		elem.Exp(group.G, group.Q, group.P)
+		if elem.Cmp(big.NewInt(1)) != 0 {
+			t.Errorf("Expected g^q mod p = 1 for group %s; got %d", name, elem)
+		}
+	}
+
+	if SchnorrGroupNIST_P2048_Q256().Q.BitLen() != 256 {
+		t.Errorf("Expected q of NIST group to have bit length 256")
+	}
+
+	// Constructors must return independent copies
+	group := SchnorrGroupRFC3526_2048()
+	group.P.SetInt64(23)
+	if SchnorrGroupRFC3526_2048().P.Cmp(big.NewInt(23)) == 0 {
+		t.Errorf("Expected modification of returned group not to affect constructor")
+	}
+}
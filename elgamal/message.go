@@ -0,0 +1,105 @@
+package elgamal
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// PadMessage zero-pads a message to exactly one block of the default hash
+// algorithm, i.e. 64 bytes, as required by Enc(). This is the recommended way
+// to prepare short messages for encryption under keys using the default hash
+// algorithm.
+//
+// Zero padding is only unambiguous for messages which do not end in zero
+// bytes, see UnpadMessage(). Use EncMessage() for arbitrary binary messages.
+//
+// An error wrapping ErrMessageLength is returned if the message exceeds 64
+// bytes.
+func PadMessage(msg []byte) ([]byte, error) {
+	blockSize := defaultHash.Size()
+	if len(msg) > blockSize {
+		return nil, fmt.Errorf("%w: must be at most %d bytes; got %d", ErrMessageLength, blockSize, len(msg))
+	}
+
+	block := make([]byte, blockSize)
+	copy(block, msg)
+
+	return block, nil
+}
+
+// UnpadMessage strips the zero padding added by PadMessage(), returning the
+// message without any trailing zero bytes.
+//
+// Trailing zero bytes of the original message are stripped as well, as they
+// cannot be told apart from the padding.
+func UnpadMessage(block []byte) []byte {
+	return bytes.TrimRight(block, "\x00")
+}
+
+// EncMessage encrypts a message shorter than one block, sparing callers from
+// padding it themselves.
+//
+// The message is encoded into a single block of length pub.BlockSize(), with
+// the first byte holding the length of the message, followed by the message
+// itself and zero padding. As such, messages of up to pub.BlockSize() - 1
+// bytes - 63 with the default hash algorithm - are supported.
+//
+// Ciphertexts produced by EncMessage() must be decrypted with
+// RecoverMessage(), which strips the encoding again.
+func EncMessage(pub PublicKey, message []byte) (Ciphertext, error) {
+	block, err := encodeMessage(pub.BlockSize(), message)
+	if err != nil {
+		return Ciphertext{}, err
+	}
+
+	return Enc(pub, block)
+}
+
+// RecoverMessage decrypts a ciphertext produced by EncMessage() using t
+// decryption shares, returning the original message without its encoding.
+//
+// An error is returned if recovery fails, or if the recovered block is not a
+// valid encoding, e.g. as the ciphertext was not produced by EncMessage().
+func RecoverMessage(pub PublicKey, decryptionShares []DecryptionShare, ctxt Ciphertext) ([]byte, error) {
+	block, err := Recover(pub, decryptionShares, ctxt)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeMessage(block)
+}
+
+// encodeMessage encodes a message into a block of length blockSize, with the
+// first byte holding the length of the message.
+func encodeMessage(blockSize int, message []byte) ([]byte, error) {
+	if blockSize == 0 {
+		return nil, fmt.Errorf("Unsupported hash algorithm")
+	}
+	if len(message) > blockSize-1 {
+		return nil, fmt.Errorf("%w: must be at most %d bytes; got %d", ErrMessageLength, blockSize-1, len(message))
+	}
+
+	block := make([]byte, blockSize)
+	block[0] = byte(len(message))
+	copy(block[1:], message)
+
+	return block, nil
+}
+
+// decodeMessage extracts the message from a block produced by
+// encodeMessage().
+func decodeMessage(block []byte) ([]byte, error) {
+	if len(block) == 0 {
+		return nil, fmt.Errorf("Encoded message is empty")
+	}
+
+	length := int(block[0])
+	if length > len(block)-1 {
+		return nil, fmt.Errorf("Encoded message length %d exceeds block of %d bytes", length, len(block))
+	}
+
+	message := make([]byte, length)
+	copy(message, block[1:1+length])
+
+	return message, nil
+}
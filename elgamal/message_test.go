@@ -0,0 +1,100 @@
+package elgamal
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"testing"
+)
+
+func TestEncMessage(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	for _, msg := range [][]byte{[]byte("Hello world"), {}, bytes.Repeat([]byte{0xFF}, 63)} {
+		ctxt, err := EncMessage(pub, msg)
+		if err != nil {
+			t.Fatalf("EncMessage returned error for message of %d bytes: %v", len(msg), err)
+		}
+
+		decShares, err := DecAll(pub, shares[:3], ctxt)
+		if err != nil {
+			t.Fatalf("DecAll returned error: %v", err)
+		}
+
+		recovered, err := RecoverMessage(pub, decShares, ctxt)
+		if err != nil {
+			t.Fatalf("RecoverMessage returned error: %v", err)
+		}
+		if len(recovered) != len(msg) {
+			t.Errorf("Expected recovered message of %d bytes; got %d", len(msg), len(recovered))
+		}
+		if !bytes.Equal(recovered, msg) {
+			t.Errorf("Expected recovered message %x; got %x", msg, recovered)
+		}
+	}
+
+	_, err = EncMessage(pub, make([]byte, 64))
+	if err == nil {
+		t.Errorf("Expected error with message of 64 bytes; got none")
+	}
+
+	// Block size is smaller with SHA-256
+	pub.Hash = crypto.SHA256
+	_, err = EncMessage(pub, make([]byte, 31))
+	if err != nil {
+		t.Errorf("EncMessage returned error for message of 31 bytes with SHA-256: %v", err)
+	}
+	_, err = EncMessage(pub, make([]byte, 32))
+	if err == nil {
+		t.Errorf("Expected error with message of 32 bytes with SHA-256; got none")
+	}
+}
+
+func TestDecodeMessage(t *testing.T) {
+	block := make([]byte, 64)
+	block[0] = 64
+	_, err := decodeMessage(block)
+	if err == nil {
+		t.Errorf("Expected error with length exceeding block; got none")
+	}
+
+	_, err = decodeMessage(nil)
+	if err == nil {
+		t.Errorf("Expected error with empty block; got none")
+	}
+}
+
+func TestPadMessage(t *testing.T) {
+	// 'Hello world', padded to 64 bytes, as in TestRecover
+	expected := []byte{0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}
+
+	padded, err := PadMessage([]byte("Hello world"))
+	if err != nil {
+		t.Fatalf("PadMessage returned error: %v", err)
+	}
+	if !bytes.Equal(padded, expected) {
+		t.Errorf("Expected padded message %x; got %x", expected, padded)
+	}
+
+	unpadded := UnpadMessage(padded)
+	if !bytes.Equal(unpadded, []byte("Hello world")) {
+		t.Errorf("Expected unpadded message %q; got %q", "Hello world", unpadded)
+	}
+
+	full := bytes.Repeat([]byte{0xFF}, 64)
+	padded, err = PadMessage(full)
+	if err != nil {
+		t.Fatalf("PadMessage returned error: %v", err)
+	}
+	if !bytes.Equal(padded, full) {
+		t.Errorf("Expected message of 64 bytes to be unchanged; got %x", padded)
+	}
+
+	_, err = PadMessage(make([]byte, 65))
+	if !errors.Is(err, ErrMessageLength) {
+		t.Errorf("Expected ErrMessageLength with message of 65 bytes; got %v", err)
+	}
+}
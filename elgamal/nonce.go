@@ -0,0 +1,56 @@
+package elgamal
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// NonceTracker keeps track of the random exponents r used during encryption,
+// allowing to reject a nonce which was used before.
+//
+// Reuse of r across two encryptions under the same key leaks the XOR of the
+// two plaintexts, so services with multiple encryptors may want to enforce
+// uniqueness.
+//
+// A tracker is consulted by EncTracked(), EncBatchTracked(), encryptors
+// created using NewEncryptorTracked(), and schemes whose Params specify one.
+// Enc() and all other encryption functions do not track nonces.
+type NonceTracker interface {
+	// Check records r as used. An error is returned if r was seen
+	// before, or if it could not be recorded.
+	Check(r *big.Int) error
+}
+
+// MemoryNonceTracker is an in-memory NonceTracker. It is safe for concurrent
+// use.
+//
+// As it only knows about nonces used within the current process, tracking
+// nonces across multiple processes or machines requires an implementation of
+// NonceTracker backed by a shared store.
+type MemoryNonceTracker struct {
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+// NewMemoryNonceTracker returns an empty in-memory nonce tracker.
+func NewMemoryNonceTracker() *MemoryNonceTracker {
+	return &MemoryNonceTracker{
+		seen: make(map[string]struct{}),
+	}
+}
+
+// Check records r as used, returning an error if it was seen before.
+func (t *MemoryNonceTracker) Check(r *big.Int) error {
+	key := string(r.Bytes())
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.seen[key]; ok {
+		return fmt.Errorf("Nonce was used before")
+	}
+	t.seen[key] = struct{}{}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package elgamal
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// rejectingTracker is a NonceTracker which rejects every nonce.
+type rejectingTracker struct{}
+
+func (rejectingTracker) Check(r *big.Int) error {
+	return fmt.Errorf("Rejected")
+}
+
+func TestMemoryNonceTracker(t *testing.T) {
+	tracker := NewMemoryNonceTracker()
+
+	err := tracker.Check(big.NewInt(4))
+	if err != nil {
+		t.Errorf("Expected unseen nonce to be accepted; got %v", err)
+	}
+
+	err = tracker.Check(big.NewInt(5))
+	if err != nil {
+		t.Errorf("Expected unseen nonce to be accepted; got %v", err)
+	}
+
+	err = tracker.Check(big.NewInt(4))
+	if err == nil {
+		t.Errorf("Expected error for previously seen nonce; got none")
+	}
+}
+
+func TestEncTracked(t *testing.T) {
+	pub := PublicKey{
+		SchnorrGroup: SchnorrGroup{
+			P: big.NewInt(23),
+			Q: big.NewInt(11),
+			G: big.NewInt(4),
+		},
+		Y: big.NewInt(16),
+	}
+	msg := make([]byte, 64)
+
+	_, err := EncTracked(pub, msg, NewMemoryNonceTracker())
+	if err != nil {
+		t.Errorf("EncTracked returned error: %v", err)
+	}
+
+	_, err = EncTracked(pub, msg, nil)
+	if err != nil {
+		t.Errorf("EncTracked without tracker returned error: %v", err)
+	}
+
+	_, err = EncTracked(pub, msg, rejectingTracker{})
+	if err == nil {
+		t.Errorf("Expected error if tracker rejects nonce; got none")
+	}
+}
+
+func TestNonceTrackerOptions(t *testing.T) {
+	group := SchnorrGroup{
+		P: big.NewInt(23),
+		Q: big.NewInt(11),
+		G: big.NewInt(4),
+	}
+	pub := PublicKey{SchnorrGroup: group, Y: big.NewInt(16)}
+	msg := make([]byte, 64)
+
+	_, err := EncBatchTracked(pub, [][]byte{msg, msg}, rejectingTracker{})
+	if err == nil {
+		t.Errorf("Expected error from EncBatchTracked if tracker rejects nonce; got none")
+	}
+
+	encryptor, err := NewEncryptorTracked(pub, rejectingTracker{})
+	if err != nil {
+		t.Fatalf("NewEncryptorTracked returned error: %v", err)
+	}
+	_, err = encryptor.Enc(msg)
+	if err == nil {
+		t.Errorf("Expected error from Encryptor if tracker rejects nonce; got none")
+	}
+
+	scheme, err := NewScheme(Params{Group: group, Tracker: rejectingTracker{}})
+	if err != nil {
+		t.Fatalf("NewScheme returned error: %v", err)
+	}
+	_, err = scheme.Enc(pub, msg)
+	if err == nil {
+		t.Errorf("Expected error from Scheme if tracker rejects nonce; got none")
+	}
+
+	// With q = 11, at most 10 distinct nonces exist
+	tracker := NewMemoryNonceTracker()
+	encryptor, err = NewEncryptorTracked(pub, tracker)
+	if err != nil {
+		t.Fatalf("NewEncryptorTracked returned error: %v", err)
+	}
+	for i := 0; i < 11; i++ {
+		_, err = encryptor.Enc(msg)
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		t.Errorf("Expected error from Encryptor once nonces are exhausted; got none")
+	}
+}
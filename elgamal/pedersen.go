@@ -0,0 +1,156 @@
+package elgamal
+
+import (
+	"context"
+	"fmt"
+	"github.com/lavode/secret-sharing/gf"
+	"math/big"
+)
+
+// PedersenSharing holds the public and private data of a Pedersen verifiable
+// secret sharing of a private key, as produced by KeyGenPedersen().
+type PedersenSharing struct {
+	// Pedersen commitments C_j = g^{a_j} * h^{b_j} mod p to the
+	// coefficients a_j of the polynomial used to share the private key,
+	// blinded by the coefficients b_j of a second, random, polynomial.
+	// h is the group's SecondGenerator().
+	Commitments []*big.Int
+
+	// Blinding shares b(i), one for each private key share, with the
+	// same IDs. Each must be handed out along with the corresponding
+	// private key share.
+	Blindings []PrivateKeyShare
+}
+
+// KeyGenPedersen generates a t-out-of-n shared private key like KeyGen(), but
+// commits to the sharing polynomial using Pedersen rather than Feldman
+// commitments.
+//
+// Feldman commitments g^{a_j} reveal information about the polynomial, and
+// as such about the private key, to a computationally unbounded adversary.
+// Pedersen commitments g^{a_j} * h^{b_j}, with the coefficients b_j of a
+// random blinding polynomial, are perfectly hiding instead. Parties verify
+// their shares using VerifyKeySharePedersen().
+//
+// For the same reason, the returned public key has neither Feldman
+// commitments nor verification keys, as any t verification keys g^{x_i}
+// reveal g^{a_j} too.
+//
+// An error is returned if t < 1, n < 1 or t > n.
+func KeyGenPedersen(pBits int, qBits int, t int, n int) (PublicKey, PrivateKey, []PrivateKeyShare, PedersenSharing, error) {
+	var sharing PedersenSharing
+
+	err := checkThreshold(t, n)
+	if err != nil {
+		return PublicKey{}, PrivateKey{}, nil, sharing, err
+	}
+
+	schnorr, err := generateSchnorrGroup(context.Background(), nil, pBits, qBits, defaultPrimeChecks)
+	if err != nil {
+		return PublicKey{}, PrivateKey{}, nil, sharing, err
+	}
+
+	h, err := schnorr.SecondGenerator()
+	if err != nil {
+		return PublicKey{}, PrivateKey{}, nil, sharing, err
+	}
+
+	pub, priv, shares, err := keyGenInGroup(nil, schnorr, t, n)
+	if err != nil {
+		return pub, priv, shares, sharing, err
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return pub, priv, shares, sharing, err
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return pub, priv, shares, sharing, err
+	}
+
+	// Blinding polynomial of degree t-1, with a random constant term too
+	blinding := gf.Polynomial{
+		Field:        zq,
+		Coefficients: make([]*big.Int, t),
+	}
+	for j := range blinding.Coefficients {
+		blinding.Coefficients[j], err = randomInt(nil, zq.P)
+		if err != nil {
+			return pub, priv, shares, sharing, err
+		}
+	}
+
+	// C_j = g^{a_j} * h^{b_j}, building upon the Feldman commitments
+	// g^{a_j} of KeyGen
+	sharing.Commitments = make([]*big.Int, t)
+	for j, feldman := range pub.Commitments {
+		sharing.Commitments[j] = zp.Mul(feldman, zp.Exp(h, blinding.Coefficients[j]))
+	}
+
+	sharing.Blindings = make([]PrivateKeyShare, n)
+	for i, share := range shares {
+		value, err := blinding.Evaluate(big.NewInt(int64(share.ID))) // b(i)
+		if err != nil {
+			return pub, priv, shares, sharing, err
+		}
+		sharing.Blindings[i] = PrivateKeyShare{ID: share.ID, Value: value}
+	}
+
+	pub.Commitments = nil
+	pub.VerificationKeys = nil
+
+	return pub, priv, shares, sharing, nil
+}
+
+// VerifyKeySharePedersen checks a private key share and its blinding share
+// against the Pedersen commitments of a sharing, as produced by
+// KeyGenPedersen().
+//
+// The share is valid if g^{share} * h^{blinding} = prod_j
+// commitments[j]^{ID^j} mod p, with h the group's SecondGenerator(). An
+// error is returned if no commitments are passed, if the IDs of the share and
+// the blinding share differ, or if the fields of the public key cannot be
+// constructed.
+func VerifyKeySharePedersen(pub PublicKey, commitments []*big.Int, share PrivateKeyShare, blinding PrivateKeyShare) (bool, error) {
+	if len(commitments) == 0 {
+		return false, fmt.Errorf("No commitments to verify share against")
+	}
+	if share.ID != blinding.ID {
+		return false, fmt.Errorf("IDs of share and blinding share differ; got %d and %d", share.ID, blinding.ID)
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return false, err
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return false, err
+	}
+	h, err := pub.SchnorrGroup.SecondGenerator()
+	if err != nil {
+		return false, err
+	}
+
+	for _, value := range []*big.Int{share.Value, blinding.Value} {
+		if value == nil || !zq.IsGroupElement(value) {
+			return false, nil
+		}
+	}
+
+	x := big.NewInt(int64(share.ID))
+	expected := big.NewInt(1)
+	for j, commitment := range commitments {
+		if commitment == nil {
+			return false, nil
+		}
+		// Commitments are of order q, so exponents are over (Z/qZ)
+		exp := zq.Exp(x, big.NewInt(int64(j))) // id^j
+		expected = zp.Mul(expected, zp.Exp(commitment, exp))
+	}
+
+	actual := zp.Mul(zp.Exp(pub.G, share.Value), zp.Exp(h, blinding.Value))
+
+	return actual.Cmp(expected) == 0, nil
+}
@@ -0,0 +1,201 @@
+package elgamal
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+)
+
+// PublicKeyPEMType is the type of PEM blocks holding a public key.
+const PublicKeyPEMType = "DISTRIBUTED ELGAMAL PUBLIC KEY"
+
+// publicKeyASN1 is the ASN.1 representation of a PublicKey, as stored in PEM
+// blocks. Fields which are zero are omitted.
+type publicKeyASN1 struct {
+	P                *big.Int
+	Q                *big.Int
+	G                *big.Int
+	Y                *big.Int
+	Hash             string                `asn1:"optional,explicit,utf8,tag:0"`
+	Threshold        int                   `asn1:"optional,explicit,tag:1"`
+	VerificationKeys []verificationKeyASN1 `asn1:"optional,explicit,tag:2"`
+	Commitments      []*big.Int            `asn1:"optional,explicit,tag:3"`
+}
+
+// verificationKeyASN1 is the ASN.1 representation of a VerificationKey.
+type verificationKeyASN1 struct {
+	ID    int
+	Value *big.Int
+}
+
+// MarshalPEM encodes the public key as a PEM block of type
+// PublicKeyPEMType.
+//
+// The block holds a DER-encoded ASN.1 sequence of P, Q, G and Y, followed by
+// the hash algorithm, threshold, verification keys and commitments, each of
+// which is omitted if empty.
+//
+// An error is returned if any of P, Q, G or Y is missing.
+func (pk PublicKey) MarshalPEM() ([]byte, error) {
+	if pk.P == nil || pk.Q == nil || pk.G == nil || pk.Y == nil {
+		return nil, fmt.Errorf("Public key is missing one of p, q, g or y")
+	}
+
+	enc := publicKeyASN1{
+		P:         pk.P,
+		Q:         pk.Q,
+		G:         pk.G,
+		Y:         pk.Y,
+		Threshold: pk.Threshold,
+	}
+	if pk.Hash != 0 {
+		enc.Hash = pk.Hash.String()
+	}
+	for _, vk := range pk.VerificationKeys {
+		if vk.Value == nil {
+			return nil, fmt.Errorf("Verification key %d is missing value", vk.ID)
+		}
+		enc.VerificationKeys = append(enc.VerificationKeys, verificationKeyASN1{ID: vk.ID, Value: vk.Value})
+	}
+	for i, commitment := range pk.Commitments {
+		if commitment == nil {
+			return nil, fmt.Errorf("Commitment %d is missing", i)
+		}
+		enc.Commitments = append(enc.Commitments, commitment)
+	}
+
+	der, err := asn1.Marshal(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: PublicKeyPEMType, Bytes: der}), nil
+}
+
+// ParsePublicKeyPEM decodes a public key which was encoded using
+// MarshalPEM(). Data following the PEM block is ignored.
+//
+// An error is returned if no PEM block of type PublicKeyPEMType is found, if
+// its contents are malformed, if the Schnorr group is not valid as per
+// Validate(), or if y is 1 or not an element of the subgroup of order q, as
+// with ParsePublicKey().
+func ParsePublicKeyPEM(data []byte) (PublicKey, error) {
+	var pub PublicKey
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return pub, fmt.Errorf("No PEM block found")
+	}
+	if block.Type != PublicKeyPEMType {
+		return pub, fmt.Errorf("Expected PEM block of type %q; got %q", PublicKeyPEMType, block.Type)
+	}
+
+	var enc publicKeyASN1
+	rest, err := asn1.Unmarshal(block.Bytes, &enc)
+	if err != nil {
+		return pub, err
+	}
+	if len(rest) != 0 {
+		return pub, fmt.Errorf("Public key has %d bytes of trailing data", len(rest))
+	}
+
+	hash, err := decodeHash(enc.Hash)
+	if err != nil {
+		return pub, err
+	}
+	if enc.Threshold < 0 {
+		return pub, fmt.Errorf("Threshold must not be negative; got %d", enc.Threshold)
+	}
+
+	pub.SchnorrGroup = SchnorrGroup{P: enc.P, Q: enc.Q, G: enc.G}
+	err = pub.SchnorrGroup.Validate()
+	if err != nil {
+		return PublicKey{}, err
+	}
+	err = checkPublicY(pub.SchnorrGroup, enc.Y)
+	if err != nil {
+		return PublicKey{}, err
+	}
+
+	pub.Y = enc.Y
+	pub.Hash = hash
+	pub.Threshold = enc.Threshold
+	for _, vk := range enc.VerificationKeys {
+		pub.VerificationKeys = append(pub.VerificationKeys, VerificationKey{ID: vk.ID, Value: vk.Value})
+	}
+	pub.Commitments = enc.Commitments
+	pub.fields = newFieldCache()
+
+	return pub, nil
+}
+
+// DHParamsPEMType is the type of PEM blocks holding PKCS#3 Diffie-Hellman
+// parameters, as written by `openssl dhparam`.
+const DHParamsPEMType = "DH PARAMETERS"
+
+// dhParamsASN1 is the ASN.1 representation of PKCS#3 Diffie-Hellman
+// parameters.
+type dhParamsASN1 struct {
+	P                  *big.Int
+	G                  *big.Int
+	PrivateValueLength int `asn1:"optional"`
+}
+
+// ParseDHParams decodes a Schnorr group from PKCS#3 Diffie-Hellman
+// parameters, such as the ones generated by `openssl dhparam`. Data following
+// the PEM block is ignored.
+//
+// PKCS#3 parameters consist of p and g only. The order q of the subgroup is
+// derived as q = (p-1) / 2, which requires p to be a safe prime, as is the
+// case for parameters generated by OpenSSL. If g generates all of (Z/pZ)*
+// rather than the subgroup of order q, g^2 mod p is used as generator
+// instead.
+//
+// An error is returned if no PEM block of type DHParamsPEMType is found, if
+// its contents are malformed, if p is not a safe prime, or if the resulting
+// group is not valid as per Validate().
+func ParseDHParams(data []byte) (SchnorrGroup, error) {
+	var group SchnorrGroup
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return group, fmt.Errorf("No PEM block found")
+	}
+	if block.Type != DHParamsPEMType {
+		return group, fmt.Errorf("Expected PEM block of type %q; got %q", DHParamsPEMType, block.Type)
+	}
+
+	var enc dhParamsASN1
+	rest, err := asn1.Unmarshal(block.Bytes, &enc)
+	if err != nil {
+		return group, err
+	}
+	if len(rest) != 0 {
+		return group, fmt.Errorf("DH parameters have %d bytes of trailing data", len(rest))
+	}
+	if enc.P.Cmp(big.NewInt(5)) < 0 {
+		return group, fmt.Errorf("%w: p must be >= 5", ErrInvalidGroup)
+	}
+
+	q := new(big.Int).Sub(enc.P, big.NewInt(1))
+	q.Rsh(q, 1) // (p-1) / 2
+	if enc.P.Bit(0) != 1 || !q.ProbablyPrime(32) {
+		return group, fmt.Errorf("%w: p is not a safe prime, cannot determine q", ErrInvalidGroup)
+	}
+
+	// As the cofactor is 2, g is either of order q already, or of order 2q,
+	// in which case its square is of order q.
+	g := new(big.Int).Set(enc.G)
+	if g.Sign() > 0 && g.Cmp(enc.P) < 0 && new(big.Int).Exp(g, q, enc.P).Cmp(big.NewInt(1)) != 0 {
+		g.Exp(g, big.NewInt(2), enc.P)
+	}
+
+	group = SchnorrGroup{P: enc.P, Q: q, G: g}
+	err = group.Validate()
+	if err != nil {
+		return SchnorrGroup{}, err
+	}
+
+	return group, nil
+}
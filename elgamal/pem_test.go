@@ -0,0 +1,189 @@
+package elgamal
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestPublicKeyPEM(t *testing.T) {
+	pub, _, _, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	pub.Hash = crypto.SHA256
+
+	data, err := pub.MarshalPEM()
+	if err != nil {
+		t.Fatalf("MarshalPEM returned error: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("-----BEGIN DISTRIBUTED ELGAMAL PUBLIC KEY-----\n")) {
+		t.Errorf("Expected PEM block of type %q; got %s", PublicKeyPEMType, data)
+	}
+
+	decoded, err := ParsePublicKeyPEM(data)
+	if err != nil {
+		t.Fatalf("ParsePublicKeyPEM returned error: %v", err)
+	}
+
+	if decoded.P.Cmp(pub.P) != 0 || decoded.Q.Cmp(pub.Q) != 0 || decoded.G.Cmp(pub.G) != 0 || decoded.Y.Cmp(pub.Y) != 0 {
+		t.Errorf("Expected decoded public key %+v; got %+v", pub, decoded)
+	}
+	if decoded.Hash != pub.Hash {
+		t.Errorf("Expected hash %v; got %v", pub.Hash, decoded.Hash)
+	}
+	if decoded.Threshold != pub.Threshold {
+		t.Errorf("Expected threshold %d; got %d", pub.Threshold, decoded.Threshold)
+	}
+
+	if len(decoded.Commitments) != len(pub.Commitments) {
+		t.Fatalf("Expected %d commitments; got %d", len(pub.Commitments), len(decoded.Commitments))
+	}
+	for i, commitment := range pub.Commitments {
+		if decoded.Commitments[i].Cmp(commitment) != 0 {
+			t.Errorf("Expected commitment %d; got %d", commitment, decoded.Commitments[i])
+		}
+	}
+
+	if len(decoded.VerificationKeys) != len(pub.VerificationKeys) {
+		t.Fatalf("Expected %d verification keys; got %d", len(pub.VerificationKeys), len(decoded.VerificationKeys))
+	}
+	for i, vk := range pub.VerificationKeys {
+		got := decoded.VerificationKeys[i]
+		if got.ID != vk.ID || got.Value.Cmp(vk.Value) != 0 {
+			t.Errorf("Expected verification key %+v; got %+v", vk, got)
+		}
+	}
+
+	// Minimal key, without any optional fields
+	minimal := PublicKey{SchnorrGroup: pub.SchnorrGroup, Y: pub.Y}
+	data, err = minimal.MarshalPEM()
+	if err != nil {
+		t.Fatalf("MarshalPEM returned error: %v", err)
+	}
+	decoded, err = ParsePublicKeyPEM(data)
+	if err != nil {
+		t.Fatalf("ParsePublicKeyPEM returned error: %v", err)
+	}
+	if decoded.Hash != 0 || decoded.Threshold != 0 || len(decoded.VerificationKeys) != 0 || len(decoded.Commitments) != 0 {
+		t.Errorf("Expected no optional fields; got %+v", decoded)
+	}
+}
+
+func TestParsePublicKeyPEMInvalid(t *testing.T) {
+	pub, _, _, err := KeyGen(512, 128, 2, 3)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	data, err := pub.MarshalPEM()
+	if err != nil {
+		t.Fatalf("MarshalPEM returned error: %v", err)
+	}
+
+	_, err = ParsePublicKeyPEM(data[:len(data)/2])
+	if err == nil {
+		t.Errorf("Expected error for truncated PEM; got none")
+	}
+
+	_, err = ParsePublicKeyPEM(bytes.Replace(data, []byte("DISTRIBUTED ELGAMAL"), []byte("RSA"), -1))
+	if err == nil {
+		t.Errorf("Expected error for PEM block of wrong type; got none")
+	}
+
+	// Group with a generator of order 2
+	invalid := pub
+	invalid.SchnorrGroup.G = new(big.Int).Sub(pub.P, big.NewInt(1))
+	data, err = invalid.MarshalPEM()
+	if err != nil {
+		t.Fatalf("MarshalPEM returned error: %v", err)
+	}
+	_, err = ParsePublicKeyPEM(data)
+	if err == nil {
+		t.Errorf("Expected error for invalid group; got none")
+	}
+
+	// Public keys outside the subgroup of order q, or trivial
+	for _, y := range []*big.Int{big.NewInt(0), big.NewInt(1), new(big.Int).Sub(pub.P, big.NewInt(1)), pub.P} {
+		invalid := pub
+		invalid.Y = y
+		data, err = invalid.MarshalPEM()
+		if err != nil {
+			t.Fatalf("MarshalPEM returned error: %v", err)
+		}
+		_, err = ParsePublicKeyPEM(data)
+		if err == nil {
+			t.Errorf("Expected error for y = %d; got none", y)
+		}
+	}
+
+	_, err = PublicKey{}.MarshalPEM()
+	if err == nil {
+		t.Errorf("Expected error marshalling empty public key; got none")
+	}
+}
+
+func TestParseDHParams(t *testing.T) {
+	// Generated using `openssl dhparam 512`
+	data := []byte(`-----BEGIN DH PARAMETERS-----
+MEYCQQCtGswP4raLCHqXiuZJFYRxpEhESRTaATH+xTtgfE+I3vHyi8ydkb7Agh4T
+uY2qpNsw5Y8pPHU9qC99QHLRTSZnAgEC
+-----END DH PARAMETERS-----
+`)
+
+	group, err := ParseDHParams(data)
+	if err != nil {
+		t.Fatalf("ParseDHParams returned error: %v", err)
+	}
+	if group.P.BitLen() != 512 {
+		t.Errorf("Expected p of 512 bits; got %d", group.P.BitLen())
+	}
+	if group.Q.BitLen() != 511 {
+		t.Errorf("Expected q of 511 bits; got %d", group.Q.BitLen())
+	}
+
+	pub, _, keyShares, err := RekeyInGroup(group, 2, 3)
+	if err != nil {
+		t.Fatalf("RekeyInGroup returned error: %v", err)
+	}
+
+	msg := make([]byte, 64)
+	msg[0] = 42
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+	decShares := make([]DecryptionShare, 2)
+	for i := range decShares {
+		decShares[i], err = Dec(pub, keyShares[i], ctxt)
+		if err != nil {
+			t.Fatalf("Dec returned error: %v", err)
+		}
+	}
+	out, err := Recover(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if !bytes.Equal(out, msg) {
+		t.Errorf("Expected message %x; got %x", msg, out)
+	}
+
+	// p = 29 is prime, but (p-1)/2 = 14 is not
+	der, err := asn1.Marshal(dhParamsASN1{P: big.NewInt(29), G: big.NewInt(2)})
+	if err != nil {
+		t.Fatalf("asn1.Marshal returned error: %v", err)
+	}
+	_, err = ParseDHParams(pem.EncodeToMemory(&pem.Block{Type: DHParamsPEMType, Bytes: der}))
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup with p not a safe prime; got %v", err)
+	}
+
+	_, err = ParseDHParams([]byte("-----BEGIN FOO-----\n-----END FOO-----\n"))
+	if err == nil {
+		t.Errorf("Expected error with PEM block of wrong type; got none")
+	}
+}
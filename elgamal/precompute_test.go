@@ -0,0 +1,131 @@
+package elgamal
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFixedBase(t *testing.T) {
+	pub, _, _, err := KeyGen(512, 128, 2, 3)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	fb := newFixedBase(pub.G, pub.P, pub.Q.BitLen())
+	exponents := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(15),
+		big.NewInt(16),
+		new(big.Int).Sub(pub.Q, big.NewInt(1)),
+	}
+	for i := 0; i < 20; i++ {
+		r, err := RandomInRange(big.NewInt(0), pub.Q)
+		if err != nil {
+			t.Fatalf("RandomInRange returned error: %v", err)
+		}
+		exponents = append(exponents, r)
+	}
+
+	for _, e := range exponents {
+		expected := new(big.Int).Exp(pub.G, e, pub.P)
+		got := fb.exp(e)
+		if got.Cmp(expected) != 0 {
+			t.Errorf("Expected g^%d = %d; got %d", e, expected, got)
+		}
+	}
+}
+
+func TestEncryptor(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	encryptor, err := NewEncryptor(pub)
+	if err != nil {
+		t.Fatalf("NewEncryptor returned error: %v", err)
+	}
+
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+
+	// Same output as Enc() for the same r
+	r := big.NewInt(123456789)
+	expected, err := EncWithRandomness(pub, msg, r)
+	if err != nil {
+		t.Fatalf("EncWithRandomness returned error: %v", err)
+	}
+	got, err := encryptor.encryptWithRandomness(msg, r)
+	if err != nil {
+		t.Fatalf("encryptWithRandomness returned error: %v", err)
+	}
+	if !got.Equal(expected) {
+		t.Errorf("Expected ciphertext %+v; got %+v", expected, got)
+	}
+
+	ctxt, err := encryptor.Enc(msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+	decShares, err := DecAll(pub, shares[:3], ctxt)
+	if err != nil {
+		t.Fatalf("DecAll returned error: %v", err)
+	}
+	recovered, err := Recover(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if string(recovered) != string(msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recovered)
+	}
+
+	_, err = encryptor.Enc(make([]byte, 63))
+	if err == nil {
+		t.Errorf("Expected error with message of wrong length; got none")
+	}
+
+	_, err = NewEncryptor(PublicKey{SchnorrGroup: pub.SchnorrGroup})
+	if err == nil {
+		t.Errorf("Expected error with public key missing y; got none")
+	}
+}
+
+func benchmarkEncryptorSetup(b *testing.B) (PublicKey, [][]byte) {
+	pub := newBenchmarkFixture(b, 1024, 256, 3, 5).pub
+
+	return pub, benchmarkMessages(pub, 1000)
+}
+
+func BenchmarkEnc1000(b *testing.B) {
+	pub, messages := benchmarkEncryptorSetup(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, message := range messages {
+			_, err := Enc(pub, message)
+			if err != nil {
+				b.Fatalf("Enc returned error: %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkEncryptor1000(b *testing.B) {
+	pub, messages := benchmarkEncryptorSetup(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		// Including precomputation
+		encryptor, err := NewEncryptor(pub)
+		if err != nil {
+			b.Fatalf("NewEncryptor returned error: %v", err)
+		}
+		for _, message := range messages {
+			_, err := encryptor.Enc(message)
+			if err != nil {
+				b.Fatalf("Enc returned error: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package elgamal
+
+import (
+	"fmt"
+	"github.com/lavode/secret-sharing/gf"
+	"math/big"
+)
+
+// DecryptionProof represents a non-interactive Chaum-Pedersen proof that a
+// decryption share R^{x_i} was computed with the same exponent x_i as the
+// party's verification key g^{x_i}.
+type DecryptionProof struct {
+	// Challenge c, from (Z / qZ)
+	C *big.Int
+	// Response s = w - c * x_i mod q
+	S *big.Int
+}
+
+// DecWithProof creates a single decryption share of a ciphertext based on the
+// passed share of the private key, along with a proof of its correctness.
+//
+// The proof can be checked with VerifyDecryptionShare() against the party's
+// verification key, allowing to reject bogus shares before recovery.
+func DecWithProof(pub PublicKey, keyShare PrivateKeyShare, ctxt Ciphertext) (DecryptionShare, DecryptionProof, error) {
+	var proof DecryptionProof
+
+	share, err := Dec(pub, keyShare, ctxt)
+	if err != nil {
+		return share, proof, err
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return share, proof, err
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return share, proof, err
+	}
+
+	vk := zp.Exp(pub.G, keyShare.Value) // g^{x_i}
+
+	w, err := zq.Rand()
+	if err != nil {
+		return share, proof, err
+	}
+	a1 := zp.Exp(pub.G, w)  // g^w
+	a2 := zp.Exp(ctxt.R, w) // R^w
+
+	c, err := challenge(&pub, pub.G, ctxt.R, vk, share.Value, a1, a2)
+	if err != nil {
+		return share, proof, err
+	}
+
+	proof.C = c
+	proof.S = zq.Sub(w, zq.Mul(c, keyShare.Value)) // w - c * x_i
+
+	return share, proof, nil
+}
+
+// VerifyDecryptionShare checks whether the passed proof shows that the
+// decryption share of the ciphertext was computed using the private key
+// share belonging to the passed verification key.
+func VerifyDecryptionShare(pub PublicKey, verificationKey VerificationKey, ctxt Ciphertext, share DecryptionShare, proof DecryptionProof) bool {
+	if verificationKey.ID != share.ID {
+		return false
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return false
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return false
+	}
+
+	// All elements must be of order q. Otherwise, e.g. the negated share
+	// p - R^{x_i} passes with an honest proof for half of all challenges.
+	for _, elem := range []*big.Int{ctxt.R, verificationKey.Value, share.Value} {
+		if !isSubgroupElement(zp, pub.Q, elem) {
+			return false
+		}
+	}
+	for _, elem := range []*big.Int{proof.C, proof.S} {
+		if elem == nil || !zq.IsGroupElement(elem) {
+			return false
+		}
+	}
+
+	// g^s * (g^{x_i})^c = g^{w - c * x_i + c * x_i} = g^w
+	a1 := zp.Mul(zp.Exp(pub.G, proof.S), zp.Exp(verificationKey.Value, proof.C))
+	// R^s * (R^{x_i})^c = R^w
+	a2 := zp.Mul(zp.Exp(ctxt.R, proof.S), zp.Exp(share.Value, proof.C))
+
+	c, err := challenge(&pub, pub.G, ctxt.R, verificationKey.Value, share.Value, a1, a2)
+	if err != nil {
+		return false
+	}
+
+	return c.Cmp(proof.C) == 0
+}
+
+// isSubgroupElement checks whether x is an element of the subgroup of order
+// q of (Z/pZ)*, that is whether x is in [1, p) and x^q = 1 mod p.
+func isSubgroupElement(zp gf.GF, q *big.Int, x *big.Int) bool {
+	if x == nil || x.Sign() <= 0 || !zp.IsGroupElement(x) {
+		return false
+	}
+
+	return zp.Exp(x, q).Cmp(big.NewInt(1)) == 0
+}
+
+// ProvenDecryptionShare is a decryption share along with the proof of its
+// correctness, as returned by DecWithProof().
+type ProvenDecryptionShare struct {
+	Share DecryptionShare
+	Proof DecryptionProof
+}
+
+// RecoverWithProofs decrypts a ciphertext like Recover(), after discarding
+// all decryption shares whose proofs do not verify against the passed
+// verification keys, as per VerifyDecryptionShare(). Shares without a
+// matching verification key, as well as repeated shares of the same party,
+// are discarded too.
+//
+// This allows to recover the plaintext despite bogus shares, as long as a
+// threshold of the shares are valid. An error is returned if fewer than
+// pub.Threshold valid shares remain.
+func RecoverWithProofs(pub PublicKey, vkeys []VerificationKey, items []ProvenDecryptionShare, ctxt Ciphertext) ([]byte, error) {
+	byID := make(map[int]VerificationKey)
+	for _, vk := range vkeys {
+		byID[vk.ID] = vk
+	}
+
+	valid := make([]DecryptionShare, 0, len(items))
+	seen := make(map[int]bool)
+	for _, item := range items {
+		vk, ok := byID[item.Share.ID]
+		if !ok || seen[item.Share.ID] {
+			continue
+		}
+		if !VerifyDecryptionShare(pub, vk, ctxt, item.Share, item.Proof) {
+			continue
+		}
+
+		seen[item.Share.ID] = true
+		valid = append(valid, item.Share)
+	}
+
+	if len(valid) == 0 || len(valid) < pub.Threshold {
+		return nil, fmt.Errorf("%w: %d of %d decryption shares are valid; need %d", ErrThresholdNotMet, len(valid), len(items), pub.Threshold)
+	}
+
+	return Recover(pub, valid, ctxt)
+}
+
+// EncryptionProof represents a non-interactive Schnorr proof of knowledge of
+// the exponent r of a ciphertext's R = g^r.
+type EncryptionProof struct {
+	// Challenge c, from (Z / qZ)
+	C *big.Int
+	// Response s = w - c * r mod q
+	S *big.Int
+}
+
+// ProveEncryption creates a proof that the sender knows the exponent r of a
+// ciphertext's R = g^r, without revealing it. The exponent is as returned by
+// EncWithEphemeral().
+//
+// An error is returned if r is not in [1, q).
+func ProveEncryption(pub PublicKey, r *big.Int) (EncryptionProof, error) {
+	var proof EncryptionProof
+
+	if r == nil || r.Sign() <= 0 || r.Cmp(pub.Q) >= 0 {
+		return proof, fmt.Errorf("r must be in [1, q)")
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return proof, err
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return proof, err
+	}
+
+	R := zp.Exp(pub.G, r) // g^r
+
+	w, err := zq.Rand()
+	if err != nil {
+		return proof, err
+	}
+	a := zp.Exp(pub.G, w) // g^w
+
+	c, err := challenge(&pub, R, a)
+	if err != nil {
+		return proof, err
+	}
+
+	proof.C = c
+	proof.S = zq.Sub(w, zq.Mul(c, r)) // w - c * r
+
+	return proof, nil
+}
+
+// VerifyEncryption checks whether the passed proof shows knowledge of the
+// exponent r of the ciphertext's R = g^r.
+func VerifyEncryption(pub PublicKey, ctxt Ciphertext, proof EncryptionProof) bool {
+	zp, err := pub.Zp()
+	if err != nil {
+		return false
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return false
+	}
+
+	if !isSubgroupElement(zp, pub.Q, ctxt.R) {
+		return false
+	}
+	for _, elem := range []*big.Int{proof.C, proof.S} {
+		if elem == nil || !zq.IsGroupElement(elem) {
+			return false
+		}
+	}
+
+	// g^s * R^c = g^{w - c * r + c * r} = g^w
+	a := zp.Mul(zp.Exp(pub.G, proof.S), zp.Exp(ctxt.R, proof.C))
+
+	c, err := challenge(&pub, ctxt.R, a)
+	if err != nil {
+		return false
+	}
+
+	return c.Cmp(proof.C) == 0
+}
+
+// challenge derives a Fiat-Shamir challenge from (Z / qZ) by hashing the
+// group parameters and the passed elements, using the public key's hash
+// algorithm.
+func challenge(pub *PublicKey, elems ...*big.Int) (*big.Int, error) {
+	hash, err := pub.hashFunc()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	for _, elem := range append([]*big.Int{pub.P, pub.Q, pub.G}, elems...) {
+		if elem == nil {
+			return nil, fmt.Errorf("Cannot derive challenge from nil element")
+		}
+		data = appendLengthPrefixed(data, elem.Bytes())
+	}
+
+	h := hash.New()
+	h.Write(data)
+
+	c := new(big.Int).SetBytes(h.Sum(nil))
+	return c.Mod(c, pub.Q), nil
+}
@@ -0,0 +1,270 @@
+package elgamal
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestDecWithProof(t *testing.T) {
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+
+	pub, _, privShares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	for i, keyShare := range privShares {
+		share, proof, err := DecWithProof(pub, keyShare, ctxt)
+		if err != nil {
+			t.Fatalf("DecWithProof returned error: %v", err)
+		}
+
+		vk := pub.VerificationKeys[i]
+		if !VerifyDecryptionShare(pub, vk, ctxt, share, proof) {
+			t.Errorf("Expected proof of share %d to verify; it did not", share.ID)
+		}
+
+		// Tampered share value
+		tampered := DecryptionShare{ID: share.ID, Value: new(big.Int).Mul(share.Value, pub.G)}
+		tampered.Value.Mod(tampered.Value, pub.P)
+		if VerifyDecryptionShare(pub, vk, ctxt, tampered, proof) {
+			t.Errorf("Expected proof of tampered share %d not to verify; it did", share.ID)
+		}
+
+		// Tampered proof
+		tamperedProof := DecryptionProof{C: proof.C, S: new(big.Int).Add(proof.S, big.NewInt(1))}
+		if VerifyDecryptionShare(pub, vk, ctxt, share, tamperedProof) {
+			t.Errorf("Expected tampered proof of share %d not to verify; it did", share.ID)
+		}
+
+		// Verification key of a different party
+		otherVK := pub.VerificationKeys[(i+1)%len(pub.VerificationKeys)]
+		otherVK.ID = share.ID
+		if VerifyDecryptionShare(pub, otherVK, ctxt, share, proof) {
+			t.Errorf("Expected proof of share %d not to verify against other verification key; it did", share.ID)
+		}
+	}
+
+	// Proof without any values
+	share, _, err := DecWithProof(pub, privShares[0], ctxt)
+	if err != nil {
+		t.Fatalf("DecWithProof returned error: %v", err)
+	}
+	if VerifyDecryptionShare(pub, pub.VerificationKeys[0], ctxt, share, DecryptionProof{}) {
+		t.Errorf("Expected empty proof not to verify; it did")
+	}
+}
+
+func TestVerifyDecryptionShareNegated(t *testing.T) {
+	pub, _, privShares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	ctxt, err := Enc(pub, make([]byte, 64))
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	zp, _ := pub.Zp()
+	zq, _ := pub.Zq()
+	keyShare := privShares[0]
+	vk := pub.VerificationKeys[0]
+
+	// The negated share -R^{x_i} is not of order q. With a2 = -(R^w), the
+	// verifier's R^s * (-R^{x_i})^c = (-1)^c * R^w matches a2 for odd c.
+	honest := zp.Exp(ctxt.R, keyShare.Value)
+	bogus := DecryptionShare{ID: keyShare.ID, Value: new(big.Int).Sub(pub.P, honest)}
+
+	var proof DecryptionProof
+	for {
+		w, err := zq.Rand()
+		if err != nil {
+			t.Fatalf("Rand returned error: %v", err)
+		}
+		a1 := zp.Exp(pub.G, w)
+		a2 := new(big.Int).Sub(pub.P, zp.Exp(ctxt.R, w))
+
+		c, err := challenge(&pub, pub.G, ctxt.R, vk.Value, bogus.Value, a1, a2)
+		if err != nil {
+			t.Fatalf("challenge returned error: %v", err)
+		}
+		if c.Bit(0) == 1 {
+			proof = DecryptionProof{C: c, S: zq.Sub(w, zq.Mul(c, keyShare.Value))}
+			break
+		}
+	}
+
+	if VerifyDecryptionShare(pub, vk, ctxt, bogus, proof) {
+		t.Errorf("Expected forged proof of negated share not to verify; it did")
+	}
+
+	items := []ProvenDecryptionShare{{Share: bogus, Proof: proof}}
+	for _, keyShare := range privShares[1:3] {
+		share, proof, err := DecWithProof(pub, keyShare, ctxt)
+		if err != nil {
+			t.Fatalf("DecWithProof returned error: %v", err)
+		}
+		items = append(items, ProvenDecryptionShare{Share: share, Proof: proof})
+	}
+	_, err = RecoverWithProofs(pub, pub.VerificationKeys, items, ctxt)
+	if !errors.Is(err, ErrThresholdNotMet) {
+		t.Errorf("Expected ErrThresholdNotMet with forged share; got %v", err)
+	}
+}
+
+func TestProveEncryption(t *testing.T) {
+	pub, _, _, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	ctxt, r, err := EncWithEphemeral(pub, make([]byte, 64))
+	if err != nil {
+		t.Fatalf("EncWithEphemeral returned error: %v", err)
+	}
+
+	proof, err := ProveEncryption(pub, r)
+	if err != nil {
+		t.Fatalf("ProveEncryption returned error: %v", err)
+	}
+
+	if !VerifyEncryption(pub, ctxt, proof) {
+		t.Errorf("Expected valid encryption proof to verify")
+	}
+
+	// Tampered R
+	tampered := ctxt.Clone()
+	tampered.R = new(big.Int).Mul(ctxt.R, pub.G)
+	tampered.R.Mod(tampered.R, pub.P)
+	if VerifyEncryption(pub, tampered, proof) {
+		t.Errorf("Expected proof not to verify for tampered R")
+	}
+
+	// Tampered response
+	tamperedProof := proof
+	tamperedProof.S = new(big.Int).Add(proof.S, big.NewInt(1))
+	tamperedProof.S.Mod(tamperedProof.S, pub.Q)
+	if VerifyEncryption(pub, ctxt, tamperedProof) {
+		t.Errorf("Expected proof with tampered response not to verify")
+	}
+
+	// Tampered challenge
+	tamperedProof = proof
+	tamperedProof.C = new(big.Int).Add(proof.C, big.NewInt(1))
+	tamperedProof.C.Mod(tamperedProof.C, pub.Q)
+	if VerifyEncryption(pub, ctxt, tamperedProof) {
+		t.Errorf("Expected proof with tampered challenge not to verify")
+	}
+
+	// Missing values
+	if VerifyEncryption(pub, ctxt, EncryptionProof{}) {
+		t.Errorf("Expected empty proof not to verify")
+	}
+
+	for _, invalid := range []*big.Int{nil, big.NewInt(0), pub.Q} {
+		_, err = ProveEncryption(pub, invalid)
+		if err == nil {
+			t.Errorf("Expected error with r = %v; got none", invalid)
+		}
+	}
+}
+
+func TestVerifyEncryptionNegated(t *testing.T) {
+	pub, _, _, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	zp, _ := pub.Zp()
+	zq, _ := pub.Zq()
+	r, err := zq.Rand()
+	if err != nil {
+		t.Fatalf("Rand returned error: %v", err)
+	}
+
+	// The negated R = -g^r is not of order q. The verifier's
+	// g^s * (-g^r)^c = (-1)^c * g^w matches a = g^w for even c.
+	bogus := Ciphertext{R: new(big.Int).Sub(pub.P, zp.Exp(pub.G, r)), C: make([]byte, 64)}
+
+	var proof EncryptionProof
+	for {
+		w, err := zq.Rand()
+		if err != nil {
+			t.Fatalf("Rand returned error: %v", err)
+		}
+
+		c, err := challenge(&pub, bogus.R, zp.Exp(pub.G, w))
+		if err != nil {
+			t.Fatalf("challenge returned error: %v", err)
+		}
+		if c.Bit(0) == 0 {
+			proof = EncryptionProof{C: c, S: zq.Sub(w, zq.Mul(c, r))}
+			break
+		}
+	}
+
+	if VerifyEncryption(pub, bogus, proof) {
+		t.Errorf("Expected forged proof of negated R not to verify; it did")
+	}
+}
+
+func TestRecoverWithProofs(t *testing.T) {
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+
+	pub, _, privShares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	items := make([]ProvenDecryptionShare, 4)
+	for i := range items {
+		items[i].Share, items[i].Proof, err = DecWithProof(pub, privShares[i], ctxt)
+		if err != nil {
+			t.Fatalf("DecWithProof returned error: %v", err)
+		}
+	}
+
+	// Bogus share without proof, which would break Recover()
+	items[0] = ProvenDecryptionShare{Share: DecryptionShare{ID: 1, Value: big.NewInt(2)}}
+
+	recovered, err := RecoverWithProofs(pub, pub.VerificationKeys, items, ctxt)
+	if err != nil {
+		t.Fatalf("RecoverWithProofs returned error: %v", err)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recovered)
+	}
+
+	// Repeating a valid share does not help reaching the threshold
+	_, err = RecoverWithProofs(pub, pub.VerificationKeys, []ProvenDecryptionShare{items[1], items[1], items[2]}, ctxt)
+	if !errors.Is(err, ErrThresholdNotMet) {
+		t.Errorf("Expected ErrThresholdNotMet with repeated share; got %v", err)
+	}
+
+	// Two bogus shares leave fewer than t valid ones
+	items[1].Proof = DecryptionProof{}
+	_, err = RecoverWithProofs(pub, pub.VerificationKeys, items, ctxt)
+	if !errors.Is(err, ErrThresholdNotMet) {
+		t.Errorf("Expected ErrThresholdNotMet with 2 valid shares; got %v", err)
+	}
+
+	// Shares without verification key are discarded
+	_, err = RecoverWithProofs(pub, nil, items[2:], ctxt)
+	if !errors.Is(err, ErrThresholdNotMet) {
+		t.Errorf("Expected ErrThresholdNotMet without verification keys; got %v", err)
+	}
+}
@@ -0,0 +1,381 @@
+package elgamal
+
+import (
+	"fmt"
+	"github.com/lavode/secret-sharing/gf"
+	"math/big"
+)
+
+// RefreshShares re-randomizes the private key shares of all n parties, such
+// that they still reconstruct the same private key x, but are otherwise
+// independent of the old shares. An attacker who compromised fewer than t
+// parties before, and fewer than t parties after the refresh, thus cannot
+// combine their shares.
+//
+// This simulates proactive secret sharing, where every party contributes a
+// random polynomial of degree t-1 with a constant term of 0, evaluated at
+// the IDs of all parties, and every party adds the sub-shares it received to
+// its share.
+//
+// Parameters:
+// - pub: Public key the shares belong to
+// - shares: Private key shares of all n parties
+// - t: Number of shares required to reconstruct the private key
+// - n: Number of total shares
+// - zq: Finite field (Z/qZ) over which the shares are defined
+//
+// Mind that the verification keys and commitments of the public key no
+// longer match the refreshed shares. An error is returned if t < 1, t > n,
+// if not exactly n shares with distinct IDs are passed, or if zq does not
+// match the public key.
+func RefreshShares(pub PublicKey, shares []PrivateKeyShare, t int, n int, zq gf.GF) ([]PrivateKeyShare, error) {
+	if t < 1 {
+		return nil, fmt.Errorf("t must be >= 1; got %d", t)
+	}
+	if t > n {
+		return nil, fmt.Errorf("t must be <= n; got t = %d, n = %d", t, n)
+	}
+	if len(shares) != n {
+		return nil, fmt.Errorf("Need all %d shares; got %d", n, len(shares))
+	}
+	if pub.Q != nil && zq.P.Cmp(pub.Q) != 0 {
+		return nil, fmt.Errorf("Field must be (Z/qZ) of the public key")
+	}
+	err := checkDistinctIDs(shares)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshed := make([]PrivateKeyShare, n)
+	for i, share := range shares {
+		refreshed[i] = PrivateKeyShare{ID: share.ID, Value: new(big.Int).Set(share.Value)}
+	}
+
+	for range shares {
+		poly, err := zq.RandomPolynomial(t - 1)
+		if err != nil {
+			return nil, err
+		}
+		// Sharing 0 leaves the private key unchanged
+		poly.Coefficients[0] = big.NewInt(0)
+
+		for i := range refreshed {
+			subShare, err := poly.Evaluate(big.NewInt(int64(refreshed[i].ID)))
+			if err != nil {
+				return nil, err
+			}
+
+			refreshed[i].Value = zq.Add(refreshed[i].Value, subShare)
+		}
+	}
+
+	return refreshed, nil
+}
+
+// Redistribute reshares the private key under a new (newT, newN) threshold
+// policy, without ever reconstructing it.
+//
+// oldT of the old shares are used. Each of their holders splits their share
+// under the new policy, and every recipient combines the sub-shares they
+// received using the Lagrange coefficients of the old holders. The new
+// shares have IDs 1 to newN, and reconstruct the same private key x.
+//
+// Parameters:
+// - oldShares: At least oldT shares under the old policy
+// - oldT: Number of shares required to reconstruct the private key under the old policy
+// - newT: Number of shares required to reconstruct the private key under the new policy
+// - newN: Number of total shares under the new policy
+// - zq: Finite field (Z/qZ) over which the shares are defined
+//
+// An error is returned if oldT < 1, newT < 1, newT > newN, or if fewer than
+// oldT shares with distinct IDs are passed. Mind that the threshold,
+// verification keys and commitments of the public key must be updated by the
+// caller.
+func Redistribute(oldShares []PrivateKeyShare, oldT int, newT int, newN int, zq gf.GF) ([]PrivateKeyShare, error) {
+	if oldT < 1 {
+		return nil, fmt.Errorf("oldT must be >= 1; got %d", oldT)
+	}
+	if newT < 1 {
+		return nil, fmt.Errorf("newT must be >= 1; got %d", newT)
+	}
+	if newT > newN {
+		return nil, fmt.Errorf("newT must be <= newN; got newT = %d, newN = %d", newT, newN)
+	}
+	if len(oldShares) < oldT {
+		return nil, fmt.Errorf("%w: need at least %d old shares; got %d", ErrThresholdNotMet, oldT, len(oldShares))
+	}
+
+	holders := oldShares[:oldT]
+	err := checkDistinctIDs(holders)
+	if err != nil {
+		return nil, err
+	}
+
+	xs := make([]*big.Int, len(holders))
+	for i, holder := range holders {
+		xs[i] = big.NewInt(int64(holder.ID))
+	}
+
+	newShares := make([]PrivateKeyShare, newN)
+	for j := range newShares {
+		newShares[j] = PrivateKeyShare{ID: j + 1, Value: big.NewInt(0)}
+	}
+
+	for i, holder := range holders {
+		// Sharing x_i under the new policy
+		poly, err := zq.RandomPolynomial(newT - 1)
+		if err != nil {
+			return nil, err
+		}
+		poly.Coefficients[0] = new(big.Int).Set(holder.Value)
+
+		// Lagrange coefficient of the holder, such that
+		// x = sum(lambda_i * x_i)
+		lambda := gf.BasePolynomial(i, xs, zq)
+
+		for j := range newShares {
+			subShare, err := poly.Evaluate(big.NewInt(int64(newShares[j].ID)))
+			if err != nil {
+				return nil, err
+			}
+
+			newShares[j].Value = zq.Add(newShares[j].Value, zq.Mul(lambda, subShare))
+		}
+	}
+
+	return newShares, nil
+}
+
+// checkDistinctIDs returns an error if any two of the passed shares have the
+// same ID, or if any ID is not positive.
+func checkDistinctIDs(shares []PrivateKeyShare) error {
+	seen := make(map[int]bool)
+	for _, share := range shares {
+		if share.ID <= 0 {
+			return fmt.Errorf("Share IDs must be positive; got %d", share.ID)
+		}
+		if seen[share.ID] {
+			return fmt.Errorf("%w: ID %d", ErrDuplicateShare, share.ID)
+		}
+		seen[share.ID] = true
+	}
+
+	return nil
+}
+
+// IssueReplacementShare computes a fresh private key share at newID on the
+// same sharing polynomial as the passed helper shares, e.g. to replace a
+// party whose share was lost. As the new share lies on the same polynomial,
+// it is compatible with all existing shares, and its verification key
+// g^{x_new} can be checked against the commitments of the public key.
+//
+// The new share is x_new = sum(lambda_i(newID) * x_i), with lambda_i(newID)
+// the Lagrange coefficient of helper i evaluated at newID. This runs all
+// helpers in one process, using ReplacementMasks(),
+// ReplacementContributionOf() and CombineReplacementShare(). Helpers which
+// must not reveal their shares to the replacement party instead run these
+// steps themselves, such that only blinded contributions are ever sent.
+//
+// An error is returned if fewer than t helper shares are passed, if their IDs
+// are not distinct and positive, if newID is not positive or is the ID of a
+// helper, or if the fields of the public key cannot be constructed.
+func IssueReplacementShare(pub PublicKey, helpers []PrivateKeyShare, newID int) (PrivateKeyShare, error) {
+	if len(helpers) < pub.Threshold {
+		return PrivateKeyShare{}, fmt.Errorf("%w: need at least %d helper shares; got %d", ErrThresholdNotMet, pub.Threshold, len(helpers))
+	}
+	err := checkDistinctIDs(helpers)
+	if err != nil {
+		return PrivateKeyShare{}, err
+	}
+
+	helperIDs := make([]int, len(helpers))
+	for i, helper := range helpers {
+		helperIDs[i] = helper.ID
+	}
+
+	masks, err := ReplacementMasks(pub, len(helpers))
+	if err != nil {
+		return PrivateKeyShare{}, err
+	}
+
+	contributions := make([]ReplacementContribution, len(helpers))
+	for i, helper := range helpers {
+		contributions[i], err = ReplacementContributionOf(pub, helper, helperIDs, newID, masks[i])
+		if err != nil {
+			return PrivateKeyShare{}, err
+		}
+	}
+
+	return CombineReplacementShare(pub, contributions)
+}
+
+// ReplacementContribution is a helper's blinded contribution
+// lambda_i(newID) * x_i + m_i towards a replacement share, as returned by
+// ReplacementContributionOf().
+type ReplacementContribution struct {
+	// ID of the helper
+	HelperID int
+	// ID of the replacement share
+	NewID int
+	// Blinded contribution, from (Z/qZ)
+	Value *big.Int
+}
+
+// ReplacementMasks returns n random masks from (Z/qZ) summing to zero, one
+// for each of n helpers issuing a replacement share.
+//
+// In a distributed setting, no single party may know all masks. Instead,
+// every pair of helpers i < j agrees on a random m_ij, and helper i uses the
+// sum of m_ij over all j > i, minus the sum of m_ji over all j < i, as its
+// mask.
+//
+// An error is returned if n < 1, or if the fields of the public key cannot
+// be constructed.
+func ReplacementMasks(pub PublicKey, n int) ([]*big.Int, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("Need at least one helper; got %d", n)
+	}
+
+	zq, err := pub.Zq()
+	if err != nil {
+		return nil, err
+	}
+
+	masks := make([]*big.Int, n)
+	sum := big.NewInt(0)
+	for i := 0; i < n-1; i++ {
+		masks[i], err = randomInt(nil, zq.P)
+		if err != nil {
+			return nil, err
+		}
+		sum = zq.Add(sum, masks[i])
+	}
+	masks[n-1] = zq.Sub(big.NewInt(0), sum)
+
+	return masks, nil
+}
+
+// ReplacementContributionOf computes a helper's blinded contribution
+// lambda_i(newID) * x_i + mask towards the replacement share at newID, with
+// lambda_i(newID) the Lagrange coefficient of the helper among all helpers,
+// evaluated at newID.
+//
+// As the masks of all helpers sum to zero, the contributions sum to the
+// replacement share, while each one on its own reveals nothing about the
+// helper's share.
+//
+// An error is returned if fewer than t helper IDs are passed, if they are not
+// distinct and positive or do not include the helper's ID, if newID is not
+// positive or is the ID of a helper, if the helper's share or the mask are
+// not in (Z/qZ), or if the fields of the public key cannot be constructed.
+func ReplacementContributionOf(pub PublicKey, helper PrivateKeyShare, helperIDs []int, newID int, mask *big.Int) (ReplacementContribution, error) {
+	if len(helperIDs) < pub.Threshold {
+		return ReplacementContribution{}, fmt.Errorf("%w: need at least %d helpers; got %d", ErrThresholdNotMet, pub.Threshold, len(helperIDs))
+	}
+	if newID <= 0 {
+		return ReplacementContribution{}, fmt.Errorf("New share ID must be positive; got %d", newID)
+	}
+
+	zq, err := pub.Zq()
+	if err != nil {
+		return ReplacementContribution{}, err
+	}
+	if helper.Value == nil || !zq.IsGroupElement(helper.Value) {
+		return ReplacementContribution{}, fmt.Errorf("Helper share %d must be in [0, q)", helper.ID)
+	}
+	if mask == nil || !zq.IsGroupElement(mask) {
+		return ReplacementContribution{}, fmt.Errorf("Mask must be in [0, q)")
+	}
+
+	index := -1
+	seen := make(map[int]bool)
+	xs := make([]*big.Int, len(helperIDs))
+	for i, id := range helperIDs {
+		if id <= 0 {
+			return ReplacementContribution{}, fmt.Errorf("Share IDs must be positive; got %d", id)
+		}
+		if seen[id] {
+			return ReplacementContribution{}, fmt.Errorf("%w: ID %d", ErrDuplicateShare, id)
+		}
+		seen[id] = true
+		if id == newID {
+			return ReplacementContribution{}, fmt.Errorf("New share ID %d is already held by a helper", newID)
+		}
+		if id == helper.ID {
+			index = i
+		}
+		xs[i] = big.NewInt(int64(id))
+	}
+	if index < 0 {
+		return ReplacementContribution{}, fmt.Errorf("Helper %d is not among the helpers", helper.ID)
+	}
+
+	lambda := lagrangeAt(index, xs, big.NewInt(int64(newID)), zq)
+
+	return ReplacementContribution{
+		HelperID: helper.ID,
+		NewID:    newID,
+		Value:    zq.Add(zq.Mul(lambda, helper.Value), mask),
+	}, nil
+}
+
+// CombineReplacementShare sums the blinded contributions of all helpers, as
+// returned by ReplacementContributionOf(), to the replacement share.
+//
+// An error is returned if fewer than t contributions are passed, if they are
+// for different new IDs, if any two are from the same helper, if any value
+// is not in (Z/qZ), or if the fields of the public key cannot be constructed.
+// Mind that contributions of a helper which used a wrong mask or share are
+// not detected here, but by checking the resulting share using
+// VerifyKeyShare().
+func CombineReplacementShare(pub PublicKey, contributions []ReplacementContribution) (PrivateKeyShare, error) {
+	if len(contributions) < pub.Threshold {
+		return PrivateKeyShare{}, fmt.Errorf("%w: need at least %d contributions; got %d", ErrThresholdNotMet, pub.Threshold, len(contributions))
+	}
+	if len(contributions) == 0 {
+		return PrivateKeyShare{}, fmt.Errorf("Need at least one contribution")
+	}
+
+	zq, err := pub.Zq()
+	if err != nil {
+		return PrivateKeyShare{}, err
+	}
+
+	newID := contributions[0].NewID
+	seen := make(map[int]bool)
+	value := big.NewInt(0)
+	for _, contribution := range contributions {
+		if contribution.NewID != newID {
+			return PrivateKeyShare{}, fmt.Errorf("Contributions are for new IDs %d and %d", newID, contribution.NewID)
+		}
+		if seen[contribution.HelperID] {
+			return PrivateKeyShare{}, fmt.Errorf("%w: helper %d", ErrDuplicateShare, contribution.HelperID)
+		}
+		seen[contribution.HelperID] = true
+		if contribution.Value == nil || !zq.IsGroupElement(contribution.Value) {
+			return PrivateKeyShare{}, fmt.Errorf("Contribution of helper %d must be in [0, q)", contribution.HelperID)
+		}
+
+		value = zq.Add(value, contribution.Value)
+	}
+
+	return PrivateKeyShare{ID: newID, Value: value}, nil
+}
+
+// lagrangeAt evaluates the i-th Lagrange base polynomial of the points xs at
+// x, that is prod_{j != i} (x - x_j) / (x_i - x_j) over the passed field.
+// gf.BasePolynomial() is the special case of x = 0.
+func lagrangeAt(i int, xs []*big.Int, x *big.Int, field gf.GF) *big.Int {
+	num := big.NewInt(1)
+	den := big.NewInt(1)
+	for j, xj := range xs {
+		if j == i {
+			continue
+		}
+
+		num = field.Mul(num, field.Sub(x, xj))
+		den = field.Mul(den, field.Sub(xs[i], xj))
+	}
+
+	return field.Div(num, den)
+}
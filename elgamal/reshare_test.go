@@ -0,0 +1,326 @@
+package elgamal
+
+import (
+	"bytes"
+	"github.com/lavode/secret-sharing/secretshare"
+	"math/big"
+	"testing"
+)
+
+func TestRefreshShares(t *testing.T) {
+	pub, priv, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		t.Fatalf("Error creating field: %v", err)
+	}
+
+	oldValue := new(big.Int).Set(shares[0].Value)
+	refreshed, err := RefreshShares(pub, shares, 3, 5, zq)
+	if err != nil {
+		t.Fatalf("RefreshShares returned error: %v", err)
+	}
+	if len(refreshed) != len(shares) {
+		t.Fatalf("Expected %d refreshed shares; got %d", len(shares), len(refreshed))
+	}
+
+	for i := range shares {
+		if refreshed[i].ID != shares[i].ID {
+			t.Errorf("Expected refreshed share to have ID %d; got %d", shares[i].ID, refreshed[i].ID)
+		}
+		if refreshed[i].Value.Cmp(shares[i].Value) == 0 {
+			t.Errorf("Expected refreshed share %d to differ from old share", refreshed[i].ID)
+		}
+	}
+
+	for _, subset := range combinations(len(refreshed), 3) {
+		recoverShares := make([]secretshare.Share, len(subset))
+		for i, idx := range subset {
+			recoverShares[i] = secretshare.Share(refreshed[idx])
+		}
+
+		x, err := secretshare.TOutOfNRecover(recoverShares, zq)
+		if err != nil {
+			t.Fatalf("Error recovering private key: %v", err)
+		}
+		if x.Cmp(priv.X) != 0 {
+			t.Errorf("Expected refreshed shares %v to recover private key %d; got %d", subset, priv.X, x)
+		}
+	}
+
+	// Mixing old and new shares must not recover the private key
+	mixed := []secretshare.Share{
+		secretshare.Share(shares[0]),
+		secretshare.Share(shares[1]),
+		secretshare.Share(refreshed[2]),
+	}
+	x, err := secretshare.TOutOfNRecover(mixed, zq)
+	if err != nil {
+		t.Fatalf("Error recovering private key: %v", err)
+	}
+	if x.Cmp(priv.X) == 0 {
+		t.Errorf("Expected mix of old and refreshed shares not to recover private key")
+	}
+
+	// Old shares must be left untouched
+	if shares[0].Value.Cmp(oldValue) != 0 {
+		t.Errorf("Expected old shares not to be modified")
+	}
+
+	_, err = RefreshShares(pub, shares[:4], 3, 5, zq)
+	if err == nil {
+		t.Errorf("Expected error with fewer than n shares; got none")
+	}
+
+	duplicate := append([]PrivateKeyShare{}, shares...)
+	duplicate[1] = PrivateKeyShare{ID: shares[0].ID, Value: big.NewInt(1)}
+	_, err = RefreshShares(pub, duplicate, 3, 5, zq)
+	if err == nil {
+		t.Errorf("Expected error with duplicate shares; got none")
+	}
+
+	_, err = RefreshShares(pub, shares, 6, 5, zq)
+	if err == nil {
+		t.Errorf("Expected error with t > n; got none")
+	}
+}
+
+func TestRedistribute(t *testing.T) {
+	pub, priv, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		t.Fatalf("Error creating field: %v", err)
+	}
+
+	newShares, err := Redistribute([]PrivateKeyShare{shares[4], shares[1], shares[2]}, 3, 4, 7, zq)
+	if err != nil {
+		t.Fatalf("Redistribute returned error: %v", err)
+	}
+	if len(newShares) != 7 {
+		t.Fatalf("Expected 7 new shares; got %d", len(newShares))
+	}
+
+	for _, subset := range combinations(len(newShares), 4) {
+		recoverShares := make([]secretshare.Share, len(subset))
+		for i, idx := range subset {
+			recoverShares[i] = secretshare.Share(newShares[idx])
+		}
+
+		x, err := secretshare.TOutOfNRecover(recoverShares, zq)
+		if err != nil {
+			t.Fatalf("Error recovering private key: %v", err)
+		}
+		if x.Cmp(priv.X) != 0 {
+			t.Errorf("Expected new shares %v to recover private key %d; got %d", subset, priv.X, x)
+		}
+	}
+
+	// Three shares no longer suffice
+	recoverShares := []secretshare.Share{
+		secretshare.Share(newShares[0]),
+		secretshare.Share(newShares[3]),
+		secretshare.Share(newShares[6]),
+	}
+	x, err := secretshare.TOutOfNRecover(recoverShares, zq)
+	if err != nil {
+		t.Fatalf("Error recovering private key: %v", err)
+	}
+	if x.Cmp(priv.X) == 0 {
+		t.Errorf("Expected 3 new shares not to recover private key")
+	}
+
+	_, err = Redistribute(shares[:2], 3, 4, 7, zq)
+	if err == nil {
+		t.Errorf("Expected error with fewer than oldT shares; got none")
+	}
+
+	_, err = Redistribute(shares, 3, 8, 7, zq)
+	if err == nil {
+		t.Errorf("Expected error with newT > newN; got none")
+	}
+
+	_, err = Redistribute([]PrivateKeyShare{shares[0], shares[0], shares[1]}, 3, 4, 7, zq)
+	if err == nil {
+		t.Errorf("Expected error with duplicate shares; got none")
+	}
+}
+
+func TestIssueReplacementShare(t *testing.T) {
+	pub, priv, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	// Share with ID 2 is lost, and reissued at ID 6
+	helpers := []PrivateKeyShare{shares[0], shares[2], shares[4]}
+	replacement, err := IssueReplacementShare(pub, helpers, 6)
+	if err != nil {
+		t.Fatalf("IssueReplacementShare returned error: %v", err)
+	}
+	if replacement.ID != 6 {
+		t.Errorf("Expected replacement share with ID 6; got %d", replacement.ID)
+	}
+
+	// The replacement share lies on the original polynomial
+	ok, err := VerifyKeyShare(pub, pub.Commitments, replacement)
+	if err != nil {
+		t.Fatalf("VerifyKeyShare returned error: %v", err)
+	}
+	if !ok {
+		t.Errorf("Expected replacement share to verify against commitments")
+	}
+
+	// Reissuing the lost share at its old ID yields the same share
+	reissued, err := IssueReplacementShare(pub, helpers, 2)
+	if err != nil {
+		t.Fatalf("IssueReplacementShare returned error: %v", err)
+	}
+	if reissued.Value.Cmp(shares[1].Value) != 0 {
+		t.Errorf("Expected reissued share %d; got %d", shares[1].Value, reissued.Value)
+	}
+
+	msg, err := PadMessage([]byte("Hello world"))
+	if err != nil {
+		t.Fatalf("PadMessage returned error: %v", err)
+	}
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+	decShares, err := DecAll(pub, []PrivateKeyShare{replacement, shares[3], shares[0]}, ctxt)
+	if err != nil {
+		t.Fatalf("DecAll returned error: %v", err)
+	}
+	recovered, err := Recover(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recovered)
+	}
+
+	key, err := RecoverPrivateKey(pub, []PrivateKeyShare{replacement, shares[3], shares[2]})
+	if err != nil {
+		t.Fatalf("RecoverPrivateKey returned error: %v", err)
+	}
+	if key.X.Cmp(priv.X) != 0 {
+		t.Errorf("Expected recovered private key %d; got %d", priv.X, key.X)
+	}
+
+	_, err = IssueReplacementShare(pub, helpers[:2], 6)
+	if err == nil {
+		t.Errorf("Expected error with fewer than t helpers; got none")
+	}
+	_, err = IssueReplacementShare(pub, helpers, 3)
+	if err == nil {
+		t.Errorf("Expected error with new ID held by a helper; got none")
+	}
+	_, err = IssueReplacementShare(pub, helpers, 0)
+	if err == nil {
+		t.Errorf("Expected error with new ID 0; got none")
+	}
+}
+
+func TestReplacementContributions(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		t.Fatalf("Error generating field: %v", err)
+	}
+
+	helpers := []PrivateKeyShare{shares[0], shares[2], shares[4]}
+	helperIDs := []int{1, 3, 5}
+
+	// Pairwise masks m_ij of helpers i < j
+	pairwise := make([][]*big.Int, len(helpers))
+	for i := range pairwise {
+		pairwise[i] = make([]*big.Int, len(helpers))
+		for j := i + 1; j < len(helpers); j++ {
+			pairwise[i][j], err = zq.Rand()
+			if err != nil {
+				t.Fatalf("Rand returned error: %v", err)
+			}
+		}
+	}
+
+	contributions := make([]ReplacementContribution, len(helpers))
+	for i, helper := range helpers {
+		mask := big.NewInt(0)
+		for j := range helpers {
+			if j > i {
+				mask = zq.Add(mask, pairwise[i][j])
+			} else if j < i {
+				mask = zq.Sub(mask, pairwise[j][i])
+			}
+		}
+
+		contributions[i], err = ReplacementContributionOf(pub, helper, helperIDs, 2, mask)
+		if err != nil {
+			t.Fatalf("ReplacementContributionOf returned error: %v", err)
+		}
+
+		unblinded, err := ReplacementContributionOf(pub, helper, helperIDs, 2, big.NewInt(0))
+		if err != nil {
+			t.Fatalf("ReplacementContributionOf returned error: %v", err)
+		}
+		if contributions[i].Value.Cmp(unblinded.Value) == 0 {
+			t.Errorf("Expected contribution of helper %d to be blinded", helper.ID)
+		}
+	}
+
+	replacement, err := CombineReplacementShare(pub, contributions)
+	if err != nil {
+		t.Fatalf("CombineReplacementShare returned error: %v", err)
+	}
+	if replacement.ID != 2 || replacement.Value.Cmp(shares[1].Value) != 0 {
+		t.Errorf("Expected replacement share %+v; got %+v", shares[1], replacement)
+	}
+
+	masks, err := ReplacementMasks(pub, 4)
+	if err != nil {
+		t.Fatalf("ReplacementMasks returned error: %v", err)
+	}
+	sum := big.NewInt(0)
+	for _, mask := range masks {
+		sum = zq.Add(sum, mask)
+	}
+	if sum.Sign() != 0 {
+		t.Errorf("Expected masks to sum to 0; got %d", sum)
+	}
+
+	_, err = ReplacementContributionOf(pub, shares[1], helperIDs, 2, masks[0])
+	if err == nil {
+		t.Errorf("Expected error with helper not among helpers; got none")
+	}
+	_, err = ReplacementContributionOf(pub, helpers[0], []int{1, 1, 5}, 2, masks[0])
+	if err == nil {
+		t.Errorf("Expected error with duplicate helper IDs; got none")
+	}
+	_, err = ReplacementContributionOf(pub, helpers[0], helperIDs, 2, nil)
+	if err == nil {
+		t.Errorf("Expected error with nil mask; got none")
+	}
+
+	mismatched := append([]ReplacementContribution{}, contributions...)
+	mismatched[2].NewID = 6
+	_, err = CombineReplacementShare(pub, mismatched)
+	if err == nil {
+		t.Errorf("Expected error with contributions for different new IDs; got none")
+	}
+	_, err = CombineReplacementShare(pub, []ReplacementContribution{contributions[0], contributions[1], contributions[0]})
+	if err == nil {
+		t.Errorf("Expected error with duplicate contributions; got none")
+	}
+	_, err = CombineReplacementShare(pub, contributions[:2])
+	if err == nil {
+		t.Errorf("Expected error with fewer than t contributions; got none")
+	}
+}
@@ -0,0 +1,138 @@
+package elgamal
+
+import (
+	"crypto"
+	"fmt"
+	"io"
+)
+
+// Params bundles the configuration of the threshold ElGamal cryptosystem.
+type Params struct {
+	// Schnorr group over which the cryptosystem is defined
+	Group SchnorrGroup
+
+	// Hash algorithm used to derive the key stream. The zero value
+	// selects SHA512.
+	Hash crypto.Hash
+
+	// Source of randomness for key generation and encryption. If nil,
+	// crypto/rand's Reader is used.
+	Rand io.Reader
+
+	// Optional nonce tracker consulted on every encryption, to reject
+	// random exponents which were used before. If nil, nonces are not
+	// tracked.
+	Tracker NonceTracker
+}
+
+// Scheme is an instance of the threshold ElGamal cryptosystem with a fixed
+// configuration, as passed to NewScheme().
+//
+// Unlike KeyGen(), which generates a new Schnorr group for every key, all
+// keys generated by a scheme share its group.
+type Scheme struct {
+	params Params
+}
+
+// NewScheme creates a scheme with the passed configuration.
+//
+// An error is returned if the group is not valid as per Validate(), or if the
+// hash algorithm is not supported.
+func NewScheme(p Params) (*Scheme, error) {
+	err := p.Group.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	probe := PublicKey{Hash: p.Hash}
+	_, err = probe.hashFunc()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Scheme{params: p}, nil
+}
+
+// Params returns the configuration of the scheme.
+func (s *Scheme) Params() Params {
+	return s.params
+}
+
+// KeyGen generates a private key within the group of the scheme, and shares
+// it t-out-of-n, as per KeyGen(). The public key specifies the hash algorithm
+// of the scheme.
+//
+// An error is returned if t < 1, n < 1 or t > n.
+func (s *Scheme) KeyGen(t int, n int) (PublicKey, PrivateKey, []PrivateKeyShare, error) {
+	pub, priv, shares, err := keyGenInGroup(s.params.Rand, s.params.Group, t, n)
+	if err != nil {
+		return pub, priv, shares, err
+	}
+	pub.Hash = s.params.Hash
+
+	return pub, priv, shares, nil
+}
+
+// Enc encrypts a message as per Enc(), sourcing randomness from the scheme's
+// reader, and consulting the scheme's nonce tracker, if any.
+//
+// An error is returned if the public key does not belong to the scheme, if
+// encryption fails, or if the tracker rejects the nonce.
+func (s *Scheme) Enc(pub PublicKey, message []byte) (Ciphertext, error) {
+	err := s.checkKey(&pub)
+	if err != nil {
+		return Ciphertext{}, err
+	}
+
+	ctxt, _, err := encrypt(s.params.Rand, pub, message, nil, s.params.Tracker)
+	return ctxt, err
+}
+
+// Dec creates a single decryption share as per Dec().
+//
+// An error is returned if the public key does not belong to the scheme.
+func (s *Scheme) Dec(pub PublicKey, keyShare PrivateKeyShare, ctxt Ciphertext) (DecryptionShare, error) {
+	err := s.checkKey(&pub)
+	if err != nil {
+		return DecryptionShare{ID: keyShare.ID}, err
+	}
+
+	return Dec(pub, keyShare, ctxt)
+}
+
+// Recover decrypts a ciphertext using t decryption shares as per Recover().
+//
+// An error is returned if the public key does not belong to the scheme, or
+// if decryption fails.
+func (s *Scheme) Recover(pub PublicKey, decryptionShares []DecryptionShare, ctxt Ciphertext) ([]byte, error) {
+	err := s.checkKey(&pub)
+	if err != nil {
+		return nil, err
+	}
+
+	return Recover(pub, decryptionShares, ctxt)
+}
+
+// checkKey checks that the public key is of the group and hash algorithm of
+// the scheme.
+func (s *Scheme) checkKey(pub *PublicKey) error {
+	group := s.params.Group
+	if pub.P == nil || pub.Q == nil || pub.G == nil ||
+		pub.P.Cmp(group.P) != 0 || pub.Q.Cmp(group.Q) != 0 || pub.G.Cmp(group.G) != 0 {
+		return fmt.Errorf("Public key is not of the scheme's group")
+	}
+
+	// Comparing the resolved algorithms, as the zero value selects the
+	// default
+	probe := PublicKey{Hash: s.params.Hash}
+	want, _ := probe.hashFunc()
+	got, err := pub.hashFunc()
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("Public key uses hash algorithm %v; scheme uses %v", got, want)
+	}
+
+	return nil
+}
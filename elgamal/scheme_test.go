@@ -0,0 +1,119 @@
+package elgamal
+
+import (
+	"bytes"
+	"crypto"
+	"math/big"
+	"testing"
+)
+
+func TestScheme(t *testing.T) {
+	group, err := GenerateSchnorrGroup(512, 128)
+	if err != nil {
+		t.Fatalf("GenerateSchnorrGroup returned error: %v", err)
+	}
+
+	scheme, err := NewScheme(Params{Group: group, Hash: crypto.SHA256})
+	if err != nil {
+		t.Fatalf("NewScheme returned error: %v", err)
+	}
+
+	pub, _, shares, err := scheme.KeyGen(3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	if pub.P.Cmp(group.P) != 0 || pub.Q.Cmp(group.Q) != 0 || pub.G.Cmp(group.G) != 0 {
+		t.Errorf("Expected key in scheme's group %+v; got %+v", group, pub.SchnorrGroup)
+	}
+	if pub.Hash != crypto.SHA256 {
+		t.Errorf("Expected key to use SHA256; got %v", pub.Hash)
+	}
+
+	msg := make([]byte, 32)
+	copy(msg, []byte("Hello world"))
+	ctxt, err := scheme.Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	decShares := make([]DecryptionShare, 3)
+	for i, share := range shares[:3] {
+		decShares[i], err = scheme.Dec(pub, share, ctxt)
+		if err != nil {
+			t.Fatalf("Dec returned error: %v", err)
+		}
+	}
+
+	recovered, err := scheme.Recover(pub, decShares, ctxt)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, recovered)
+	}
+
+	// Keys of a different group or hash are rejected
+	other, _, _, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	_, err = scheme.Enc(other, make([]byte, 64))
+	if err == nil {
+		t.Errorf("Expected error with key of different group; got none")
+	}
+
+	otherHash := pub
+	otherHash.Hash = 0
+	_, err = scheme.Enc(otherHash, make([]byte, 64))
+	if err == nil {
+		t.Errorf("Expected error with key of different hash; got none")
+	}
+}
+
+func TestSchemeWithReader(t *testing.T) {
+	group := SchnorrGroup{
+		P: big.NewInt(23),
+		Q: big.NewInt(11),
+		G: big.NewInt(4),
+	}
+	pub := PublicKey{SchnorrGroup: group, Y: big.NewInt(16)}
+
+	// Exponents are drawn as 1 + [0, q-1), so a byte of 3 yields r = 4,
+	// as in TestEncWithReader.
+	scheme, err := NewScheme(Params{Group: group, Rand: bytes.NewReader([]byte{0x03})})
+	if err != nil {
+		t.Fatalf("NewScheme returned error: %v", err)
+	}
+
+	ctxt, err := scheme.Enc(pub, make([]byte, 64))
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+	if ctxt.R.Cmp(big.NewInt(3)) != 0 {
+		t.Errorf("Expected R = 3; got %d", ctxt.R)
+	}
+
+	// Reader is exhausted
+	_, err = scheme.Enc(pub, make([]byte, 64))
+	if err == nil {
+		t.Errorf("Expected error with exhausted reader; got none")
+	}
+}
+
+func TestNewSchemeInvalid(t *testing.T) {
+	group := SchnorrGroup{
+		P: big.NewInt(23),
+		Q: big.NewInt(11),
+		G: big.NewInt(4),
+	}
+
+	_, err := NewScheme(Params{Group: SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(11), G: big.NewInt(5)}})
+	if err == nil {
+		t.Errorf("Expected error with generator of wrong order; got none")
+	}
+
+	_, err = NewScheme(Params{Group: group, Hash: crypto.MD5})
+	if err == nil {
+		t.Errorf("Expected error with unsupported hash; got none")
+	}
+}
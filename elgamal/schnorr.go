@@ -1,9 +1,15 @@
 package elgamal
 
 import (
-	"crypto/rand"
+	"context"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"github.com/lavode/secret-sharing/gf"
+	"io"
 	"math/big"
+	"time"
 )
 
 // SchnorrGroup represents a q-order subgroup of the multiplicative group of
@@ -25,26 +31,140 @@ type SchnorrGroup struct {
 // error may also be returned if sourcing of cryptographically secure
 // randomness fails.
 func GenerateSchnorrGroup(pBits int, qBits int) (SchnorrGroup, error) {
+	return GenerateSchnorrGroupContext(context.Background(), pBits, qBits)
+}
+
+// GenerateSchnorrGroupContext is like GenerateSchnorrGroup(), but aborts the
+// search for p and g once ctx is done, returning ctx.Err().
+//
+// The context is checked once per candidate, so cancellation takes effect
+// after at most one primality test.
+func GenerateSchnorrGroupContext(ctx context.Context, pBits int, qBits int) (SchnorrGroup, error) {
+	return generateSchnorrGroup(ctx, nil, pBits, qBits, defaultPrimeChecks)
+}
+
+// GenerateSchnorrGroupWithin is like GenerateSchnorrGroup(), but gives up
+// once generation takes longer than d.
+//
+// If the deadline is exceeded, an error wrapping context.DeadlineExceeded is
+// returned. Generation happens on the calling goroutine and is aborted at the
+// next candidate, so no computation continues in the background after a
+// timeout.
+func GenerateSchnorrGroupWithin(pBits int, qBits int, d time.Duration) (SchnorrGroup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	schnorr, err := GenerateSchnorrGroupContext(ctx, pBits, qBits)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return schnorr, fmt.Errorf("Generation of Schnorr group exceeded %v: %w", d, err)
+	}
+
+	return schnorr, err
+}
+
+// GenerateSchnorrGroupWithReader is like GenerateSchnorrGroup(), but sources
+// all randomness from the passed reader, e.g. a hardware RNG. If the reader is
+// nil, crypto/rand's Reader is used.
+//
+// Given a deterministic reader, the generated group is reproducible.
+func GenerateSchnorrGroupWithReader(random io.Reader, pBits int, qBits int) (SchnorrGroup, error) {
+	return generateSchnorrGroup(context.Background(), random, pBits, qBits, defaultPrimeChecks)
+}
+
+// GenerateSchnorrGroupCofactor is like GenerateSchnorrGroup(), but fixes the
+// bit length of the cofactor r in p = q*r + 1 to rBits, rather than the bit
+// length of p. p then has a bit length of exactly qBits + rBits.
+//
+// rBits must be > 2, otherwise an error is returned.
+func GenerateSchnorrGroupCofactor(qBits int, rBits int) (SchnorrGroup, error) {
+	if rBits <= 2 {
+		return SchnorrGroup{}, fmt.Errorf("%w: rBits must be > 2; got %d", ErrInvalidGroup, rBits)
+	}
+
+	// The search for p draws r with exactly pBits - qBits bits
+	return generateSchnorrGroup(context.Background(), nil, qBits+rBits, qBits, defaultPrimeChecks)
+}
+
+// GenerateSchnorrGroupWithPrimeChecks is like GenerateSchnorrGroup(), but
+// tests candidates for p and q using primeChecks rounds of Miller-Rabin,
+// rather than defaultPrimeChecks. The probability of a composite passing is
+// at most 4^-primeChecks.
+//
+// primeChecks must be positive, otherwise an error is returned.
+func GenerateSchnorrGroupWithPrimeChecks(pBits int, qBits int, primeChecks int) (SchnorrGroup, error) {
+	if primeChecks <= 0 {
+		return SchnorrGroup{}, fmt.Errorf("primeChecks must be > 0; got %d", primeChecks)
+	}
+
+	return generateSchnorrGroup(context.Background(), nil, pBits, qBits, primeChecks)
+}
+
+// defaultPrimeChecks is the number of rounds of Miller-Rabin which candidates
+// for p and q are subjected to during group generation.
+const defaultPrimeChecks = 32
+
+// generateSchnorrGroup implements generation of Schnorr groups, checking ctx
+// for cancellation, sourcing randomness from the passed reader, and testing
+// candidates using primeChecks rounds of Miller-Rabin.
+func generateSchnorrGroup(ctx context.Context, random io.Reader, pBits int, qBits int, primeChecks int) (SchnorrGroup, error) {
 	var err error
 	schnorr := SchnorrGroup{}
 
+	if err := ctx.Err(); err != nil {
+		return schnorr, err
+	}
+
 	if qBits >= pBits {
-		return schnorr, fmt.Errorf("qbits must be < pbits")
+		return schnorr, fmt.Errorf("%w: qBits must be < pBits", ErrInvalidGroup)
 	}
 
 	// Starting with q-order subgroup
-	schnorr.Q, err = rand.Prime(rand.Reader, qBits)
+	schnorr.Q, err = randomPrime(ctx, random, qBits, primeChecks)
 	if err != nil {
 		return schnorr, err
 	}
 
 	// Find a prime p such that p = q*r + 1 for some integer r
-	schnorr.P = big.NewInt(0)
-	for !schnorr.P.ProbablyPrime(32) {
-		rBits := pBits - qBits
-		r, err := RandomBits(rBits)
+	schnorr.P, err = findP(ctx, random, schnorr.Q, pBits, primeChecks)
+	if err != nil {
+		return schnorr, err
+	}
+
+	// Finally find a generator by picking random values 1 < h < p such that g = h^r mod p != 1
+	schnorr.G, err = findGenerator(ctx, random, schnorr.P, schnorr.Q)
+	if err != nil {
+		return schnorr, err
+	}
+
+	return schnorr, nil
+}
+
+// primeSearchFactor bounds the search for p to primeSearchFactor * pBits
+// candidates. By the prime number theorem, roughly one in pBits candidates
+// p = q*r + 1 with even r is prime, so the bound is never reached in practice
+// and merely prevents pathological parameters from looping forever.
+const primeSearchFactor = 4096
+
+// findP searches for a prime p = q*r + 1 of length pBits, with r drawn at
+// random, testing candidates using primeChecks rounds of Miller-Rabin. An
+// error wrapping ErrPrimeSearchExhausted is returned if none is found within
+// primeSearchFactor * pBits candidates.
+func findP(ctx context.Context, random io.Reader, q *big.Int, pBits int, primeChecks int) (*big.Int, error) {
+	rBits := pBits - q.BitLen()
+	maxIterations := primeSearchFactor * pBits
+
+	p := big.NewInt(0)
+	for i := 0; !p.ProbablyPrime(primeChecks); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if i == maxIterations {
+			return nil, fmt.Errorf("%w: no prime p = q*r + 1 of %d bits within %d candidates", ErrPrimeSearchExhausted, pBits, maxIterations)
+		}
+
+		r, err := randomBitsFrom(random, rBits)
 		if err != nil {
-			return schnorr, err
+			return nil, err
 		}
 
 		// At this point, r and q both are guaranteed to have their
@@ -54,35 +174,325 @@ func GenerateSchnorrGroup(pBits int, qBits int) (SchnorrGroup, error) {
 		// cause it to overflow.
 
 		// p = r * q + 1
-		schnorr.P.SetBytes(r)
-		schnorr.P.Mul(schnorr.P, schnorr.Q)
-		schnorr.P.Add(schnorr.P, big.NewInt(1))
+		p.SetBytes(r)
+		p.Mul(p, q)
+		p.Add(p, big.NewInt(1))
 	}
 
-	// Finally find a generator by picking random values 1 < h < p such that g = h^r mod p != 1
-	schnorr.G = big.NewInt(1)
+	return p, nil
+}
+
+// Zp returns the finite field (Z/pZ), which the subgroup G is a subgroup of.
+//
+// An error wrapping ErrInvalidGroup is returned if P is missing or not
+// prime.
+func (sg SchnorrGroup) Zp() (gf.GF, error) {
+	return newField("p", sg.P)
+}
+
+// Zq returns the finite field (Z/qZ), over which exponents of elements of G
+// are defined.
+//
+// An error wrapping ErrInvalidGroup is returned if Q is missing or not
+// prime.
+func (sg SchnorrGroup) Zq() (gf.GF, error) {
+	return newField("q", sg.Q)
+}
+
+// Order returns the order q of the subgroup. A copy is returned, such that
+// modifying it does not affect the group.
+//
+// nil is returned if Q is missing.
+func (sg SchnorrGroup) Order() *big.Int {
+	if sg.Q == nil {
+		return nil
+	}
+
+	return new(big.Int).Set(sg.Q)
+}
+
+// Cofactor returns the cofactor r = (p-1)/q of the subgroup, such that
+// p = q*r + 1. Raising any element of (Z/pZ)* to r maps it into the subgroup
+// of order q.
+//
+// nil is returned if P or Q is missing or if q is not positive. Whether q
+// divides p-1 is not checked, use Validate() for this.
+func (sg SchnorrGroup) Cofactor() *big.Int {
+	if sg.P == nil || sg.Q == nil || sg.Q.Sign() <= 0 {
+		return nil
+	}
+
+	r := new(big.Int).Sub(sg.P, big.NewInt(1))
+	return r.Div(r, sg.Q)
+}
+
+// FindGenerator finds a generator of the subgroup of order q, given valid P
+// and Q, and sets G accordingly. This allows to complete a group whose
+// primes stem from an external source, without generating new primes.
+//
+// An error is returned if P or Q is missing, if q does not divide p-1, or if
+// sourcing of cryptographically secure randomness fails. Whether P and Q are
+// prime is not checked, use Validate() for this.
+func (sg *SchnorrGroup) FindGenerator() error {
+	if sg.P == nil || sg.Q == nil {
+		return ErrGroupIncomplete
+	}
+	if sg.Q.Sign() <= 0 || sg.P.Cmp(big.NewInt(2)) <= 0 {
+		return fmt.Errorf("p must be > 2 and q must be > 0")
+	}
+
+	var rem = &big.Int{}
+	rem.Sub(sg.P, big.NewInt(1))
+	rem.Rem(rem, sg.Q)
+	if rem.Sign() != 0 {
+		return ErrQNotDivisor
+	}
+
+	g, err := findGenerator(context.Background(), nil, sg.P, sg.Q)
+	if err != nil {
+		return err
+	}
+	sg.G = g
+
+	return nil
+}
+
+// secondGeneratorDomain separates the hashes used to derive the second
+// generator from any other use of the hash function.
+const secondGeneratorDomain = "distributed-elgamal second generator"
+
+// maxSecondGeneratorAttempts bounds the number of candidates considered by
+// SecondGenerator(). For a valid group, a candidate fails with probability
+// of about 1/q, so the bound is never reached in practice.
+const maxSecondGeneratorAttempts = 256
+
+// SecondGenerator deterministically derives a second generator h of the
+// subgroup of order q, such that nobody knows log_g(h), as required for
+// Pedersen commitments.
+//
+// h is derived by hashing the group parameters and a counter with SHA512,
+// expanded to 64 bits more than p to make the bias negligible, reducing the
+// result modulo p to u, and raising it to the cofactor, h = u^{(p-1)/q} mod p.
+// Candidates which yield 1 or g are skipped by incrementing the counter. As
+// h is a hash output, finding its discrete logarithm is as hard as the
+// discrete logarithm problem in the group.
+//
+// An error is returned if P, Q or G is missing, or if q does not divide p-1.
+func (sg SchnorrGroup) SecondGenerator() (*big.Int, error) {
+	if sg.P == nil || sg.Q == nil || sg.G == nil {
+		return nil, ErrGroupIncomplete
+	}
+	if !qDividesPMinusOne(sg.P, sg.Q) {
+		return nil, ErrQNotDivisor
+	}
+
+	cofactor := sg.Cofactor()
+
+	var prefix []byte
+	prefix = append(prefix, secondGeneratorDomain...)
+	for _, x := range []*big.Int{sg.P, sg.Q, sg.G} {
+		prefix = appendLengthPrefixed(prefix, x.Bytes())
+	}
+
+	size := (sg.P.BitLen() + 64 + 7) / 8
+	one := big.NewInt(1)
+	for ctr := uint32(0); ctr < maxSecondGeneratorAttempts; ctr++ {
+		// Expanding the hash to the desired size using a block counter
+		var digest []byte
+		for block := uint32(0); len(digest) < size; block++ {
+			var counters [8]byte
+			binary.BigEndian.PutUint32(counters[:4], ctr)
+			binary.BigEndian.PutUint32(counters[4:], block)
+
+			h := sha512.New()
+			h.Write(prefix)
+			h.Write(counters[:])
+			digest = h.Sum(digest)
+		}
+
+		u := new(big.Int).SetBytes(digest[:size])
+		u.Mod(u, sg.P)
+		h := u.Exp(u, cofactor, sg.P)
+		if h.Cmp(one) == 0 || h.Cmp(sg.G) == 0 {
+			continue
+		}
+
+		return h, nil
+	}
+
+	return nil, fmt.Errorf("%w: no second generator after %d attempts", ErrInvalidGroup, maxSecondGeneratorAttempts)
+}
+
+// maxGeneratorOrderFailures bounds the number of candidate generators which
+// may fail the check for being of order q, before findGenerator() gives up.
+// For prime p the check never fails, so hitting the bound indicates that p is
+// not prime.
+const maxGeneratorOrderFailures = 64
+
+// findGenerator finds a generator g = h^{(p-1)/q} mod p != 1 of the subgroup
+// of order q by picking random values 1 < h < p. q must divide p-1.
+//
+// While the construction implies g^q = h^{p-1} = 1 mod p, this is checked
+// explicitly as a safeguard, picking a new h if the check fails.
+func findGenerator(ctx context.Context, random io.Reader, p *big.Int, q *big.Int) (*big.Int, error) {
+	exp := SchnorrGroup{P: p, Q: q}.Cofactor()
+
+	one := big.NewInt(1)
+	g := &big.Int{}
+	order := &big.Int{}
+	failures := 0
 	for {
-		// rand.Int produces in [0, max), we want [2, p).
-		var max = &big.Int{}
-		max.Set(schnorr.P)
-		max.Sub(max, big.NewInt(2))
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 
-		h, err := rand.Int(rand.Reader, max) // [0, p-2)
+		h, err := randomInRange(random, big.NewInt(2), p) // [2, p)
 		if err != nil {
-			return schnorr, err
+			return nil, err
+		}
+
+		g.Exp(h, exp, p)
+		if g.Cmp(one) == 0 {
+			continue
+		}
+
+		// g^q mod p must be 1 for g to be of order q
+		if order.Exp(g, q, p).Cmp(one) != 0 {
+			failures++
+			if failures >= maxGeneratorOrderFailures {
+				return nil, fmt.Errorf("%w: no candidate of order q after %d attempts", ErrGeneratorOrder, failures)
+			}
+			continue
 		}
-		h.Add(h, big.NewInt(2)) // [2, p)
 
-		var exp = &big.Int{}
-		exp.Sub(schnorr.P, big.NewInt(1))
-		exp.Div(exp, schnorr.Q)
+		return g, nil
+	}
+}
 
-		schnorr.G.Exp(h, exp, schnorr.P)
+// GenerateSafePrimeGroup generates a Schnorr group where p = 2q + 1 is a safe
+// prime of length pBits, such that the subgroup G of prime order q - of
+// length pBits-1 - has a cofactor of exactly 2.
+//
+// pBits must be greater than 2, otherwise an error is returned. An error may
+// also be returned if sourcing of cryptographically secure randomness fails.
+//
+// Mind that safe primes are considerably rarer than the primes found by
+// GenerateSchnorrGroup(), so generation of large groups takes a while.
+func GenerateSafePrimeGroup(pBits int) (SchnorrGroup, error) {
+	return GenerateSafePrimeGroupContext(context.Background(), pBits)
+}
 
-		if schnorr.G.Cmp(big.NewInt(1)) != 0 {
+// GenerateSafePrimeGroupContext is like GenerateSafePrimeGroup(), but aborts
+// the search for p and g once ctx is done, returning ctx.Err().
+func GenerateSafePrimeGroupContext(ctx context.Context, pBits int) (SchnorrGroup, error) {
+	return generateSafePrimeGroup(ctx, nil, pBits, defaultPrimeChecks)
+}
+
+// generateSafePrimeGroup implements generation of safe prime groups, checking
+// ctx for cancellation, sourcing randomness from the passed reader, and
+// testing candidates using primeChecks rounds of Miller-Rabin.
+func generateSafePrimeGroup(ctx context.Context, random io.Reader, pBits int, primeChecks int) (SchnorrGroup, error) {
+	var err error
+	schnorr := SchnorrGroup{}
+
+	if err := ctx.Err(); err != nil {
+		return schnorr, err
+	}
+
+	if pBits <= 2 {
+		return schnorr, fmt.Errorf("%w: pBits must be > 2", ErrInvalidGroup)
+	}
+
+	schnorr.Q, schnorr.P, err = findSafePrime(ctx, random, pBits, primeChecks)
+	if err != nil {
+		return schnorr, err
+	}
+
+	// As the cofactor is 2, this picks g = h^2 mod p != 1
+	schnorr.G, err = findGenerator(ctx, random, schnorr.P, schnorr.Q)
+	if err != nil {
+		return schnorr, err
+	}
+
+	return schnorr, nil
+}
+
+// findSafePrime searches for a prime q of length pBits-1 such that
+// p = 2q + 1 is prime too, testing candidates using primeChecks rounds of
+// Miller-Rabin. As with findP(), an error wrapping ErrPrimeSearchExhausted is
+// returned if none is found within primeSearchFactor * pBits candidates for
+// q.
+func findSafePrime(ctx context.Context, random io.Reader, pBits int, primeChecks int) (*big.Int, *big.Int, error) {
+	maxIterations := primeSearchFactor * pBits
+
+	p := &big.Int{}
+	for i := 0; i < maxIterations; i++ {
+		// Checks ctx before drawing every candidate
+		q, err := randomPrime(ctx, random, pBits-1, primeChecks)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// As the two most significant bits of q are set, p has a bit
+		// length of exactly pBits
+		p.Lsh(q, 1)             // 2q
+		p.Add(p, big.NewInt(1)) // 2q + 1
+
+		if p.ProbablyPrime(primeChecks) {
+			return q, p, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("%w: no safe prime p = 2q + 1 of %d bits within %d candidates", ErrPrimeSearchExhausted, pBits, maxIterations)
+}
+
+// securityLevels maps the bit length of p to the security level - in bits -
+// provided against discrete logarithm computations in (Z/pZ)*, as per NIST SP
+// 800-57 Part 1, Table 2. The generic security provided by the subgroup of
+// order q is half the bit length of q.
+//
+//	Security | p (L) | q (N)
+//	---------+-------+------
+//	      80 |  1024 |  160
+//	     112 |  2048 |  224
+//	     128 |  3072 |  256
+//	     192 |  7680 |  384
+//	     256 | 15360 |  512
+var securityLevels = []struct {
+	pBits    int
+	security int
+}{
+	{15360, 256},
+	{7680, 192},
+	{3072, 128},
+	{2048, 112},
+	{1024, 80},
+}
+
+// SecurityBits estimates the security level - in bits - provided by the
+// group. It is the minimum of the strength against discrete logarithm
+// computations in (Z/pZ)*, and the generic strength of the subgroup of order
+// q.
+//
+// Zero is returned for groups with p of less than 1024 bits, which provide
+// less than 80 bits of security and should not be considered secure.
+func (sg SchnorrGroup) SecurityBits() int {
+	if sg.P == nil || sg.Q == nil {
+		return 0
+	}
+
+	pSecurity := 0
+	for _, level := range securityLevels {
+		if sg.P.BitLen() >= level.pBits {
+			pSecurity = level.security
 			break
 		}
 	}
 
-	return schnorr, nil
+	qSecurity := sg.Q.BitLen() / 2
+
+	if qSecurity < pSecurity {
+		return qSecurity
+	}
+	return pSecurity
 }
@@ -1,8 +1,11 @@
 package elgamal
 
 import (
+	"context"
+	"errors"
 	"math/big"
 	"testing"
+	"time"
 )
 
 func TestGenerateSchnorrGroup(t *testing.T) {
@@ -49,3 +52,372 @@ func TestGenerateSchnorrGroup(t *testing.T) {
 		t.Errorf("Expected error when pbits <= qbits; got none")
 	}
 }
+
+func TestGenerateSchnorrGroupCofactor(t *testing.T) {
+	qBits := 128
+	rBits := 384
+	schnorr, err := GenerateSchnorrGroupCofactor(qBits, rBits)
+	if err != nil {
+		t.Fatalf("GenerateSchnorrGroupCofactor returned error: %v", err)
+	}
+
+	if schnorr.P.BitLen() != qBits+rBits {
+		t.Errorf("Expected p to have bit length %d; got %d", qBits+rBits, schnorr.P.BitLen())
+	}
+	if schnorr.Q.BitLen() != qBits {
+		t.Errorf("Expected q to have bit length %d; got %d", qBits, schnorr.Q.BitLen())
+	}
+
+	// r = (p-1)/q
+	r, rem := new(big.Int).QuoRem(new(big.Int).Sub(schnorr.P, big.NewInt(1)), schnorr.Q, new(big.Int))
+	if rem.Sign() != 0 {
+		t.Errorf("Expected q to divide p-1; got p = %d, q = %d", schnorr.P, schnorr.Q)
+	}
+	if r.BitLen() != rBits {
+		t.Errorf("Expected r to have bit length %d; got %d", rBits, r.BitLen())
+	}
+
+	err = schnorr.Validate()
+	if err != nil {
+		t.Errorf("Expected valid group; got %v", err)
+	}
+
+	_, err = GenerateSchnorrGroupCofactor(qBits, 2)
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup with rBits = 2; got %v", err)
+	}
+}
+
+func TestGenerateSchnorrGroupWithPrimeChecks(t *testing.T) {
+	schnorr, err := GenerateSchnorrGroupWithPrimeChecks(512, 128, 64)
+	if err != nil {
+		t.Fatalf("GenerateSchnorrGroupWithPrimeChecks returned error: %v", err)
+	}
+
+	err = schnorr.Validate()
+	if err != nil {
+		t.Errorf("Expected valid group; got %v", err)
+	}
+
+	for _, primeChecks := range []int{0, -1} {
+		_, err = GenerateSchnorrGroupWithPrimeChecks(512, 128, primeChecks)
+		if err == nil {
+			t.Errorf("Expected error with primeChecks = %d; got none", primeChecks)
+		}
+	}
+}
+
+func TestCofactor(t *testing.T) {
+	schnorr, err := GenerateSchnorrGroup(512, 128)
+	if err != nil {
+		t.Fatalf("GenerateSchnorrGroup returned error: %v", err)
+	}
+
+	order := schnorr.Order()
+	if order.Cmp(schnorr.Q) != 0 {
+		t.Errorf("Expected order %d; got %d", schnorr.Q, order)
+	}
+	order.SetInt64(0)
+	if schnorr.Q.Sign() == 0 {
+		t.Errorf("Expected modifying order not to affect q")
+	}
+
+	// r*q + 1 = p
+	p := new(big.Int).Mul(schnorr.Cofactor(), schnorr.Order())
+	p.Add(p, big.NewInt(1))
+	if p.Cmp(schnorr.P) != 0 {
+		t.Errorf("Expected cofactor * order + 1 = %d; got %d", schnorr.P, p)
+	}
+
+	small := SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(11)}
+	if small.Cofactor().Int64() != 2 {
+		t.Errorf("Expected cofactor 2 for p = 23, q = 11; got %d", small.Cofactor())
+	}
+
+	if (SchnorrGroup{}).Cofactor() != nil || (SchnorrGroup{}).Order() != nil {
+		t.Errorf("Expected nil cofactor and order for empty group")
+	}
+}
+
+func TestFindPBounded(t *testing.T) {
+	// With a cofactor of 3 bits, r is one of 6 or 7. For q = 29, both
+	// 6*29 + 1 = 175 and 7*29 + 1 = 204 are composite, so the search can
+	// never succeed.
+	_, err := findP(context.Background(), nil, big.NewInt(29), 8, defaultPrimeChecks)
+	if !errors.Is(err, ErrPrimeSearchExhausted) {
+		t.Errorf("Expected ErrPrimeSearchExhausted with q = 29; got %v", err)
+	}
+
+	// For q = 13, 6*13 + 1 = 79 is prime
+	p, err := findP(context.Background(), nil, big.NewInt(13), 7, defaultPrimeChecks)
+	if err != nil {
+		t.Fatalf("findP returned error: %v", err)
+	}
+	if p.Int64() != 79 {
+		t.Errorf("Expected p = 79; got %v", p)
+	}
+}
+
+func TestGenerateSchnorrGroupWithin(t *testing.T) {
+	start := time.Now()
+	_, err := GenerateSchnorrGroupWithin(8192, 256, time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded error; got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected generation to abort promptly; took %v", elapsed)
+	}
+
+	schnorr, err := GenerateSchnorrGroupWithin(512, 128, time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateSchnorrGroupWithin returned error: %v", err)
+	}
+	if err := schnorr.Validate(); err != nil {
+		t.Errorf("Expected valid group; got %v", err)
+	}
+}
+
+func TestGenerateSafePrimeGroup(t *testing.T) {
+	pBits := 128
+	schnorr, err := GenerateSafePrimeGroup(pBits)
+	if err != nil {
+		t.Fatalf("Error generating safe prime group: %v", err)
+	}
+
+	if !schnorr.P.ProbablyPrime(32) {
+		t.Errorf("P is not prime; got %d", schnorr.P)
+	}
+	if schnorr.P.BitLen() != pBits {
+		t.Errorf("Expected p to have bit length %d; got %d", pBits, schnorr.P.BitLen())
+	}
+	if !schnorr.Q.ProbablyPrime(32) {
+		t.Errorf("Q is not prime; got %d", schnorr.Q)
+	}
+
+	// p = 2q + 1
+	var expected = &big.Int{}
+	expected.Lsh(schnorr.Q, 1)
+	expected.Add(expected, big.NewInt(1))
+	if expected.Cmp(schnorr.P) != 0 {
+		t.Errorf("Expected p = 2q + 1; got p = %d, q = %d", schnorr.P, schnorr.Q)
+	}
+
+	// g must be of order q
+	if schnorr.G.Cmp(big.NewInt(1)) == 0 {
+		t.Errorf("Expected g != 1")
+	}
+	var elem = &big.Int{}
+	elem.Exp(schnorr.G, schnorr.Q, schnorr.P)
+	if elem.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("Expected g^q mod p = 1; got %d", elem)
+	}
+
+	_, err = GenerateSafePrimeGroup(2)
+	if err == nil {
+		t.Errorf("Expected error when pBits <= 2; got none")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = GenerateSafePrimeGroupContext(ctx, pBits)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled with canceled context; got %v", err)
+	}
+}
+
+func TestFindSafePrimeBounded(t *testing.T) {
+	// With q of 3 bits, q = 7 is the only candidate, and 2*7 + 1 = 15 is
+	// composite, so the search can never succeed.
+	_, _, err := findSafePrime(context.Background(), nil, 4, defaultPrimeChecks)
+	if !errors.Is(err, ErrPrimeSearchExhausted) {
+		t.Errorf("Expected ErrPrimeSearchExhausted with p of 4 bits; got %v", err)
+	}
+
+	// With q of 2 bits, q = 3 and 2*3 + 1 = 7 is prime
+	q, p, err := findSafePrime(context.Background(), nil, 3, defaultPrimeChecks)
+	if err != nil {
+		t.Fatalf("findSafePrime returned error: %v", err)
+	}
+	if q.Int64() != 3 || p.Int64() != 7 {
+		t.Errorf("Expected q = 3, p = 7; got q = %v, p = %v", q, p)
+	}
+}
+
+func TestFindGenerator(t *testing.T) {
+	group := SchnorrGroup{
+		P: big.NewInt(23),
+		Q: big.NewInt(11),
+	}
+
+	err := group.FindGenerator()
+	if err != nil {
+		t.Fatalf("FindGenerator returned error: %v", err)
+	}
+
+	err = group.Validate()
+	if err != nil {
+		t.Errorf("Expected group with found generator to be valid; got %v", err)
+	}
+
+	// Primes must be left untouched
+	if group.P.Cmp(big.NewInt(23)) != 0 || group.Q.Cmp(big.NewInt(11)) != 0 {
+		t.Errorf("Expected P and Q to be unchanged; got %+v", group)
+	}
+
+	group = SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(7)}
+	err = group.FindGenerator()
+	if !errors.Is(err, ErrQNotDivisor) {
+		t.Errorf("Expected ErrQNotDivisor if q does not divide p-1; got %v", err)
+	}
+
+	group = SchnorrGroup{P: big.NewInt(23)}
+	err = group.FindGenerator()
+	if !errors.Is(err, ErrGroupIncomplete) {
+		t.Errorf("Expected ErrGroupIncomplete if q is missing; got %v", err)
+	}
+
+	// With composite p = 21, no candidate h^{(p-1)/q} is of order q = 5
+	group = SchnorrGroup{P: big.NewInt(21), Q: big.NewInt(5)}
+	err = group.FindGenerator()
+	if !errors.Is(err, ErrGeneratorOrder) {
+		t.Errorf("Expected ErrGeneratorOrder if p is composite; got %v", err)
+	}
+}
+
+func TestSchnorrGroupFields(t *testing.T) {
+	pub, _, _, err := KeyGen(512, 128, 2, 3)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	zp, err := pub.SchnorrGroup.Zp()
+	if err != nil {
+		t.Fatalf("Zp returned error: %v", err)
+	}
+	pubZp, err := pub.Zp()
+	if err != nil {
+		t.Fatalf("Zp returned error: %v", err)
+	}
+	if zp.P.Cmp(pubZp.P) != 0 {
+		t.Errorf("Expected field of order %d; got %d", pubZp.P, zp.P)
+	}
+
+	zq, err := pub.SchnorrGroup.Zq()
+	if err != nil {
+		t.Fatalf("Zq returned error: %v", err)
+	}
+	pubZq, err := pub.Zq()
+	if err != nil {
+		t.Fatalf("Zq returned error: %v", err)
+	}
+	if zq.P.Cmp(pubZq.P) != 0 {
+		t.Errorf("Expected field of order %d; got %d", pubZq.P, zq.P)
+	}
+
+	_, err = SchnorrGroup{P: big.NewInt(23)}.Zq()
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup if q is missing; got %v", err)
+	}
+	_, err = SchnorrGroup{P: big.NewInt(21), Q: big.NewInt(5)}.Zp()
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrInvalidGroup if p is not prime; got %v", err)
+	}
+}
+
+func TestSecondGenerator(t *testing.T) {
+	groups := []SchnorrGroup{
+		{P: big.NewInt(23), Q: big.NewInt(11), G: big.NewInt(4)},
+		SchnorrGroupRFC3526_2048(),
+	}
+	generated, err := GenerateSchnorrGroup(512, 128)
+	if err != nil {
+		t.Fatalf("GenerateSchnorrGroup returned error: %v", err)
+	}
+	groups = append(groups, generated)
+
+	for _, group := range groups {
+		h, err := group.SecondGenerator()
+		if err != nil {
+			t.Fatalf("SecondGenerator returned error: %v", err)
+		}
+
+		if h.Cmp(group.G) == 0 {
+			t.Errorf("Expected h to differ from g = %d", group.G)
+		}
+		if h.Cmp(big.NewInt(1)) == 0 {
+			t.Errorf("Expected h to differ from 1")
+		}
+		if new(big.Int).Exp(h, group.Q, group.P).Cmp(big.NewInt(1)) != 0 {
+			t.Errorf("Expected h^q mod p = 1 for h = %d", h)
+		}
+
+		// Deterministic
+		again, err := group.SecondGenerator()
+		if err != nil {
+			t.Fatalf("SecondGenerator returned error: %v", err)
+		}
+		if again.Cmp(h) != 0 {
+			t.Errorf("Expected h = %d on repeated call; got %d", h, again)
+		}
+	}
+
+	_, err = SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(11)}.SecondGenerator()
+	if !errors.Is(err, ErrGroupIncomplete) {
+		t.Errorf("Expected ErrGroupIncomplete if g is missing; got %v", err)
+	}
+	_, err = SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(7), G: big.NewInt(4)}.SecondGenerator()
+	if !errors.Is(err, ErrQNotDivisor) {
+		t.Errorf("Expected ErrQNotDivisor if q does not divide p-1; got %v", err)
+	}
+}
+
+func TestGeneratorOrder(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		schnorr, err := GenerateSchnorrGroup(128, 32)
+		if err != nil {
+			t.Fatalf("GenerateSchnorrGroup returned error: %v", err)
+		}
+
+		order := new(big.Int).Exp(schnorr.G, schnorr.Q, schnorr.P)
+		if order.Cmp(big.NewInt(1)) != 0 {
+			t.Errorf("Expected g^q mod p = 1 for group %+v; got %d", schnorr, order)
+		}
+	}
+}
+
+func TestSecurityBits(t *testing.T) {
+	bits := func(n uint) *big.Int {
+		return new(big.Int).Lsh(big.NewInt(1), n-1)
+	}
+
+	tests := []struct {
+		pBits    uint
+		qBits    uint
+		expected int
+	}{
+		{1024, 160, 80},
+		{1024, 256, 80},
+		{2048, 224, 112},
+		{2048, 256, 112},
+		{3072, 256, 128},
+		{3072, 160, 80},
+		{7680, 384, 192},
+		{15360, 512, 256},
+		{4096, 512, 128},
+		{512, 128, 0},
+	}
+
+	for _, test := range tests {
+		sg := SchnorrGroup{P: bits(test.pBits), Q: bits(test.qBits)}
+		got := sg.SecurityBits()
+		if got != test.expected {
+			t.Errorf("Expected %d bits of security for p of %d bits and q of %d bits; got %d", test.expected, test.pBits, test.qBits, got)
+		}
+	}
+
+	// Promoted to public keys
+	pub := PublicKey{SchnorrGroup: SchnorrGroup{P: bits(2048), Q: bits(256)}}
+	if pub.SecurityBits() != 112 {
+		t.Errorf("Expected 112 bits of security for public key; got %d", pub.SecurityBits())
+	}
+}
@@ -0,0 +1,219 @@
+package elgamal
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"github.com/lavode/secret-sharing/gf"
+	"math/big"
+)
+
+// Signature is a Schnorr signature, consisting of the challenge e and the
+// response s.
+type Signature struct {
+	// Challenge e = H(g^k || m) mod q
+	E *big.Int
+	// Response s = k + x*e mod q
+	S *big.Int
+}
+
+// Sign creates a Schnorr signature of the message, using the private key x
+// within the passed group. This allows to authenticate with the same key
+// material as used for decryption.
+//
+// With a random nonce k from [1, q), the signature is (e, s) with
+// e = H(g^k || m) mod q and s = k + x*e mod q, where H is SHA512 and g^k is
+// encoded left-padded to the byte length of p.
+//
+// An error is returned if the group or private key is incomplete, or if
+// sourcing of randomness fails.
+func Sign(priv PrivateKey, group SchnorrGroup, message []byte) (Signature, error) {
+	if group.P == nil || group.Q == nil || group.G == nil {
+		return Signature{}, fmt.Errorf("%w: group is incomplete", ErrInvalidGroup)
+	}
+	if priv.X == nil {
+		return Signature{}, fmt.Errorf("Private key X is nil")
+	}
+
+	zp, err := group.Zp()
+	if err != nil {
+		return Signature{}, err
+	}
+	zq, err := group.Zq()
+	if err != nil {
+		return Signature{}, err
+	}
+
+	k, err := randomInRange(nil, big.NewInt(1), group.Q) // [1, q)
+	if err != nil {
+		return Signature{}, err
+	}
+
+	e := signatureChallenge(group, zp.Exp(group.G, k), message)
+	s := zq.Add(k, zq.Mul(priv.X, e))
+
+	return Signature{E: e, S: s}, nil
+}
+
+// Verify checks a Schnorr signature of the message, as created by Sign(),
+// against the public key y = g^x.
+//
+// The signature is valid if e = H(g^s * y^{-e} || m) mod q, as
+// g^s * y^{-e} = g^{k + x*e - x*e} = g^k. Malformed signatures or public keys
+// are reported as invalid.
+func Verify(pub PublicKey, message []byte, sig Signature) bool {
+	if pub.checkComplete() != nil || sig.E == nil || sig.S == nil {
+		return false
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return false
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return false
+	}
+	if !zq.IsGroupElement(sig.E) || !zq.IsGroupElement(sig.S) {
+		return false
+	}
+
+	// y^{-e} = y^{q-e}, as y is of order q
+	yInv := zp.Exp(pub.Y, zq.Sub(big.NewInt(0), sig.E))
+	commitment := zp.Mul(zp.Exp(pub.G, sig.S), yInv)
+
+	return signatureChallenge(pub.SchnorrGroup, commitment, message).Cmp(sig.E) == 0
+}
+
+// signatureChallenge computes the challenge H(commitment || m) mod q of a
+// Schnorr signature.
+func signatureChallenge(group SchnorrGroup, commitment *big.Int, message []byte) *big.Int {
+	h := sha512.New()
+	h.Write(fixedBytes(commitment, (group.P.BitLen()+7)/8))
+	h.Write(message)
+
+	e := new(big.Int).SetBytes(h.Sum(nil))
+	return e.Mod(e, group.Q)
+}
+
+// CommitmentShare is a party's share of a signing nonce k, along with the
+// public commitment K = g^k mod p. All parties signing a message jointly must
+// hold shares of the same nonce, with the same IDs as their private key
+// shares.
+//
+// A nonce can be generated without any party learning k by running a
+// DealerlessKeygen among the signing parties: the resulting private key share
+// is the party's share of k, and the public key's y is K.
+//
+// A nonce must never be used for more than one message, as two signatures
+// with the same nonce reveal the private key.
+type CommitmentShare struct {
+	// Share k_i of the nonce k
+	Share PrivateKeyShare
+	// Commitment K = g^k mod p
+	Commitment *big.Int
+}
+
+// PartialSignature is a party's share of a threshold Schnorr signature, as
+// produced by SignShare().
+type PartialSignature struct {
+	// ID of the party
+	ID int
+	// Share s_i = k_i + x_i*e mod q of the response s
+	S *big.Int
+	// Commitment K = g^k mod p of the nonce used
+	Commitment *big.Int
+}
+
+// SignShare creates a party's partial Schnorr signature of the message, using
+// its private key share x_i and its share k_i of a jointly generated nonce.
+//
+// The partial signature is s_i = k_i + x_i*e mod q, with the challenge
+// e = H(K || m) mod q as in Sign(). t partial signatures can be combined into
+// a full signature with CombineSignatures().
+//
+// An error is returned if the IDs of the key share and nonce share differ, or
+// if the public key, key share or nonce share is incomplete.
+func SignShare(pub PublicKey, keyShare PrivateKeyShare, commitmentShare CommitmentShare, message []byte) (PartialSignature, error) {
+	if keyShare.ID != commitmentShare.Share.ID {
+		return PartialSignature{}, fmt.Errorf("IDs of key share and nonce share differ; got %d and %d", keyShare.ID, commitmentShare.Share.ID)
+	}
+	if keyShare.Value == nil || commitmentShare.Share.Value == nil || commitmentShare.Commitment == nil {
+		return PartialSignature{}, fmt.Errorf("Key share or nonce share is incomplete")
+	}
+
+	err := pub.checkComplete()
+	if err != nil {
+		return PartialSignature{}, err
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return PartialSignature{}, err
+	}
+
+	e := signatureChallenge(pub.SchnorrGroup, commitmentShare.Commitment, message)
+	s := zq.Add(commitmentShare.Share.Value, zq.Mul(keyShare.Value, e))
+
+	return PartialSignature{
+		ID:         keyShare.ID,
+		S:          s,
+		Commitment: new(big.Int).Set(commitmentShare.Commitment),
+	}, nil
+}
+
+// CombineSignatures combines t partial signatures of the message into a
+// Schnorr signature, which can be checked with Verify() against the public
+// key.
+//
+// The response is s = sum(lambda_i * s_i) = k + x*e mod q, by Lagrange
+// interpolation over the IDs of the partial signatures. Neither k nor x is
+// reconstructed in the process.
+//
+// An error is returned if fewer than t partial signatures are passed, if
+// their IDs are not distinct, or if they were created with different nonces.
+// Partial signatures are not checked individually, so a bogus one yields a
+// signature which fails verification.
+func CombineSignatures(pub PublicKey, partials []PartialSignature, message []byte) (Signature, error) {
+	if len(partials) < pub.Threshold {
+		return Signature{}, fmt.Errorf("%w: need at least %d partial signatures; got %d", ErrThresholdNotMet, pub.Threshold, len(partials))
+	}
+	if len(partials) == 0 {
+		return Signature{}, fmt.Errorf("Need at least one partial signature")
+	}
+
+	err := pub.checkComplete()
+	if err != nil {
+		return Signature{}, err
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return Signature{}, err
+	}
+
+	commitment := partials[0].Commitment
+	seen := make(map[int]bool, len(partials))
+	xs := make([]*big.Int, len(partials))
+	for i, partial := range partials {
+		if partial.S == nil || partial.Commitment == nil {
+			return Signature{}, fmt.Errorf("Partial signature %d is incomplete", partial.ID)
+		}
+		if partial.Commitment.Cmp(commitment) != 0 {
+			return Signature{}, fmt.Errorf("Partial signature %d uses a different nonce", partial.ID)
+		}
+		if seen[partial.ID] {
+			return Signature{}, fmt.Errorf("%w: partial signature with ID %d", ErrDuplicateShare, partial.ID)
+		}
+		seen[partial.ID] = true
+		xs[i] = big.NewInt(int64(partial.ID))
+	}
+
+	s := big.NewInt(0)
+	for i, partial := range partials {
+		lambda := gf.BasePolynomial(i, xs, zq)
+		s = zq.Add(s, zq.Mul(lambda, partial.S))
+	}
+
+	return Signature{
+		E: signatureChallenge(pub.SchnorrGroup, commitment, message),
+		S: s,
+	}, nil
+}
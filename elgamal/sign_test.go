@@ -0,0 +1,119 @@
+package elgamal
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	pub, priv, _, err := KeyGen(512, 128, 2, 3)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	msg := []byte("Hello world")
+	sig, err := Sign(priv, pub.SchnorrGroup, msg)
+	if err != nil {
+		t.Fatalf("Sign returned error: %v", err)
+	}
+
+	if !Verify(pub, msg, sig) {
+		t.Errorf("Expected signature to verify; it did not")
+	}
+
+	if Verify(pub, []byte("Hello world!"), sig) {
+		t.Errorf("Expected signature of tampered message not to verify; it did")
+	}
+
+	tampered := Signature{E: sig.E, S: new(big.Int).Add(sig.S, big.NewInt(1))}
+	tampered.S.Mod(tampered.S, pub.Q)
+	if Verify(pub, msg, tampered) {
+		t.Errorf("Expected tampered signature not to verify; it did")
+	}
+
+	other, _, _, err := KeyGen(512, 128, 2, 3)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+	if Verify(other, msg, sig) {
+		t.Errorf("Expected signature not to verify under another key; it did")
+	}
+
+	if Verify(pub, msg, Signature{}) {
+		t.Errorf("Expected empty signature not to verify; it did")
+	}
+
+	_, err = Sign(PrivateKey{}, pub.SchnorrGroup, msg)
+	if err == nil {
+		t.Errorf("Expected error with empty private key; got none")
+	}
+	_, err = Sign(priv, SchnorrGroup{}, msg)
+	if err == nil {
+		t.Errorf("Expected error with empty group; got none")
+	}
+}
+
+func TestThresholdSign(t *testing.T) {
+	group, err := GenerateSchnorrGroup(512, 128)
+	if err != nil {
+		t.Fatalf("Error generating Schnorr group: %v", err)
+	}
+
+	// Both the private key and the nonce are generated without a dealer
+	keyPubs, keyShares, _ := runDealerlessKeygen(t, group, 3, 5, 0, 0)
+	noncePubs, nonceShares, _ := runDealerlessKeygen(t, group, 3, 5, 0, 0)
+	pub := keyPubs[0]
+
+	msg := []byte("Hello world")
+	signers := []int{4, 0, 2}
+	partials := make([]PartialSignature, len(signers))
+	for i, signer := range signers {
+		commitmentShare := CommitmentShare{
+			Share:      nonceShares[signer],
+			Commitment: noncePubs[signer].Y,
+		}
+		partials[i], err = SignShare(pub, keyShares[signer], commitmentShare, msg)
+		if err != nil {
+			t.Fatalf("SignShare returned error: %v", err)
+		}
+	}
+
+	sig, err := CombineSignatures(pub, partials, msg)
+	if err != nil {
+		t.Fatalf("CombineSignatures returned error: %v", err)
+	}
+	if !Verify(pub, msg, sig) {
+		t.Errorf("Expected threshold signature to verify; it did not")
+	}
+	if Verify(pub, []byte("Hello world!"), sig) {
+		t.Errorf("Expected threshold signature of tampered message not to verify; it did")
+	}
+
+	// A bogus partial signature yields an invalid signature
+	bogus := append([]PartialSignature{}, partials...)
+	bogus[1].S = new(big.Int).Add(bogus[1].S, big.NewInt(1))
+	sig, err = CombineSignatures(pub, bogus, msg)
+	if err != nil {
+		t.Fatalf("CombineSignatures returned error: %v", err)
+	}
+	if Verify(pub, msg, sig) {
+		t.Errorf("Expected signature with bogus partial signature not to verify; it did")
+	}
+
+	_, err = CombineSignatures(pub, partials[:2], msg)
+	if err == nil {
+		t.Errorf("Expected error with fewer than t partial signatures; got none")
+	}
+
+	mixed := append([]PartialSignature{}, partials...)
+	mixed[2].Commitment = new(big.Int).Add(mixed[2].Commitment, big.NewInt(1))
+	_, err = CombineSignatures(pub, mixed, msg)
+	if err == nil {
+		t.Errorf("Expected error with partial signatures using different nonces; got none")
+	}
+
+	_, err = SignShare(pub, keyShares[0], CommitmentShare{Share: nonceShares[1], Commitment: noncePubs[1].Y}, msg)
+	if err == nil {
+		t.Errorf("Expected error with mismatching IDs of key and nonce share; got none")
+	}
+}
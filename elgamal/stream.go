@@ -0,0 +1,116 @@
+package elgamal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// EncStream reads exactly one block of plaintext from r, encrypts it using
+// Enc(), and writes the ciphertext to w in the binary encoding of
+// MarshalBinary(), that is R prefixed with its length, followed by C.
+//
+// An error is returned if r yields fewer than BlockSize() bytes. Errors of r
+// and w are wrapped, and can be inspected using errors.Is().
+func EncStream(pub PublicKey, r io.Reader, w io.Writer) error {
+	blockSize := pub.BlockSize()
+	if blockSize == 0 {
+		_, err := pub.hashFunc()
+		return err
+	}
+
+	msg := make([]byte, blockSize)
+	_, err := io.ReadFull(r, msg)
+	if err != nil {
+		return fmt.Errorf("Error reading %d byte plaintext block: %w", blockSize, err)
+	}
+
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		return err
+	}
+
+	data, err := ctxt.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		return fmt.Errorf("Error writing ciphertext: %w", err)
+	}
+
+	return nil
+}
+
+// RecoverStream reads a ciphertext which was written by EncStream() from r,
+// recovers the plaintext from the decryption shares using Recover(), and
+// writes it to w.
+//
+// The decryption shares must have been computed for the ciphertext which is
+// read from r. As R must not be longer than p, and C must be exactly one
+// block, at most a bounded amount of data is read from r. Errors of r and w
+// are wrapped, and can be inspected using errors.Is().
+func RecoverStream(pub PublicKey, decryptionShares []DecryptionShare, r io.Reader, w io.Writer) error {
+	blockSize := pub.BlockSize()
+	if blockSize == 0 {
+		_, err := pub.hashFunc()
+		return err
+	}
+	if pub.P == nil {
+		return fmt.Errorf("Public key is missing p")
+	}
+
+	rBytes, err := readLengthPrefixedFrom("R", r, (pub.P.BitLen()+7)/8)
+	if err != nil {
+		return err
+	}
+	c := make([]byte, blockSize)
+	_, err = io.ReadFull(r, c)
+	if err != nil {
+		return fmt.Errorf("Error reading C: %w", err)
+	}
+
+	ctxt := Ciphertext{
+		R:     new(big.Int).SetBytes(rBytes),
+		C:     c,
+		rSize: len(rBytes),
+	}
+
+	msg, err := Recover(pub, decryptionShares, ctxt)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(msg)
+	if err != nil {
+		return fmt.Errorf("Error writing plaintext: %w", err)
+	}
+
+	return nil
+}
+
+// readLengthPrefixedFrom reads a field which was encoded by
+// appendLengthPrefixed() from r. An error is returned if the field is longer
+// than max bytes. The name of the field is used in error messages.
+func readLengthPrefixedFrom(name string, r io.Reader, max int) ([]byte, error) {
+	var prefix [lengthPrefixSize]byte
+	_, err := io.ReadFull(r, prefix[:])
+	if err != nil {
+		return nil, fmt.Errorf("Error reading length prefix of %s: %w", name, err)
+	}
+
+	length := binary.BigEndian.Uint32(prefix[:])
+	if uint64(length) > uint64(max) {
+		return nil, fmt.Errorf("Length of %s must be <= %d bytes; got %d", name, max, length)
+	}
+
+	field := make([]byte, length)
+	_, err = io.ReadFull(r, field)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s: %w", name, err)
+	}
+
+	return field, nil
+}
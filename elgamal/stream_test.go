@@ -0,0 +1,99 @@
+package elgamal
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/big"
+	"testing"
+)
+
+// failingWriter is an io.Writer which fails every write.
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestEncStream(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	msg := bytes.Repeat([]byte("streamed"), 8)
+
+	var buf bytes.Buffer
+	err = EncStream(pub, bytes.NewReader(msg), &buf)
+	if err != nil {
+		t.Fatalf("EncStream returned error: %v", err)
+	}
+
+	var ctxt Ciphertext
+	err = ctxt.UnmarshalBinary(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Error decoding streamed ciphertext: %v", err)
+	}
+
+	decShares := make([]DecryptionShare, 3)
+	for i := range decShares {
+		decShares[i], err = Dec(pub, shares[i], ctxt)
+		if err != nil {
+			t.Fatalf("Dec returned error: %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	err = RecoverStream(pub, decShares, bytes.NewReader(buf.Bytes()), &out)
+	if err != nil {
+		t.Fatalf("RecoverStream returned error: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), msg) {
+		t.Errorf("Expected recovered message %x; got %x", msg, out.Bytes())
+	}
+
+	// Truncated ciphertext
+	err = RecoverStream(pub, decShares, bytes.NewReader(buf.Bytes()[:buf.Len()-1]), &out)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF with truncated ciphertext; got %v", err)
+	}
+}
+
+func TestEncStreamErrors(t *testing.T) {
+	pub := PublicKey{
+		SchnorrGroup: SchnorrGroup{
+			P: big.NewInt(23),
+			Q: big.NewInt(11),
+			G: big.NewInt(4),
+		},
+		Y: big.NewInt(16),
+	}
+
+	// Short read
+	err := EncStream(pub, bytes.NewReader(make([]byte, 63)), io.Discard)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF with short plaintext; got %v", err)
+	}
+
+	// Empty reader
+	err = EncStream(pub, bytes.NewReader(nil), io.Discard)
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("Expected io.EOF with empty plaintext; got %v", err)
+	}
+
+	// Failing writer
+	writeErr := errors.New("write failed")
+	err = EncStream(pub, bytes.NewReader(make([]byte, 64)), failingWriter{writeErr})
+	if !errors.Is(err, writeErr) {
+		t.Errorf("Expected writer error to propagate; got %v", err)
+	}
+
+	// Oversized R
+	oversized := []byte{0x00, 0x00, 0x00, 0x02, 0x00, 0x03}
+	err = RecoverStream(pub, nil, bytes.NewReader(oversized), io.Discard)
+	if err == nil {
+		t.Errorf("Expected error with R longer than p; got none")
+	}
+}
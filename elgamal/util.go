@@ -1,9 +1,19 @@
 package elgamal
 
 import (
+	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
 	"fmt"
+	"hash"
+	"io"
 	"math"
+	"math/big"
+	"runtime"
+	"sync"
 )
 
 // RandomBits returns bits random bits suitable for cryptographic usage.
@@ -15,6 +25,90 @@ import (
 // bits of randomness, but helps with multiplying such numbers together. As
 // such it is not suitable for use with low bit counts.
 func RandomBits(bits int) ([]byte, error) {
+	return randomBitsFrom(rand.Reader, bits)
+}
+
+// RandomBitsUniform returns bits uniformly random bits suitable for
+// cryptographic usage.
+//
+// Bits must be > 0. If bits is not a multiple of 8, the leading bits of the
+// first byte (at index 0) will be forced to 0.
+//
+// Unlike RandomBits(), the most significant bits are not forced to 1, so the
+// returned value is uniform in [0, 2^bits), and may be shorter than bits
+// bits. Use this for e.g. random exponents, and RandomBits() where the bit
+// length must be exact.
+func RandomBitsUniform(bits int) ([]byte, error) {
+	if bits <= 0 {
+		return nil, fmt.Errorf("Bits must be > 0")
+	}
+
+	bytes := int(math.Ceil(float64(bits) / 8))
+	out := make([]byte, bytes)
+
+	_, err := rand.Read(out)
+	if err != nil {
+		return out, err
+	}
+
+	zeroLeadingBits := 8*bytes - bits
+	// Zero leading bits, if requested not a multiple of eight
+	out[0] = out[0] & (0xFF >> zeroLeadingBits)
+
+	return out, nil
+}
+
+// BitStats reports the frequency of ones per bit position over many outputs
+// of RandomBits(), as returned by RandomBitsStats().
+type BitStats struct {
+	// Number of samples drawn
+	Samples int
+	// Frequency of ones - in [0, 1] - of each of the bits-2 bits which are
+	// not forced to 1, indexed from the least significant bit. All should
+	// be close to 0.5.
+	Ones []float64
+}
+
+// RandomBitsStats draws the passed number of samples from RandomBits(bits),
+// and reports the frequency of ones per bit position, excluding the two most
+// significant bits, which RandomBits() forces to 1. This is a diagnostic to
+// detect bias in the output, not a full statistical test of the RNG.
+//
+// An error is returned if samples < 1, if bits <= 2, or if sourcing of
+// randomness fails.
+func RandomBitsStats(samples int, bits int) (BitStats, error) {
+	if samples < 1 {
+		return BitStats{}, fmt.Errorf("Samples must be >= 1; got %d", samples)
+	}
+	if bits <= 2 {
+		return BitStats{}, fmt.Errorf("Bits must be > 2")
+	}
+
+	counts := make([]int, bits-2)
+	x := new(big.Int)
+	for i := 0; i < samples; i++ {
+		out, err := RandomBits(bits)
+		if err != nil {
+			return BitStats{}, err
+		}
+
+		x.SetBytes(out)
+		for j := range counts {
+			counts[j] += int(x.Bit(j))
+		}
+	}
+
+	stats := BitStats{Samples: samples, Ones: make([]float64, len(counts))}
+	for j, count := range counts {
+		stats.Ones[j] = float64(count) / float64(samples)
+	}
+
+	return stats, nil
+}
+
+// randomBitsFrom implements RandomBits(), sourcing randomness from the passed
+// reader.
+func randomBitsFrom(random io.Reader, bits int) ([]byte, error) {
 	bytes := int(math.Ceil(float64(bits) / 8))
 	out := make([]byte, bytes)
 
@@ -22,7 +116,7 @@ func RandomBits(bits int) ([]byte, error) {
 		return out, fmt.Errorf("Bits must be > 2")
 	}
 
-	_, err := rand.Read(out)
+	_, err := io.ReadFull(randomReader(random), out)
 	if err != nil {
 		return out, err
 	}
@@ -35,3 +129,261 @@ func RandomBits(bits int) ([]byte, error) {
 
 	return out, nil
 }
+
+// seededReaderDomain separates the key stream of a seededReader from other
+// uses of HMAC keyed by the same seed.
+const seededReaderDomain = "distributed-elgamal seeded reader"
+
+// seededReader is a deterministic CSPRNG, producing the stream of blocks
+// HMAC-SHA256(seed, domain || counter) for counter = 0, 1, ...
+//
+// This is the expand step of HKDF, without its limit on the output length.
+type seededReader struct {
+	mac     hash.Hash
+	counter uint64
+	buf     []byte
+}
+
+// newSeededReader returns a reader whose output is fully determined by the
+// passed seed.
+func newSeededReader(seed []byte) *seededReader {
+	return &seededReader{mac: hmac.New(sha256.New, seed)}
+}
+
+func (r *seededReader) Read(p []byte) (int, error) {
+	for len(r.buf) < len(p) {
+		var counter [8]byte
+		binary.BigEndian.PutUint64(counter[:], r.counter)
+		r.counter++
+
+		r.mac.Reset()
+		r.mac.Write([]byte(seededReaderDomain))
+		r.mac.Write(counter[:])
+		r.buf = r.mac.Sum(r.buf)
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// constantTimeEqual reports whether a and b are equal, in time independent of
+// their contents. Use it to compare secret-derived values such as tags or
+// recovered plaintexts, where bytes.Equal() would leak the length of the
+// common prefix through timing. The lengths of a and b are not secret.
+func constantTimeEqual(a []byte, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// randomReader returns the passed source of randomness, or crypto/rand's
+// Reader if it is nil.
+func randomReader(random io.Reader) io.Reader {
+	if random == nil {
+		return rand.Reader
+	}
+
+	return random
+}
+
+// randomInt returns a uniformly random integer in [0, max), sourcing
+// randomness from the passed reader.
+//
+// Unlike crypto/rand.Int(), it is guaranteed to only consume randomness from
+// the passed reader, which allows to produce reproducible outputs from a
+// deterministic reader. Uniformity is achieved by rejection sampling.
+func randomInt(random io.Reader, max *big.Int) (*big.Int, error) {
+	if max == nil || max.Sign() <= 0 {
+		return nil, fmt.Errorf("max must be > 0")
+	}
+
+	n := new(big.Int).Sub(max, big.NewInt(1))
+	bitLen := n.BitLen()
+	if bitLen == 0 {
+		return n, nil
+	}
+
+	// Number of bits to keep in the most significant byte
+	keep := uint(bitLen % 8)
+	if keep == 0 {
+		keep = 8
+	}
+
+	buf := make([]byte, (bitLen+7)/8)
+	for {
+		_, err := io.ReadFull(randomReader(random), buf)
+		if err != nil {
+			return nil, err
+		}
+		buf[0] &= byte(1<<keep - 1)
+
+		n.SetBytes(buf)
+		if n.Cmp(max) < 0 {
+			return n, nil
+		}
+	}
+}
+
+// RandomInRange returns a uniformly random integer in [min, max), suitable for
+// cryptographic usage. Uniformity is achieved by rejection sampling over
+// crypto/rand.
+//
+// An error is returned if min >= max, or if sourcing of randomness fails.
+func RandomInRange(min *big.Int, max *big.Int) (*big.Int, error) {
+	return randomInRange(nil, min, max)
+}
+
+// randomInRange implements RandomInRange(), sourcing randomness from the
+// passed reader.
+func randomInRange(random io.Reader, min *big.Int, max *big.Int) (*big.Int, error) {
+	if min == nil || max == nil || min.Cmp(max) >= 0 {
+		return nil, fmt.Errorf("min must be < max")
+	}
+
+	var width = &big.Int{}
+	width.Sub(max, min)
+
+	n, err := randomInt(random, width) // [0, max-min)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.Add(n, min), nil // [min, max)
+}
+
+// randomPrime returns a prime of exactly bits bits, sourcing randomness from
+// the passed reader. As with RandomBits(), its two most significant bits are
+// set, so the product of two such primes has exactly the sum of their bit
+// lengths. Candidates are tested using primeChecks rounds of Miller-Rabin.
+//
+// The search stops once ctx is done, returning ctx.Err().
+func randomPrime(ctx context.Context, random io.Reader, bits int, primeChecks int) (*big.Int, error) {
+	if bits < 2 {
+		return nil, fmt.Errorf("Prime size must be at least 2 bits")
+	}
+
+	// Number of bits to keep in the most significant byte
+	keep := uint(bits % 8)
+	if keep == 0 {
+		keep = 8
+	}
+
+	buf := make([]byte, (bits+7)/8)
+	p := new(big.Int)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		_, err := io.ReadFull(randomReader(random), buf)
+		if err != nil {
+			return nil, err
+		}
+
+		buf[0] &= byte(1<<keep - 1)
+		// Set the two most significant bits
+		if keep >= 2 {
+			buf[0] |= 3 << (keep - 2)
+		} else {
+			buf[0] |= 1
+			if len(buf) > 1 {
+				buf[1] |= 0x80
+			}
+		}
+		// Only odd numbers, except for 2, can be prime
+		buf[len(buf)-1] |= 1
+
+		p.SetBytes(buf)
+		if p.ProbablyPrime(primeChecks) {
+			return p, nil
+		}
+	}
+}
+
+// combinations returns all k-element subsets of the indices [0, n), each in
+// ascending order. The subsets themselves are in lexicographic order.
+func combinations(n int, k int) [][]int {
+	var out [][]int
+	if k < 0 || k > n {
+		return out
+	}
+
+	current := make([]int, k)
+	var build func(start int, depth int)
+	build = func(start int, depth int) {
+		if depth == k {
+			subset := make([]int, k)
+			copy(subset, current)
+			out = append(out, subset)
+			return
+		}
+
+		for i := start; i <= n-(k-depth); i++ {
+			current[depth] = i
+			build(i+1, depth+1)
+		}
+	}
+	build(0, 0)
+
+	return out
+}
+
+// expLadder computes base^exp mod m using a Montgomery ladder over exactly
+// bits bits of the exponent, which must be at least exp.BitLen().
+//
+// Every iteration performs one multiplication and one squaring, regardless
+// of the value of the current bit, so the sequence of operations does not
+// depend on the exponent. Mind that math/big itself makes no constant-time
+// guarantees, so this reduces rather than eliminates timing leakage.
+func expLadder(base *big.Int, exp *big.Int, m *big.Int, bits int) *big.Int {
+	r := [2]*big.Int{
+		big.NewInt(1),
+		new(big.Int).Mod(base, m),
+	}
+
+	for i := bits - 1; i >= 0; i-- {
+		b := exp.Bit(i)
+
+		// Invariant: r[1] = r[0] * base
+		prod := new(big.Int).Mul(r[0], r[1])
+		prod.Mod(prod, m)
+		sq := new(big.Int).Mul(r[b], r[b])
+		sq.Mod(sq, m)
+
+		r[1-b] = prod
+		r[b] = sq
+	}
+
+	return r[0]
+}
+
+// parallelFor calls fn for every index in [0, n), distributing the calls
+// across a pool of at most GOMAXPROCS goroutines. It returns once all calls
+// have finished.
+//
+// fn must be safe for concurrent use, e.g. by only writing to the element
+// at index i of a preallocated output.
+func parallelFor(n int, fn func(i int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
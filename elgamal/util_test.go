@@ -1,6 +1,10 @@
 package elgamal
 
 import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
 	"testing"
 )
 
@@ -31,3 +35,194 @@ func TestRandomBits(t *testing.T) {
 		t.Errorf("Expected error when bits <= 2; got none")
 	}
 }
+
+func TestRandomBitsStats(t *testing.T) {
+	stats, err := RandomBitsStats(20000, 30)
+	if err != nil {
+		t.Fatalf("RandomBitsStats returned error: %v", err)
+	}
+
+	if stats.Samples != 20000 {
+		t.Errorf("Expected 20000 samples; got %d", stats.Samples)
+	}
+	if len(stats.Ones) != 28 {
+		t.Fatalf("Expected frequencies of 28 bits; got %d", len(stats.Ones))
+	}
+
+	// The standard deviation of the frequency is 0.0035 at 20000
+	// samples, so 0.03 is far beyond what chance would produce.
+	for i, ones := range stats.Ones {
+		if ones < 0.47 || ones > 0.53 {
+			t.Errorf("Expected frequency of ones of bit %d to be close to 0.5; got %f", i, ones)
+		}
+	}
+
+	_, err = RandomBitsStats(0, 30)
+	if err == nil {
+		t.Errorf("Expected error with 0 samples; got none")
+	}
+	_, err = RandomBitsStats(10, 2)
+	if err == nil {
+		t.Errorf("Expected error with 2 bits; got none")
+	}
+}
+
+func TestRandomBitsUniform(t *testing.T) {
+	out, err := RandomBitsUniform(14)
+	if err != nil {
+		t.Fatalf("Error generating random bits: %v", err)
+	}
+	if len(out) != 2 {
+		t.Errorf("Expected 2 random byte; got %d", len(out))
+	}
+
+	// The top requested bit must be 0 in about half of the samples, and
+	// the padding bits always.
+	topBitZero := 0
+	for i := 0; i < 200; i++ {
+		out, err := RandomBitsUniform(14)
+		if err != nil {
+			t.Fatalf("Error generating random bits: %v", err)
+		}
+
+		if out[0]&0xC0 != 0 {
+			t.Errorf("Expected padding bits to be zero, got %d", out[0]&0xC0)
+		}
+		if out[0]&0x20 == 0 {
+			topBitZero++
+		}
+	}
+	if topBitZero == 0 || topBitZero == 200 {
+		t.Errorf("Expected top bit to be random; was zero in %d of 200 samples", topBitZero)
+	}
+
+	out, err = RandomBitsUniform(1)
+	if err != nil {
+		t.Fatalf("Error generating random bits: %v", err)
+	}
+	if len(out) != 1 || out[0] > 1 {
+		t.Errorf("Expected single random bit; got %x", out)
+	}
+
+	// Error when bits <= 0
+	_, err = RandomBitsUniform(0)
+	if err == nil {
+		t.Errorf("Expected error when bits <= 0; got none")
+	}
+}
+
+func TestCombinations(t *testing.T) {
+	combs := combinations(4, 2)
+	expected := [][]int{{0, 1}, {0, 2}, {0, 3}, {1, 2}, {1, 3}, {2, 3}}
+	if !reflect.DeepEqual(combs, expected) {
+		t.Errorf("Expected combinations %v; got %v", expected, combs)
+	}
+
+	if len(combinations(5, 3)) != 10 {
+		t.Errorf("Expected 10 combinations of 3 out of 5; got %d", len(combinations(5, 3)))
+	}
+
+	if len(combinations(3, 4)) != 0 {
+		t.Errorf("Expected no combinations of 4 out of 3; got %v", combinations(3, 4))
+	}
+}
+
+func TestExpLadder(t *testing.T) {
+	m := big.NewInt(23)
+	for base := int64(0); base < 23; base++ {
+		for exp := int64(0); exp < 32; exp++ {
+			expected := new(big.Int).Exp(big.NewInt(base), big.NewInt(exp), m)
+			got := expLadder(big.NewInt(base), big.NewInt(exp), m, 8)
+			if got.Cmp(expected) != 0 {
+				t.Errorf("Expected %d^%d mod 23 = %d; got %d", base, exp, expected, got)
+			}
+		}
+	}
+}
+
+func TestRandomInt(t *testing.T) {
+	for _, max := range []int64{1, 2, 7, 8, 11, 256, 257} {
+		for i := 0; i < 100; i++ {
+			n, err := randomInt(nil, big.NewInt(max))
+			if err != nil {
+				t.Fatalf("randomInt returned error: %v", err)
+			}
+			if n.Sign() < 0 || n.Cmp(big.NewInt(max)) >= 0 {
+				t.Errorf("Expected value in [0, %d); got %d", max, n)
+			}
+		}
+	}
+
+	_, err := randomInt(nil, big.NewInt(0))
+	if err == nil {
+		t.Errorf("Expected error with max = 0; got none")
+	}
+}
+
+func TestRandomInRange(t *testing.T) {
+	ranges := [][2]int64{{0, 1}, {1, 11}, {2, 23}, {-5, 5}, {255, 257}}
+	for _, r := range ranges {
+		min := big.NewInt(r[0])
+		max := big.NewInt(r[1])
+
+		for i := 0; i < 100; i++ {
+			n, err := RandomInRange(min, max)
+			if err != nil {
+				t.Fatalf("RandomInRange returned error: %v", err)
+			}
+			if n.Cmp(min) < 0 || n.Cmp(max) >= 0 {
+				t.Errorf("Expected value in [%d, %d); got %d", min, max, n)
+			}
+		}
+	}
+
+	for _, r := range [][2]int64{{1, 1}, {5, 2}} {
+		_, err := RandomInRange(big.NewInt(r[0]), big.NewInt(r[1]))
+		if err == nil {
+			t.Errorf("Expected error with empty range [%d, %d); got none", r[0], r[1])
+		}
+	}
+}
+
+func TestRandomPrime(t *testing.T) {
+	for _, bits := range []int{2, 3, 8, 9, 64, 130} {
+		p, err := randomPrime(context.Background(), nil, bits, defaultPrimeChecks)
+		if err != nil {
+			t.Fatalf("randomPrime returned error: %v", err)
+		}
+		if p.BitLen() != bits {
+			t.Errorf("Expected prime of %d bits; got %d", bits, p.BitLen())
+		}
+		if !p.ProbablyPrime(32) {
+			t.Errorf("Expected prime; got %d", p)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := randomPrime(ctx, nil, 256, defaultPrimeChecks)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled with canceled context; got %v", err)
+	}
+}
+
+func TestConstantTimeEqual(t *testing.T) {
+	cases := []struct {
+		a, b     []byte
+		expected bool
+	}{
+		{[]byte("tag"), []byte("tag"), true},
+		{[]byte{}, []byte{}, true},
+		{nil, []byte{}, true},
+		{[]byte("tag"), []byte("tah"), false},
+		{[]byte("tag"), []byte("ta"), false},
+		{[]byte("tag"), nil, false},
+		{[]byte{0x00}, []byte{0x01}, false},
+	}
+
+	for _, c := range cases {
+		if constantTimeEqual(c.a, c.b) != c.expected {
+			t.Errorf("Expected constantTimeEqual(%x, %x) to be %v; got %v", c.a, c.b, c.expected, !c.expected)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package elgamal
+
+import (
+	"crypto/rand"
+	"fmt"
+	"github.com/lavode/secret-sharing/secretshare"
+	"math/big"
+)
+
+// ValidateSetup performs a pre-flight check of a complete threshold setup,
+// returning the first problem found. It checks that:
+// - t matches the threshold of the public key, if set
+// - The shares have distinct, positive IDs and non-nil values
+// - The Schnorr group of the public key is valid
+// - The verification keys of the public key, if any, match the shares
+// - Any t of the shares reconstruct the same private key x, with g^x = y
+// - A test message survives an encryption and threshold decryption
+//
+// As every t-sized subset of the shares is checked, this is intended for
+// moderate numbers of shares.
+func ValidateSetup(pub PublicKey, shares []PrivateKeyShare, t int) error {
+	if t < 1 {
+		return fmt.Errorf("t must be >= 1; got %d", t)
+	}
+	if len(shares) < t {
+		return fmt.Errorf("Need at least %d shares; got %d", t, len(shares))
+	}
+	if pub.Threshold != 0 && pub.Threshold != t {
+		return fmt.Errorf("t = %d does not match threshold %d of the public key", t, pub.Threshold)
+	}
+	if pub.Y == nil {
+		return fmt.Errorf("Public key is missing y")
+	}
+
+	err := checkDistinctIDs(shares)
+	if err != nil {
+		return err
+	}
+	for _, share := range shares {
+		if share.Value == nil {
+			return fmt.Errorf("Share %d is nil", share.ID)
+		}
+	}
+
+	err = pub.SchnorrGroup.Validate()
+	if err != nil {
+		return err
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return err
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return err
+	}
+
+	for _, vk := range pub.VerificationKeys {
+		var share *PrivateKeyShare
+		for i := range shares {
+			if shares[i].ID == vk.ID {
+				share = &shares[i]
+				break
+			}
+		}
+		if share == nil {
+			continue
+		}
+
+		if zp.Exp(pub.G, share.Value).Cmp(vk.Value) != 0 {
+			return fmt.Errorf("Share %d does not match its verification key", vk.ID)
+		}
+	}
+
+	for _, subset := range combinations(len(shares), t) {
+		recoverShares := make([]secretshare.Share, t)
+		ids := make([]int, t)
+		for i, idx := range subset {
+			recoverShares[i] = secretshare.Share(shares[idx])
+			ids[i] = shares[idx].ID
+		}
+
+		x, err := secretshare.TOutOfNRecover(recoverShares, zq)
+		if err != nil {
+			return fmt.Errorf("Shares %v do not reconstruct a private key: %w", ids, err)
+		}
+
+		if zp.Exp(pub.G, x).Cmp(pub.Y) != 0 {
+			return fmt.Errorf("Shares %v reconstruct a private key not matching y", ids)
+		}
+	}
+
+	msg := make([]byte, pub.BlockSize())
+	_, err = rand.Read(msg)
+	if err != nil {
+		return err
+	}
+
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		return fmt.Errorf("Test encryption failed: %w", err)
+	}
+
+	decShares := make([]DecryptionShare, t)
+	for i := 0; i < t; i++ {
+		decShares[i], err = Dec(pub, shares[i], ctxt)
+		if err != nil {
+			return fmt.Errorf("Test decryption failed: %w", err)
+		}
+	}
+
+	recovered, err := Recover(pub, decShares, ctxt)
+	if err != nil {
+		return fmt.Errorf("Test recovery failed: %w", err)
+	}
+	if !constantTimeEqual(recovered, msg) {
+		return fmt.Errorf("Test message did not survive encryption and recovery")
+	}
+
+	return nil
+}
+
+// Validate checks that P and Q are probable primes, that q divides p-1, and
+// that g is a generator of the subgroup of order q.
+//
+// Groups from untrusted sources, such as decoded JSON, should be validated
+// before use. The first failed condition is reported by returning one of
+// ErrGroupIncomplete, ErrPNotPrime, ErrQNotPrime, ErrQNotDivisor,
+// ErrGeneratorOutOfRange, ErrTrivialGenerator or ErrGeneratorOrder.
+func (sg SchnorrGroup) Validate() error {
+	if sg.P == nil || sg.Q == nil || sg.G == nil {
+		return ErrGroupIncomplete
+	}
+
+	if !sg.P.ProbablyPrime(32) {
+		return ErrPNotPrime
+	}
+	if !sg.Q.ProbablyPrime(32) {
+		return ErrQNotPrime
+	}
+
+	var rem = &big.Int{}
+	rem.Sub(sg.P, big.NewInt(1))
+	rem.Rem(rem, sg.Q)
+	if rem.Sign() != 0 {
+		return ErrQNotDivisor
+	}
+
+	if sg.G.Sign() <= 0 || sg.G.Cmp(sg.P) >= 0 {
+		return ErrGeneratorOutOfRange
+	}
+	if sg.G.Cmp(big.NewInt(1)) == 0 {
+		return ErrTrivialGenerator
+	}
+
+	var elem = &big.Int{}
+	elem.Exp(sg.G, sg.Q, sg.P)
+	if elem.Cmp(big.NewInt(1)) != 0 {
+		return ErrGeneratorOrder
+	}
+
+	return nil
+}
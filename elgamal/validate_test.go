@@ -0,0 +1,131 @@
+package elgamal
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestValidateSetup(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	err = ValidateSetup(pub, shares, 3)
+	if err != nil {
+		t.Errorf("Expected valid setup; got %v", err)
+	}
+
+	// Too few shares
+	err = ValidateSetup(pub, shares[:2], 3)
+	if err == nil {
+		t.Errorf("Expected error with fewer than t shares; got none")
+	}
+
+	// Corrupted share
+	corrupted := make([]PrivateKeyShare, len(shares))
+	copy(corrupted, shares)
+	corrupted[1].Value = new(big.Int).Add(corrupted[1].Value, big.NewInt(1))
+	err = ValidateSetup(pub, corrupted, 3)
+	if err == nil {
+		t.Errorf("Expected error with corrupted share; got none")
+	}
+
+	// Corrupted share, without verification keys to catch it early
+	noVKs := pub
+	noVKs.VerificationKeys = nil
+	err = ValidateSetup(noVKs, corrupted, 3)
+	if err == nil {
+		t.Errorf("Expected error with corrupted share; got none")
+	}
+
+	// Wrong public value
+	wrongY := pub
+	wrongY.Y = new(big.Int).Mul(pub.Y, pub.G)
+	wrongY.Y.Mod(wrongY.Y, pub.P)
+	err = ValidateSetup(wrongY, shares, 3)
+	if err == nil {
+		t.Errorf("Expected error with wrong y; got none")
+	}
+
+	// Invalid group
+	badGroup := pub
+	badGroup.G = big.NewInt(1)
+	err = ValidateSetup(badGroup, shares, 3)
+	if err == nil {
+		t.Errorf("Expected error with invalid group; got none")
+	}
+
+	// Threshold not matching the public key
+	err = ValidateSetup(pub, shares, 2)
+	if err == nil {
+		t.Errorf("Expected error with t not matching threshold; got none")
+	}
+
+	// Nil share
+	nilShare := make([]PrivateKeyShare, len(shares))
+	copy(nilShare, shares)
+	nilShare[2].Value = nil
+	err = ValidateSetup(pub, nilShare, 3)
+	if err == nil {
+		t.Errorf("Expected error with nil share; got none")
+	}
+
+	// Duplicate IDs
+	err = ValidateSetup(pub, []PrivateKeyShare{shares[0], shares[0], shares[1]}, 3)
+	if !errors.Is(err, ErrDuplicateShare) {
+		t.Errorf("Expected ErrDuplicateShare with duplicate IDs; got %v", err)
+	}
+}
+
+func TestValidateGroup(t *testing.T) {
+	group := SchnorrGroup{
+		P: big.NewInt(23),
+		Q: big.NewInt(11),
+		G: big.NewInt(4),
+	}
+
+	err := group.Validate()
+	if err != nil {
+		t.Errorf("Expected valid group; got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		group    SchnorrGroup
+		expected error
+	}{
+		{"missing p", SchnorrGroup{Q: big.NewInt(11), G: big.NewInt(4)}, ErrGroupIncomplete},
+		{"composite p", SchnorrGroup{P: big.NewInt(22), Q: big.NewInt(11), G: big.NewInt(4)}, ErrPNotPrime},
+		{"composite q", SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(12), G: big.NewInt(4)}, ErrQNotPrime},
+		{"q not dividing p-1", SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(7), G: big.NewInt(4)}, ErrQNotDivisor},
+		{"g = 0", SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(11), G: big.NewInt(0)}, ErrGeneratorOutOfRange},
+		{"g >= p", SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(11), G: big.NewInt(27)}, ErrGeneratorOutOfRange},
+		{"g = 1", SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(11), G: big.NewInt(1)}, ErrTrivialGenerator},
+		{"g of order 22", SchnorrGroup{P: big.NewInt(23), Q: big.NewInt(11), G: big.NewInt(5)}, ErrGeneratorOrder},
+	}
+
+	for _, test := range tests {
+		err := test.group.Validate()
+		if !errors.Is(err, test.expected) {
+			t.Errorf("Expected %v for group with %s; got %v", test.expected, test.name, err)
+		}
+		if !errors.Is(err, ErrInvalidGroup) {
+			t.Errorf("Expected error for group with %s to match ErrInvalidGroup; got %v", test.name, err)
+		}
+	}
+
+	// Callers validating groups pass the errors on
+	_, err = ParseSchnorrGroup("17", "b", "5")
+	if !errors.Is(err, ErrGeneratorOrder) || !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrGeneratorOrder and ErrInvalidGroup from ParseSchnorrGroup; got %v", err)
+	}
+	_, _, _, err = RekeyInGroup(SchnorrGroup{P: big.NewInt(22), Q: big.NewInt(11), G: big.NewInt(4)}, 2, 3)
+	if !errors.Is(err, ErrPNotPrime) || !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Expected ErrPNotPrime and ErrInvalidGroup from RekeyInGroup; got %v", err)
+	}
+	if errors.Is(ErrPNotPrime, ErrQNotPrime) {
+		t.Errorf("Expected ErrPNotPrime not to match ErrQNotPrime")
+	}
+}
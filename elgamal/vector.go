@@ -0,0 +1,110 @@
+package elgamal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/lavode/secret-sharing/secretshare"
+)
+
+// Vector is a test vector for recovery, allowing to cross-check this
+// implementation against others. It is typically decoded from JSON, with all
+// integers and byte strings encoded as base-16 strings.
+type Vector struct {
+	// Name of the vector, used in error messages
+	Name string `json:"name"`
+
+	// Public key, with integers with or without "0x" prefix
+	P string `json:"p"`
+	Q string `json:"q"`
+	G string `json:"g"`
+	Y string `json:"y"`
+	// Name of the hash algorithm, e.g. "SHA-512". Empty selects the
+	// default.
+	Hash string `json:"hash,omitempty"`
+
+	// Ciphertext. R is an integer, C and Label are byte strings
+	R     string `json:"r"`
+	C     string `json:"c"`
+	Label string `json:"label,omitempty"`
+
+	// Decryption shares to recover the ciphertext with
+	Shares []VectorShare `json:"shares"`
+
+	// Expected plaintext, as byte string
+	Plaintext string `json:"plaintext"`
+}
+
+// VectorShare is a decryption share of a test vector.
+type VectorShare struct {
+	ID int `json:"id"`
+	// Value R^{x_i} mod p, as base-16 integer
+	Value string `json:"value"`
+}
+
+// RunVector decodes the passed test vector, recovers its ciphertext from its
+// decryption shares using Recover(), and compares the result to the expected
+// plaintext.
+//
+// An error naming the vector is returned if any value of the vector is not
+// valid, if recovery fails, or if the recovered plaintext differs from the
+// expected one.
+func RunVector(v Vector) error {
+	err := runVector(v)
+	if err != nil {
+		return fmt.Errorf("Vector %q: %w", v.Name, err)
+	}
+
+	return nil
+}
+
+// runVector implements RunVector(), without naming the vector in errors.
+func runVector(v Vector) error {
+	pub, err := ParsePublicKey(v.P, v.Q, v.G, v.Y)
+	if err != nil {
+		return err
+	}
+	pub.Hash, err = decodeHash(v.Hash)
+	if err != nil {
+		return err
+	}
+
+	var ctxt Ciphertext
+	ctxt.R, err = parseHex("r", v.R)
+	if err != nil {
+		return err
+	}
+	ctxt.C, err = hex.DecodeString(v.C)
+	if err != nil {
+		return fmt.Errorf("Value of c is not valid hex: %w", err)
+	}
+	if v.Label != "" {
+		ctxt.Label, err = hex.DecodeString(v.Label)
+		if err != nil {
+			return fmt.Errorf("Value of label is not valid hex: %w", err)
+		}
+	}
+
+	expected, err := hex.DecodeString(v.Plaintext)
+	if err != nil {
+		return fmt.Errorf("Value of plaintext is not valid hex: %w", err)
+	}
+
+	shares := make([]DecryptionShare, len(v.Shares))
+	for i, share := range v.Shares {
+		value, err := parseHex(fmt.Sprintf("share %d", share.ID), share.Value)
+		if err != nil {
+			return err
+		}
+		shares[i] = DecryptionShare(secretshare.Share{ID: share.ID, Value: value})
+	}
+
+	recovered, err := Recover(pub, shares, ctxt)
+	if err != nil {
+		return fmt.Errorf("Recovery failed: %w", err)
+	}
+	if !constantTimeEqual(recovered, expected) {
+		return fmt.Errorf("Recovered plaintext %x does not match expected plaintext %x", recovered, expected)
+	}
+
+	return nil
+}
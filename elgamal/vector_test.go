@@ -0,0 +1,66 @@
+package elgamal
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func loadVectors(t *testing.T) []Vector {
+	data, err := os.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatalf("Reading test vectors failed: %v", err)
+	}
+
+	var vectors []Vector
+	err = json.Unmarshal(data, &vectors)
+	if err != nil {
+		t.Fatalf("Decoding test vectors failed: %v", err)
+	}
+	if len(vectors) == 0 {
+		t.Fatalf("Expected at least one test vector; got none")
+	}
+
+	return vectors
+}
+
+func TestRunVector(t *testing.T) {
+	for _, v := range loadVectors(t) {
+		err := RunVector(v)
+		if err != nil {
+			t.Errorf("RunVector returned error: %v", err)
+		}
+	}
+
+	v := loadVectors(t)[0]
+
+	wrongPlaintext := v
+	wrongPlaintext.Plaintext = strings.Repeat("00", 64)
+	err := RunVector(wrongPlaintext)
+	if err == nil || !strings.Contains(err.Error(), v.Name) {
+		t.Errorf("Expected error naming vector with wrong plaintext; got %v", err)
+	}
+
+	wrongShare := v
+	wrongShare.Shares = append([]VectorShare{}, v.Shares...)
+	wrongShare.Shares[0].Value = "05"
+	err = RunVector(wrongShare)
+	if err == nil {
+		t.Errorf("Expected error with wrong decryption share; got none")
+	}
+
+	invalidHex := v
+	invalidHex.C = "zz"
+	err = RunVector(invalidHex)
+	if err == nil {
+		t.Errorf("Expected error with invalid hex; got none")
+	}
+
+	unsupportedHash := v
+	unsupportedHash.Hash = "MD5"
+	err = RunVector(unsupportedHash)
+	if err == nil {
+		t.Errorf("Expected error with unsupported hash; got none")
+	}
+}
@@ -0,0 +1,199 @@
+package elgamal
+
+import (
+	"fmt"
+	"github.com/lavode/secret-sharing/gf"
+	"math/big"
+)
+
+// VerifyKeyShare checks a private key share against the Feldman commitments
+// g^{a_j} mod p to the coefficients a_j of the polynomial used to share the
+// private key, as published by KeyGen() in pub.Commitments. This allows a
+// party to detect an inconsistent share handed out by a faulty or malicious
+// dealer.
+//
+// The share is valid if g^{share} = prod_j commitments[j]^{ID^j} mod p. An
+// error is returned if no commitments are passed, or if the fields of the
+// public key cannot be constructed.
+func VerifyKeyShare(pub PublicKey, commitments []*big.Int, share PrivateKeyShare) (bool, error) {
+	if len(commitments) == 0 {
+		return false, fmt.Errorf("No commitments to verify share against")
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return false, err
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return false, err
+	}
+
+	if share.Value == nil || !zq.IsGroupElement(share.Value) {
+		return false, nil
+	}
+
+	return verifyFeldman(zp, zq, pub.G, commitments, share.ID, share.Value), nil
+}
+
+// feldmanCommitments returns the Feldman commitments g^{a_j} mod p to the
+// coefficients a_j of the passed polynomial.
+func feldmanCommitments(zp gf.GF, g *big.Int, poly gf.Polynomial) []*big.Int {
+	commitments := make([]*big.Int, len(poly.Coefficients))
+	for j, coef := range poly.Coefficients {
+		commitments[j] = zp.Exp(g, coef) // g^{a_j}
+	}
+
+	return commitments
+}
+
+// verifyFeldman checks whether value = f(id) for the polynomial f committed
+// to by the passed Feldman commitments, that is whether
+// g^value = prod_j commitments[j]^{id^j} mod p.
+func verifyFeldman(zp gf.GF, zq gf.GF, g *big.Int, commitments []*big.Int, id int, value *big.Int) bool {
+	if len(commitments) == 0 || value == nil {
+		return false
+	}
+
+	expected := evalCommitments(zp, zq, commitments, id)
+	if expected == nil {
+		return false
+	}
+
+	return zp.Exp(g, value).Cmp(expected) == 0
+}
+
+// evalCommitments evaluates the polynomial committed to by the passed Feldman
+// commitments at id in the exponent, returning
+// g^{f(id)} = prod_j commitments[j]^{id^j} mod p. nil is returned if any
+// commitment is missing.
+func evalCommitments(zp gf.GF, zq gf.GF, commitments []*big.Int, id int) *big.Int {
+	x := big.NewInt(int64(id))
+
+	expected := big.NewInt(1)
+	for j, commitment := range commitments {
+		if commitment == nil {
+			return nil
+		}
+		// Commitments are of order q, so exponents are over (Z/qZ)
+		exp := zq.Exp(x, big.NewInt(int64(j))) // id^j
+		expected = zp.Mul(expected, zp.Exp(commitment, exp))
+	}
+
+	return expected
+}
+
+// VerifyDealerOutput checks the public output of a trusted dealer's KeyGen()
+// for consistency, without knowledge of any private key share. It checks
+// that:
+// - There are exactly t commitments, the first of which is y = g^x
+// - Each verification key g^{x_i} equals prod_j commitments[j]^{i^j} mod p
+// - Interpolating t of the verification keys in the exponent yields y
+//
+// As all verification keys lie on the same committed polynomial of degree
+// t-1, any other t of them interpolate to y as well.
+//
+// An error wrapping ErrInconsistentDealerOutput and identifying the first
+// inconsistency is returned if any check fails. Other errors are returned if
+// fewer than t verification keys are passed, if their IDs are not distinct,
+// or if the group is invalid.
+func VerifyDealerOutput(pub PublicKey, vshares []VerificationKey, commitments []*big.Int, t int) error {
+	if t < 1 {
+		return fmt.Errorf("t must be >= 1; got %d", t)
+	}
+	if len(vshares) < t {
+		return fmt.Errorf("%w: need at least %d verification keys; got %d", ErrThresholdNotMet, t, len(vshares))
+	}
+	if pub.Y == nil {
+		return fmt.Errorf("Public key is missing y")
+	}
+
+	zp, err := pub.Zp()
+	if err != nil {
+		return err
+	}
+	zq, err := pub.Zq()
+	if err != nil {
+		return err
+	}
+
+	if len(commitments) != t {
+		return fmt.Errorf("%w: expected %d commitments; got %d", ErrInconsistentDealerOutput, t, len(commitments))
+	}
+	if commitments[0] == nil || commitments[0].Cmp(pub.Y) != 0 {
+		return fmt.Errorf("%w: commitment to constant term does not match y", ErrInconsistentDealerOutput)
+	}
+
+	for _, vk := range vshares {
+		expected := evalCommitments(zp, zq, commitments, vk.ID)
+		if expected == nil {
+			return fmt.Errorf("%w: commitments are incomplete", ErrInconsistentDealerOutput)
+		}
+		if vk.Value == nil || vk.Value.Cmp(expected) != 0 {
+			return fmt.Errorf("%w: verification key %d does not match commitments", ErrInconsistentDealerOutput, vk.ID)
+		}
+	}
+
+	y, err := PublicKeyFromVerificationShares(pub.SchnorrGroup, vshares, t)
+	if err != nil {
+		return err
+	}
+	if y.Cmp(pub.Y) != 0 {
+		return fmt.Errorf("%w: verification keys do not interpolate to y", ErrInconsistentDealerOutput)
+	}
+
+	return nil
+}
+
+// PublicKeyFromVerificationShares recovers the public key y = g^x from t
+// verification keys g^{x_i} mod p, without knowledge of x or any x_i, by
+// Lagrange interpolation in the exponent. This allows to audit the output of
+// a key generation, e.g. of a DealerlessKeygen, by comparing the result with
+// the published public key.
+//
+// Parameters:
+// - group: Schnorr group the verification keys are elements of
+// - vshares: Verification keys, of which the first t are used
+// - t: Threshold of the sharing
+//
+// An error is returned if t < 1, if fewer than t verification keys are
+// passed, if their IDs are not distinct, or if the group is invalid.
+func PublicKeyFromVerificationShares(group SchnorrGroup, vshares []VerificationKey, t int) (*big.Int, error) {
+	if t < 1 {
+		return nil, fmt.Errorf("t must be >= 1; got %d", t)
+	}
+	if len(vshares) < t {
+		return nil, fmt.Errorf("%w: need at least %d verification keys; got %d", ErrThresholdNotMet, t, len(vshares))
+	}
+
+	zp, err := group.Zp()
+	if err != nil {
+		return nil, err
+	}
+	zq, err := group.Zq()
+	if err != nil {
+		return nil, err
+	}
+
+	// Interpolation in the exponent is the same as combining decryption
+	// shares R^{x_i}, with R = g
+	shares := make([]DecryptionShare, t)
+	seen := make(map[int]bool)
+	for i, vk := range vshares[:t] {
+		if vk.ID <= 0 {
+			return nil, fmt.Errorf("Verification key IDs must be positive; got %d", vk.ID)
+		}
+		if seen[vk.ID] {
+			return nil, fmt.Errorf("%w: verification key with ID %d", ErrDuplicateShare, vk.ID)
+		}
+		seen[vk.ID] = true
+
+		if vk.Value == nil || vk.Value.Sign() <= 0 || vk.Value.Cmp(group.P) >= 0 {
+			return nil, fmt.Errorf("Verification key %d must be in [1, p)", vk.ID)
+		}
+
+		shares[i] = DecryptionShare{ID: vk.ID, Value: vk.Value}
+	}
+
+	return combineShares(zp, zq, shares), nil
+}
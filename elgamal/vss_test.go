@@ -0,0 +1,209 @@
+package elgamal
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestVerifyKeyShare(t *testing.T) {
+	pub, _, shares, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	if len(pub.Commitments) != 3 {
+		t.Fatalf("Expected 3 commitments; got %d", len(pub.Commitments))
+	}
+	// The constant term of the polynomial is the private key
+	if pub.Commitments[0].Cmp(pub.Y) != 0 {
+		t.Errorf("Expected first commitment to equal y = %d; got %d", pub.Y, pub.Commitments[0])
+	}
+
+	for _, share := range shares {
+		ok, err := VerifyKeyShare(pub, pub.Commitments, share)
+		if err != nil {
+			t.Fatalf("VerifyKeyShare returned error: %v", err)
+		}
+		if !ok {
+			t.Errorf("Expected share %d to verify; it did not", share.ID)
+		}
+
+		tampered := PrivateKeyShare{ID: share.ID, Value: new(big.Int).Add(share.Value, big.NewInt(1))}
+		tampered.Value.Mod(tampered.Value, pub.Q)
+		ok, err = VerifyKeyShare(pub, pub.Commitments, tampered)
+		if err != nil {
+			t.Fatalf("VerifyKeyShare returned error: %v", err)
+		}
+		if ok {
+			t.Errorf("Expected tampered share %d not to verify; it did", share.ID)
+		}
+	}
+
+	_, err = VerifyKeyShare(pub, nil, shares[0])
+	if err == nil {
+		t.Errorf("Expected error without commitments; got none")
+	}
+}
+
+func TestPublicKeyFromVerificationShares(t *testing.T) {
+	pub, _, _, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	for _, vshares := range [][]VerificationKey{
+		pub.VerificationKeys[:3],
+		pub.VerificationKeys[2:],
+		{pub.VerificationKeys[4], pub.VerificationKeys[0], pub.VerificationKeys[2]},
+	} {
+		y, err := PublicKeyFromVerificationShares(pub.SchnorrGroup, vshares, 3)
+		if err != nil {
+			t.Fatalf("PublicKeyFromVerificationShares returned error: %v", err)
+		}
+		if y.Cmp(pub.Y) != 0 {
+			t.Errorf("Expected y = %d; got %d", pub.Y, y)
+		}
+	}
+
+	// Fewer than t shares yield a different value
+	y, err := PublicKeyFromVerificationShares(pub.SchnorrGroup, pub.VerificationKeys[:2], 2)
+	if err != nil {
+		t.Fatalf("PublicKeyFromVerificationShares returned error: %v", err)
+	}
+	if y.Cmp(pub.Y) == 0 {
+		t.Errorf("Expected 2 of 3 shares not to recover y")
+	}
+
+	_, err = PublicKeyFromVerificationShares(pub.SchnorrGroup, pub.VerificationKeys[:2], 3)
+	if err == nil {
+		t.Errorf("Expected error with fewer than t verification keys; got none")
+	}
+
+	duplicate := []VerificationKey{pub.VerificationKeys[0], pub.VerificationKeys[1], pub.VerificationKeys[0]}
+	_, err = PublicKeyFromVerificationShares(pub.SchnorrGroup, duplicate, 3)
+	if err == nil {
+		t.Errorf("Expected error with duplicate verification keys; got none")
+	}
+
+	_, err = PublicKeyFromVerificationShares(pub.SchnorrGroup, pub.VerificationKeys, 0)
+	if err == nil {
+		t.Errorf("Expected error with t = 0; got none")
+	}
+
+	invalid := []VerificationKey{{ID: 1, Value: big.NewInt(0)}}
+	_, err = PublicKeyFromVerificationShares(pub.SchnorrGroup, invalid, 1)
+	if err == nil {
+		t.Errorf("Expected error with verification key of 0; got none")
+	}
+}
+
+func TestVerifyDealerOutput(t *testing.T) {
+	pub, _, _, err := KeyGen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	err = VerifyDealerOutput(pub, pub.VerificationKeys, pub.Commitments, 3)
+	if err != nil {
+		t.Errorf("Expected KeyGen output to verify; got %v", err)
+	}
+
+	// Corrupting a single verification key
+	vshares := make([]VerificationKey, len(pub.VerificationKeys))
+	copy(vshares, pub.VerificationKeys)
+	vshares[3].Value = new(big.Int).Mul(vshares[3].Value, pub.G)
+	vshares[3].Value.Mod(vshares[3].Value, pub.P)
+	err = VerifyDealerOutput(pub, vshares, pub.Commitments, 3)
+	if !errors.Is(err, ErrInconsistentDealerOutput) {
+		t.Errorf("Expected ErrInconsistentDealerOutput with corrupted verification key; got %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("verification key %d", vshares[3].ID)) {
+		t.Errorf("Expected error to identify verification key %d; got %v", vshares[3].ID, err)
+	}
+
+	// Public key not matching the commitments
+	other := pub
+	other.Y = new(big.Int).Mul(pub.Y, pub.G)
+	other.Y.Mod(other.Y, pub.P)
+	err = VerifyDealerOutput(other, pub.VerificationKeys, pub.Commitments, 3)
+	if !errors.Is(err, ErrInconsistentDealerOutput) {
+		t.Errorf("Expected ErrInconsistentDealerOutput with different y; got %v", err)
+	}
+
+	err = VerifyDealerOutput(pub, pub.VerificationKeys, pub.Commitments[:2], 3)
+	if !errors.Is(err, ErrInconsistentDealerOutput) {
+		t.Errorf("Expected ErrInconsistentDealerOutput with too few commitments; got %v", err)
+	}
+}
+
+func TestVerifyKeySharePedersen(t *testing.T) {
+	pub, priv, shares, sharing, err := KeyGenPedersen(512, 128, 3, 5)
+	if err != nil {
+		t.Fatalf("KeyGenPedersen returned error: %v", err)
+	}
+
+	if len(sharing.Commitments) != 3 {
+		t.Fatalf("Expected 3 commitments; got %d", len(sharing.Commitments))
+	}
+	if len(sharing.Blindings) != len(shares) {
+		t.Fatalf("Expected %d blinding shares; got %d", len(shares), len(sharing.Blindings))
+	}
+	if len(pub.Commitments) != 0 || len(pub.VerificationKeys) != 0 {
+		t.Errorf("Expected public key without Feldman commitments or verification keys")
+	}
+	// Unlike a Feldman commitment, the first commitment hides y
+	if sharing.Commitments[0].Cmp(pub.Y) == 0 {
+		t.Errorf("Expected first commitment to differ from y")
+	}
+
+	for i, share := range shares {
+		ok, err := VerifyKeySharePedersen(pub, sharing.Commitments, share, sharing.Blindings[i])
+		if err != nil {
+			t.Fatalf("VerifyKeySharePedersen returned error: %v", err)
+		}
+		if !ok {
+			t.Errorf("Expected share %d to verify; it did not", share.ID)
+		}
+
+		tampered := PrivateKeyShare{ID: share.ID, Value: new(big.Int).Add(share.Value, big.NewInt(1))}
+		tampered.Value.Mod(tampered.Value, pub.Q)
+		ok, err = VerifyKeySharePedersen(pub, sharing.Commitments, tampered, sharing.Blindings[i])
+		if err != nil {
+			t.Fatalf("VerifyKeySharePedersen returned error: %v", err)
+		}
+		if ok {
+			t.Errorf("Expected tampered share %d not to verify; it did", share.ID)
+		}
+
+		tamperedBlinding := PrivateKeyShare{ID: share.ID, Value: new(big.Int).Add(sharing.Blindings[i].Value, big.NewInt(1))}
+		tamperedBlinding.Value.Mod(tamperedBlinding.Value, pub.Q)
+		ok, err = VerifyKeySharePedersen(pub, sharing.Commitments, share, tamperedBlinding)
+		if err != nil {
+			t.Fatalf("VerifyKeySharePedersen returned error: %v", err)
+		}
+		if ok {
+			t.Errorf("Expected share %d with tampered blinding not to verify; it did", share.ID)
+		}
+	}
+
+	// Shares are regular shares of the private key
+	recovered, err := RecoverPrivateKey(pub, shares[1:4])
+	if err != nil {
+		t.Fatalf("RecoverPrivateKey returned error: %v", err)
+	}
+	if recovered.X.Cmp(priv.X) != 0 {
+		t.Errorf("Expected recovered private key %d; got %d", priv.X, recovered.X)
+	}
+
+	_, err = VerifyKeySharePedersen(pub, sharing.Commitments, shares[0], sharing.Blindings[1])
+	if err == nil {
+		t.Errorf("Expected error with mismatching IDs; got none")
+	}
+	_, err = VerifyKeySharePedersen(pub, nil, shares[0], sharing.Blindings[0])
+	if err == nil {
+		t.Errorf("Expected error without commitments; got none")
+	}
+}
@@ -0,0 +1,51 @@
+package elgamal
+
+import (
+	"context"
+	"fmt"
+)
+
+// KeyGenWeighted generates a t-out-of-n shared private key like KeyGen(), but
+// gives parties different weights towards the threshold.
+//
+// Party i receives weights[i] shares, with consecutive IDs, such that any set
+// of parties whose weights sum to at least t can recover. The shares are
+// regular shares of a t-out-of-sum(weights) sharing, so recovery works on
+// the flattened list of the parties' shares, e.g. by passing all decryption
+// shares of the participating parties to Recover().
+//
+// Parameters:
+// - pBits: Bit length of p
+// - qBits: Bit length of q. Must be less than pBits
+// - t: Total weight required to recover
+// - weights: Weight of each party, each of which must be positive
+//
+// An error is returned if any weight is not positive, if t < 1, or if t
+// exceeds the sum of the weights.
+func KeyGenWeighted(pBits int, qBits int, t int, weights []int) (PublicKey, PrivateKey, [][]PrivateKeyShare, error) {
+	if len(weights) == 0 {
+		return PublicKey{}, PrivateKey{}, nil, fmt.Errorf("Need at least one party")
+	}
+
+	n := 0
+	for i, weight := range weights {
+		if weight < 1 {
+			return PublicKey{}, PrivateKey{}, nil, fmt.Errorf("Weight of party %d must be >= 1; got %d", i, weight)
+		}
+		n += weight
+	}
+
+	pub, priv, shares, err := keyGen(context.Background(), nil, pBits, qBits, t, n)
+	if err != nil {
+		return pub, priv, nil, err
+	}
+
+	partyShares := make([][]PrivateKeyShare, len(weights))
+	offset := 0
+	for i, weight := range weights {
+		partyShares[i] = shares[offset : offset+weight : offset+weight]
+		offset += weight
+	}
+
+	return pub, priv, partyShares, nil
+}
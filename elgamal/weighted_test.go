@@ -0,0 +1,89 @@
+package elgamal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKeyGenWeighted(t *testing.T) {
+	pub, _, partyShares, err := KeyGenWeighted(512, 128, 3, []int{2, 1, 1, 1})
+	if err != nil {
+		t.Fatalf("KeyGenWeighted returned error: %v", err)
+	}
+
+	if len(partyShares) != 4 {
+		t.Fatalf("Expected shares of 4 parties; got %d", len(partyShares))
+	}
+	for i, expected := range []int{2, 1, 1, 1} {
+		if len(partyShares[i]) != expected {
+			t.Errorf("Expected party %d to hold %d shares; got %d", i, expected, len(partyShares[i]))
+		}
+	}
+
+	msg := make([]byte, 64)
+	copy(msg, []byte("Hello world"))
+	ctxt, err := Enc(pub, msg)
+	if err != nil {
+		t.Fatalf("Enc returned error: %v", err)
+	}
+
+	// Decryption shares of the passed parties, flattened
+	decrypt := func(parties ...int) []DecryptionShare {
+		var decShares []DecryptionShare
+		for _, party := range parties {
+			shares, err := DecAll(pub, partyShares[party], ctxt)
+			if err != nil {
+				t.Fatalf("DecAll returned error: %v", err)
+			}
+			decShares = append(decShares, shares...)
+		}
+		return decShares
+	}
+
+	// Weight-2 party along with any other party
+	for _, other := range []int{1, 2, 3} {
+		recovered, err := Recover(pub, decrypt(0, other), ctxt)
+		if err != nil {
+			t.Fatalf("Recover returned error: %v", err)
+		}
+		if !bytes.Equal(recovered, msg) {
+			t.Errorf("Expected parties 0 and %d to recover message %x; got %x", other, msg, recovered)
+		}
+	}
+
+	// Three parties of weight 1
+	recovered, err := Recover(pub, decrypt(1, 2, 3), ctxt)
+	if err != nil {
+		t.Fatalf("Recover returned error: %v", err)
+	}
+	if !bytes.Equal(recovered, msg) {
+		t.Errorf("Expected parties 1, 2 and 3 to recover message %x; got %x", msg, recovered)
+	}
+
+	// Weight-2 party alone
+	_, err = Recover(pub, decrypt(0), ctxt)
+	if err == nil {
+		t.Errorf("Expected error when weight-2 party recovers alone; got none")
+	}
+}
+
+func TestKeyGenWeightedParameters(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       int
+		weights []int
+	}{
+		{"no parties", 1, nil},
+		{"zero weight", 2, []int{2, 0, 1}},
+		{"negative weight", 2, []int{2, -1, 1}},
+		{"t exceeds total weight", 5, []int{2, 1, 1}},
+		{"t zero", 0, []int{2, 1, 1}},
+	}
+
+	for _, test := range tests {
+		_, _, _, err := KeyGenWeighted(20, 10, test.t, test.weights)
+		if err == nil {
+			t.Errorf("%s: Expected error for t = %d, weights = %v; got none", test.name, test.t, test.weights)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package elgamal
+
+import (
+	"math/big"
+)
+
+// Zero overwrites the private exponent x with zeros, and replaces it with a
+// fresh zero value.
+//
+// This is a best-effort measure: math/big may have left copies of x in
+// memory during earlier arithmetic, e.g. when growing its internal buffer,
+// and the garbage collector may have moved it, neither of which can be
+// scrubbed. Copies of the key, such as shares derived from it, are not
+// affected either.
+func (priv *PrivateKey) Zero() {
+	zeroInt(priv.X)
+	priv.X = new(big.Int)
+}
+
+// Zero overwrites the value of the private key share with zeros, and replaces
+// it with a fresh zero value. As with PrivateKey.Zero(), this is a best-effort
+// measure.
+func (ks *PrivateKeyShare) Zero() {
+	zeroInt(ks.Value)
+	ks.Value = new(big.Int)
+}
+
+// zeroInt overwrites the words backing x with zeros, and sets x to 0. x may
+// be nil.
+func zeroInt(x *big.Int) {
+	if x == nil {
+		return
+	}
+
+	// Bits() exposes the underlying word slice, rather than a copy. Its
+	// capacity may exceed its length, so the whole buffer is scrubbed.
+	words := x.Bits()
+	words = words[:cap(words)]
+	for i := range words {
+		words[i] = 0
+	}
+
+	x.SetInt64(0)
+}
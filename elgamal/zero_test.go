@@ -0,0 +1,59 @@
+package elgamal
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestPrivateKeyZero(t *testing.T) {
+	_, priv, shares, err := KeyGen(512, 128, 2, 3)
+	if err != nil {
+		t.Fatalf("KeyGen returned error: %v", err)
+	}
+
+	x := priv.X
+	words := x.Bits()
+	priv.Zero()
+
+	if priv.X == nil || priv.X.Sign() != 0 {
+		t.Errorf("Expected x to be zero; got %v", priv.X)
+	}
+	if priv.X == x {
+		t.Errorf("Expected x to be replaced by a fresh value")
+	}
+	if x.Sign() != 0 {
+		t.Errorf("Expected previous x to be zero; got %d", x)
+	}
+	for i, word := range words {
+		if word != 0 {
+			t.Errorf("Expected word %d of previous x to be scrubbed; got %x", i, word)
+		}
+	}
+
+	share := shares[0]
+	value := share.Value
+	words = value.Bits()
+	share.Zero()
+
+	if share.Value == nil || share.Value.Sign() != 0 {
+		t.Errorf("Expected share value to be zero; got %v", share.Value)
+	}
+	if value.Sign() != 0 {
+		t.Errorf("Expected previous share value to be zero; got %d", value)
+	}
+	for i, word := range words {
+		if word != 0 {
+			t.Errorf("Expected word %d of previous share value to be scrubbed; got %x", i, word)
+		}
+	}
+	if share.ID != shares[0].ID {
+		t.Errorf("Expected ID to be kept; got %d", share.ID)
+	}
+
+	// Zeroing missing values does not panic
+	var empty PrivateKey
+	empty.Zero()
+	if empty.X.Cmp(big.NewInt(0)) != 0 {
+		t.Errorf("Expected x to be zero; got %d", empty.X)
+	}
+}